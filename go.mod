@@ -3,8 +3,13 @@ module github.com/bt-smart/btlog
 go 1.23
 
 require (
+	github.com/golang/snappy v1.0.0
+	go.opentelemetry.io/otel/trace v1.28.0
 	go.uber.org/zap v1.27.0
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 )
 
-require go.uber.org/multierr v1.10.0 // indirect
+require (
+	go.opentelemetry.io/otel v1.28.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+)
@@ -0,0 +1,37 @@
+package loki
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Auth 定义访问Loki时的认证方式
+// 按以下优先级生效：TokenProvider、BearerToken、Username/Password，全部为空时不认证
+type Auth struct {
+	// BearerToken 是静态的 Bearer 令牌
+	BearerToken string
+	// TokenProvider 在每次请求前获取 Bearer 令牌，用于刷新短期有效的令牌
+	TokenProvider func() (string, error)
+	// Username 是 HTTP 基本认证的用户名
+	Username string
+	// Password 是 HTTP 基本认证的密码
+	Password string
+}
+
+// apply 为请求设置认证信息
+// 返回的错误不会包含令牌或密码，可以安全地记录到日志中
+func (a Auth) apply(req *http.Request) error {
+	switch {
+	case a.TokenProvider != nil:
+		token, err := a.TokenProvider()
+		if err != nil {
+			return fmt.Errorf("get auth token failed: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case a.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+a.BearerToken)
+	case a.Username != "":
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+	return nil
+}
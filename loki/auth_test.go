@@ -0,0 +1,105 @@
+package loki
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+func TestAuthHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		auth Auth
+		want string
+	}{
+		{"none", Auth{}, ""},
+		{"bearer", Auth{BearerToken: "static-token"}, "Bearer static-token"},
+		{"basic", Auth{Username: "user", Password: "pass"}, "Basic dXNlcjpwYXNz"},
+		{"provider wins", Auth{BearerToken: "static-token", TokenProvider: func() (string, error) { return "fresh", nil }}, "Bearer fresh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeLoki(t, nil)
+			c := newStartedClient(t, ClientConfig{URL: server.URL, Auth: tt.auth})
+			_ = c.Info("hello")
+			if err := c.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := server.Pushes()[0].Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenProviderCalledPerRequest(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	var calls int
+	c := newStartedClient(t, ClientConfig{URL: server.URL, Auth: Auth{TokenProvider: func() (string, error) {
+		calls++
+		return "token-" + strconv.Itoa(calls), nil
+	}}})
+
+	for i := 0; i < 2; i++ {
+		_ = c.Info("hello")
+		_ = c.Flush()
+	}
+
+	pushes := server.Pushes()
+	if len(pushes) != 2 {
+		t.Fatalf("got %d requests, want 2", len(pushes))
+	}
+	for i, p := range pushes {
+		if want := "Bearer token-" + strconv.Itoa(i+1); p.Header.Get("Authorization") != want {
+			t.Errorf("request %d Authorization = %q, want %q", i, p.Header.Get("Authorization"), want)
+		}
+	}
+}
+
+func TestAuthSecretsNotInErrors(t *testing.T) {
+	const secret = "s3cr3t-value"
+
+	tests := []struct {
+		name    string
+		auth    Auth
+		respond func(w http.ResponseWriter, r *http.Request)
+	}{
+		{"bearer rejected", Auth{BearerToken: secret}, respondStatus(http.StatusUnauthorized)},
+		{"basic rejected", Auth{Username: "user", Password: secret}, respondStatus(http.StatusForbidden)},
+		{"provider rejected", Auth{TokenProvider: func() (string, error) { return secret, nil }}, respondStatus(http.StatusUnauthorized)},
+		{"provider failed", Auth{TokenProvider: func() (string, error) { return "", errors.New("token endpoint unavailable") }}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeLoki(t, tt.respond)
+			var dropErr error
+			c := newStartedClient(t, ClientConfig{
+				URL:         server.URL,
+				Auth:        tt.auth,
+				OnSendError: func(_ []pkg.LogEntry, err error) { dropErr = err },
+			})
+			_ = c.Info("hello")
+
+			err := c.Flush()
+			if err == nil {
+				t.Fatal("Flush() error = nil, want auth failure")
+			}
+			for _, e := range []error{err, dropErr} {
+				if e != nil && strings.Contains(e.Error(), secret) {
+					t.Errorf("error leaks the secret: %v", e)
+				}
+			}
+			for _, event := range c.DroppedEvents() {
+				if strings.Contains(event.Error, secret) {
+					t.Errorf("drop event leaks the secret: %s", event.Error)
+				}
+			}
+		})
+	}
+}
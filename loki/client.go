@@ -3,19 +3,34 @@ package loki
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap/zapcore"
+	"hash/fnv"
 	"io"
 	"log"
 	"net/http"
-	"strconv"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/bt-smart/btlog/pkg"
 )
 
+// 写入日志时返回的缓冲错误，表示日志没有进入缓冲区
+// 发送错误在后台产生，不会从 Push 等方法返回，而是传给 OnSendError，并可以通过 LastError 查询
+var (
+	// ErrClientClosed 表示客户端已关闭
+	ErrClientClosed = errors.New("client is closed")
+	// ErrClientNotStarted 表示客户端尚未启动
+	ErrClientNotStarted = errors.New("client is not started")
+)
+
 // Client 实现了Loki的客户端，提供日志推送功能
 // 支持批量发送、缓存、自动重试等特性
 type Client struct {
@@ -23,14 +38,68 @@ type Client struct {
 	config ClientConfig
 	// buffer 是内存中的日志缓冲区，用于批量发送日志
 	buffer *pkg.Buffer
-	// done 是用于优雅关闭的信号通道
-	done chan bool
-	// httpClient 是用于发送请求的 HTTP 客户端
-	httpClient *http.Client
+	// done 在 Stop 时关闭，通知工作协程发送缓冲区中的日志后退出
+	// 使用关闭而不是发送值，工作协程是否在运行都不会阻塞 Stop
+	done chan struct{}
+	// doneOnce 保证 done 只被关闭一次
+	doneOnce sync.Once
+	// workerDone 在工作协程完成最后一次刷新并退出后关闭
+	workerDone chan struct{}
+	// ctx 是所有发送请求使用的 context，Stop 超时时被取消以中断进行中的请求
+	ctx context.Context
+	// cancel 用于取消 ctx
+	cancel context.CancelFunc
+	// flushReq 用于请求工作协程刷新缓冲区，容量为 1，多次请求会被合并
+	flushReq chan struct{}
+	// sendCalls 用于把需要同步等待结果的发送交给工作协程执行，
+	// 保证工作协程运行期间所有发送都在同一个协程中串行进行
+	sendCalls chan sendCall
+	// targets 是所有推送目标，第一个是 URL 指定的默认目标
+	targets []*target
+	// limiter 限制同时进行的推送数量，为 nil 时不限制
+	limiter *sendLimiter
+	// errorLog 记录客户端自身的诊断信息
+	errorLog ErrorLogger
+	// labelWarnings 记录已经输出过警告的非法标签名，每个标签名只警告一次
+	labelWarnings sync.Map
 	// closed 是用于标记客户端是否已关闭的标志
 	closed atomic.Bool
 	// started 是用于标记客户端是否已启动的标志
 	started atomic.Bool
+	// lastSuccess 是最近一次发送成功的Unix纳秒时间戳
+	lastSuccess atomic.Int64
+	// lastFailure 是最近一次发送失败的Unix纳秒时间戳
+	lastFailure atomic.Int64
+	// lastErr 是最近一次发送失败的错误
+	lastErr atomic.Pointer[error]
+	// drops 记录最近的日志丢弃事件
+	drops *dropLog
+	// counters 保存运行统计
+	counters counters
+	// startedAt 是客户端启动时的Unix纳秒时间戳
+	startedAt atomic.Int64
+	// paused 是用于标记是否暂停发送的标志
+	paused atomic.Bool
+	// lastRetryAfter 是最近一次 429 响应中 Retry-After 的时长（纳秒）
+	lastRetryAfter atomic.Int64
+	// lastRateLimitWarn 是最近一次输出限流警告的Unix纳秒时间戳
+	lastRateLimitWarn atomic.Int64
+	// rateLimitedUntil 是被限流后允许再次自动刷新的Unix纳秒时间戳
+	rateLimitedUntil atomic.Int64
+	// overflowRecorded 是已经记录到丢弃事件中的缓冲区溢出条数
+	overflowRecorded atomic.Uint64
+	// lastFlush 是最近一次刷新的Unix纳秒时间戳
+	lastFlush atomic.Int64
+	// flushPending 表示是否已有一次被推迟的刷新在等待执行
+	flushPending atomic.Bool
+	// sends 跟踪所有正在进行的发送，包括 Flush、Resume、PushBatch 和被推迟的刷新
+	sends inflight
+	// latency 是推送请求的耗时直方图
+	latency latencyHistogram
+	// minLevel 是当前的最低日志级别，初始值为 MinLevel，可以通过 SetMinLevel 修改
+	minLevel atomic.Int32
+	// levelLabel 是级别标签名，为空时不添加级别标签
+	levelLabel string
 }
 
 // NewClient 创建并初始化一个新的Loki客户端实例
@@ -44,6 +113,19 @@ func NewClient(config ClientConfig) (*Client, error) {
 	if config.URL == "" {
 		return nil, fmt.Errorf("URL is required")
 	}
+	if err := validateLabelNames(config.Labels); err != nil {
+		return nil, err
+	}
+	levelLabel := resolveLevelLabel(config.LevelLabel)
+	if levelLabel != "" && !ValidLabelName(levelLabel) {
+		return nil, fmt.Errorf("invalid level label name %q: must match [a-zA-Z_][a-zA-Z0-9_]*", levelLabel)
+	}
+	if err := checkLabelPolicy(config.LabelPolicy, config.Labels); err != nil {
+		return nil, err
+	}
+	if !validTransport(config.Transport) {
+		return nil, fmt.Errorf("invalid transport %q: must be %q or %q", config.Transport, TransportHTTP, TransportGRPC)
+	}
 
 	if config.BatchSize == 0 {
 		config.BatchSize = 100
@@ -54,21 +136,42 @@ func NewClient(config ClientConfig) (*Client, error) {
 	if config.MaxWaitTime == 0 {
 		config.MaxWaitTime = 10
 	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = 10 * time.Second
+	}
 	if config.MaxWaitTime <= config.MinWaitTime {
 		config.MaxWaitTime = config.MinWaitTime + 1
 	}
 
-	httpClient := config.HTTPClient
-	if httpClient == nil {
-		httpClient = http.DefaultClient
+	errorLog := config.ErrorLogger
+	if errorLog == nil {
+		errorLog = log.Default()
 	}
 
-	return &Client{
+	targets, err := newTargets(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		ctx:        ctx,
+		cancel:     cancel,
 		config:     config,
-		buffer:     pkg.NewBuffer(config.BatchSize),
-		done:       make(chan bool, 1),
-		httpClient: httpClient,
-	}, nil
+		buffer:     pkg.NewBoundedBuffer(config.BatchSize, config.MaxBufferEntries, config.BufferDropPolicy),
+		done:       make(chan struct{}),
+		workerDone: make(chan struct{}),
+		flushReq:   make(chan struct{}, 1),
+		sendCalls:  make(chan sendCall),
+		targets:    targets,
+		limiter:    newSendLimiter(config.MaxConcurrentSends, config.MaxQueuedSends),
+		errorLog:   errorLog,
+		drops:      newDropLog(config.DropEventsSize, config.DropAuditFile, errorLog),
+		levelLabel: levelLabel,
+	}
+	c.minLevel.Store(int32(config.MinLevel))
+	c.buffer.SetMaxBytes(config.MaxBatchBytes)
+	return c, nil
 }
 
 // Debug 记录调试级别的日志
@@ -91,6 +194,24 @@ func (c *Client) Error(message string) error {
 	return c.pushLogWithLevel(message, zapcore.ErrorLevel)
 }
 
+// DPanic 记录 DPanic 级别的日志
+// 只发送日志，不会 panic
+func (c *Client) DPanic(message string) error {
+	return c.pushLogWithLevel(message, zapcore.DPanicLevel)
+}
+
+// Panic 记录 Panic 级别的日志
+// 只发送日志，不会 panic
+func (c *Client) Panic(message string) error {
+	return c.pushLogWithLevel(message, zapcore.PanicLevel)
+}
+
+// Fatal 记录 Fatal 级别的日志
+// 只发送日志，不会退出程序，退出之前应调用 Flush 或 Stop 发送缓冲区中的日志
+func (c *Client) Fatal(message string) error {
+	return c.pushLogWithLevel(message, zapcore.FatalLevel)
+}
+
 // pushLogWithLevel 内部方法，处理带级别的日志推送
 // 参数：
 //   - message: 日志消息内容
@@ -99,55 +220,159 @@ func (c *Client) Error(message string) error {
 // 返回：
 //   - error: 如果客户端未启动或已关闭，或者推送失败则返回错误
 func (c *Client) pushLogWithLevel(message string, level zapcore.Level) error {
+	return c.Push(pkg.LogEntry{
+		Message: message,
+		Level:   level,
+	})
+}
+
+// Push 将一条完整的日志条目加入缓冲区
+// 与 Debug/Info 等方法不同，可以同时指定附加标签和结构化元数据
+// 参数：
+//   - entry: 日志条目，Timestamp 为 0 时使用当前时间
+//
+// 返回：
+//   - error: 只返回缓冲错误，客户端已关闭时为 ErrClientClosed，未启动时为 ErrClientNotStarted。
+//     日志在后台异步发送，发送错误不会从这里返回，需要确认送达时使用 PushBatch 或 Flush，
+//     或者通过 OnSendError 和 LastError 获取
+func (c *Client) Push(entry pkg.LogEntry) error {
 	// 检查是否已关闭或未启动
 	if c.closed.Load() {
-		return fmt.Errorf("client is closed")
+		return ErrClientClosed
 	}
 	if !c.started.Load() {
-		return fmt.Errorf("client is not started")
+		return ErrClientNotStarted
 	}
 
-	if level < c.config.MinLevel {
+	if entry.Level < c.MinLevel() {
 		return nil
 	}
-
-	entry := pkg.LogEntry{
-		Timestamp: time.Now().UnixNano(),
-		Message:   message,
-		Level:     level,
+	if entry.Timestamp == 0 {
+		entry.Timestamp = time.Now().UnixNano()
 	}
+	entry = c.dropInvalidLabels(entry)
 
+	c.counters.logsPushed.Add(1)
+	c.counters.addLevel(entry.Level, 1)
 	if c.buffer.Add(entry) {
-		c.flush()
+		c.requestFlush()
 	}
 	return nil
 }
 
+// requestFlush 通知工作协程刷新缓冲区，不会阻塞
+// 发送和重试等待都在工作协程中进行，不占用写日志的调用方
+func (c *Client) requestFlush() {
+	select {
+	case c.flushReq <- struct{}{}:
+	default:
+		// 已有未处理的请求，本次请求合并到其中
+	}
+}
+
 // Start 启动客户端的后台工作协程
 // 该方法是线程安全的，可以被多次调用
 // 只有第一次调用会真正启动工作协程
 func (c *Client) Start() {
+	c.StartContext(context.Background())
+}
+
+// StartContext 启动客户端的后台工作协程，ctx 结束时自动停止客户端
+// 停止的过程与 Stop 相同：发送缓冲区中的所有日志后退出，最多等待 ShutdownTimeout，
+// 停止失败时使用 ErrorLogger 记录错误。ctx 结束之前仍然可以调用 Stop，两者只会停止一次。
+// 与 Start 一样只有第一次调用生效，之后的调用传入的 ctx 会被忽略
+func (c *Client) StartContext(ctx context.Context) {
 	// 防止重复启动
 	if c.started.Swap(true) {
 		return
 	}
+	c.startedAt.Store(time.Now().UnixNano())
 	go c.worker()
+
+	context.AfterFunc(ctx, func() {
+		if err := c.Stop(context.Background()); err != nil {
+			c.errorLog.Printf("Failed to stop Loki client after context cancellation: %v", err)
+		}
+	})
+}
+
+// signalDone 关闭 done 通知工作协程退出，可以被多次调用
+func (c *Client) signalDone() {
+	c.doneOnce.Do(func() { close(c.done) })
 }
 
 // Stop 停止客户端的后台工作协程
 // 该方法是线程安全的，可以被多次调用
-// 在停止前会确保所有缓存的日志都被发送
-func (c *Client) Stop() {
-	// 如果未启动或已关闭，直接返回
-	if !c.started.Load() || c.closed.Swap(true) {
-		return
+// 工作协程会在退出前发送所有缓存的日志，Stop 等待其完成，
+// 包括 FlushWorkers 和多个推送目标并发进行的所有请求，以及工作协程退出后其他协程中的发送。
+// ctx 结束或超过 ShutdownTimeout 仍未完成时，取消所有进行中的发送请求并返回错误，
+// 被取消的请求中的日志计入丢弃统计，
+// 便于与服务整体的优雅关闭 context 配合使用；正常完成时不会中断任何发送
+func (c *Client) Stop(ctx context.Context) error {
+	// 已关闭时直接返回
+	if c.closed.Swap(true) {
+		return nil
+	}
+	// 未启动时没有需要等待的工作协程，之后再调用 Start 启动的工作协程会立即退出
+	if !c.started.Load() {
+		c.signalDone()
+		return nil
+	}
+
+	// 停止前恢复发送，确保暂停期间积压的日志也被发送
+	c.paused.Store(false)
+
+	if c.config.LogShutdownSummary {
+		// 在最后一次刷新之前加入缓冲区，确保摘要随最后一批日志一起发送
+		c.buffer.Add(c.shutdownSummary())
 	}
+	c.recordOverflow()
+	c.signalDone()
 
-	c.flush() // 最后一次刷新
-	c.done <- true
+	// 等待工作协程完成最后一次刷新
+	timer := time.NewTimer(c.config.ShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.workerDone:
+	case <-ctx.Done():
+		c.cancel()
+		return fmt.Errorf("stop cancelled before final flush: %w", ctx.Err())
+	case <-timer.C:
+		c.cancel()
+		return fmt.Errorf("timed out after %s waiting for final flush", c.config.ShutdownTimeout)
+	}
+
+	// 等待其他协程中正在进行的发送
+	select {
+	case <-c.sends.wait():
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		return fmt.Errorf("stop cancelled before in-flight sends finished: %w", ctx.Err())
+	case <-timer.C:
+		c.cancel()
+		return fmt.Errorf("timed out after %s waiting for in-flight sends", c.config.ShutdownTimeout)
+	}
+}
+
+// shutdownSummary 生成记录本次运行统计的关闭摘要日志
+func (c *Client) shutdownSummary() pkg.LogEntry {
+	stats := c.Stats()
+	summary := map[string]interface{}{
+		"logs_by_level": stats.LogsByLevel,
+		"logs_pushed":   stats.LogsPushed,
+		"batches_sent":  stats.BatchesSent,
+		"send_failures": stats.SendFailures,
+		"dropped":       stats.Dropped,
+		"uptime":        stats.Uptime.String(),
+	}
+	data, _ := json.Marshal(summary)
 
-	// 等待一小段时间确保最后的日志被发送
-	time.Sleep(time.Millisecond * 100)
+	return pkg.LogEntry{
+		Timestamp: time.Now().UnixNano(),
+		Message:   "logger shutdown summary " + string(data),
+		Level:     zapcore.InfoLevel,
+	}
 }
 
 // worker 是后台工作协程的主循环
@@ -155,10 +380,15 @@ func (c *Client) Stop() {
 // 1. 定期检查是否需要发送日志
 // 2. 处理优雅关闭信号
 // 3. 确保日志不会在缓冲区中停留太久
+// 4. 缓冲区写满时响应刷新请求
 func (c *Client) worker() {
-	// 创建定时器，用于周期性检查是否需要发送日志
-	ticker := time.NewTicker(time.Second * time.Duration(c.config.MaxWaitTime))
-	lastFlush := time.Now()
+	defer close(c.workerDone)
+
+	// 以最大等待时间的几分之一为间隔，周期性检查最早一条日志的等待时间
+	// 检查间隔与最大等待时间无关的话，日志最多可能等待接近两倍的最大等待时间
+	maxWait := c.maxWait()
+	interval := entryAgeCheckInterval(maxWait)
+	ticker := time.NewTicker(interval)
 
 	// 确保 ticker 被正确清理
 	defer ticker.Stop()
@@ -166,91 +396,731 @@ func (c *Client) worker() {
 	for {
 		select {
 		case <-c.done:
-			// 在退出前应该再次检查是否有未发送的日志
-			if time.Since(lastFlush) > 0 {
-				c.flush()
-			}
+			// 在退出前发送所有未发送的日志
+			c.flush()
 			return
+		case <-c.flushReq:
+			// 缓冲区已满
+			c.triggerFlush()
+		case call := <-c.sendCalls:
+			call.result <- call.send()
 		case <-ticker.C:
-			// 检查是否超过最大等待时间
-			if time.Since(lastFlush) >= time.Second*time.Duration(c.config.MaxWaitTime) {
+			c.recordOverflow()
+			// 最早一条日志到下一次检查时会超过最大等待时间的话，本次就发送
+			if oldest := c.buffer.Oldest(); !oldest.IsZero() && time.Since(oldest)+interval >= maxWait {
 				c.flush()
-				lastFlush = time.Now()
 			}
 		}
 	}
 }
 
+// maxWait 返回日志在缓冲区中的最长等待时间，取 MaxWaitTime 和 MaxEntryAge 中较小的一个
+func (c *Client) maxWait() time.Duration {
+	maxWait := time.Second * time.Duration(c.config.MaxWaitTime)
+	if c.config.MaxEntryAge > 0 && c.config.MaxEntryAge < maxWait {
+		maxWait = c.config.MaxEntryAge
+	}
+	return maxWait
+}
+
+// entryAgeCheckInterval 返回检查日志停留时间的间隔
+// 取最大等待时间的四分之一，保证日志在超时之前被发送
+func entryAgeCheckInterval(maxAge time.Duration) time.Duration {
+	interval := maxAge / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	return interval
+}
+
+// sendCall 是交给工作协程执行的一次同步发送
+type sendCall struct {
+	// send 执行发送并返回错误
+	send func() error
+	// result 接收发送结果，容量为 1，调用方放弃等待时工作协程也不会阻塞
+	result chan error
+}
+
+// runInWorker 在工作协程中执行 send 并等待结果
+// 客户端未启动或工作协程已退出时不存在并发的发送，直接在当前协程中执行。
+// ctx 结束时不再等待并返回 ctx 的错误，send 应当使用同一个 ctx 以便及时结束
+func (c *Client) runInWorker(ctx context.Context, send func() error) error {
+	if !c.started.Load() {
+		return send()
+	}
+
+	call := sendCall{send: send, result: make(chan error, 1)}
+	select {
+	case c.sendCalls <- call:
+	case <-c.workerDone:
+		return send()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-call.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // flush 将缓冲区中的日志发送到Loki服务器
 // 主要步骤：
 // 1. 从缓冲区获取所有待发送的日志
 // 2. 将日志转换为Loki期望的格式
 // 3. 发送到服务器
+// 暂停期间不发送，日志继续留在缓冲区中；被限流时等到 Retry-After 指定的时间之后再发送，
+// 关闭前的最后一次刷新除外
+// 积压的日志按 BatchSize 分批发送，避免单个请求过大
 func (c *Client) flush() {
-	entries := c.buffer.Flush()
-	if len(entries) == 0 {
+	if c.paused.Load() {
+		return
+	}
+	if !c.closed.Load() && time.Now().UnixNano() < c.rateLimitedUntil.Load() {
+		return
+	}
+	// 处理发送错误
+	c.logSendError(c.sendBuffered(c.ctx))
+}
+
+// logSendError 在没有配置 OnSendError 时记录后台发送的错误
+func (c *Client) logSendError(err error) {
+	if err != nil && c.config.OnSendError == nil {
+		c.errorLog.Printf("Failed to send logs to Loki: %v", err)
+	}
+}
+
+// Flush 立即同步发送缓冲区中的所有日志，并返回发送错误
+// 适用于短生命周期任务在检查点确保日志送达而不关闭客户端的场景。
+// 该方法是线程安全的，发送交给后台工作协程执行，与自动刷新串行进行，
+// 不会与工作协程同时发送。暂停期间返回错误，日志继续留在缓冲区中。
+// 不要在 OnSendError 中调用，它在工作协程中执行，调用会一直等待到 ctx 结束
+func (c *Client) Flush() error {
+	return c.FlushContext(context.Background())
+}
+
+// FlushContext 与 Flush 相同，但 ctx 结束时取消进行中的发送请求和重试等待并返回
+// 用于需要限制等待时间的场景，例如 Fatal 退出程序之前发送最后的日志
+func (c *Client) FlushContext(ctx context.Context) error {
+	if c.paused.Load() {
+		return fmt.Errorf("client is paused")
+	}
+
+	// 客户端被强制关闭时同样取消发送
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+
+	return c.runInWorker(ctx, func() error {
+		return c.sendBuffered(ctx)
+	})
+}
+
+// sendBuffered 取出缓冲区中的所有日志并按 BatchSize 分批发送
+// 返回所有批次发送错误的合并结果
+func (c *Client) sendBuffered(ctx context.Context) error {
+	c.sends.add()
+	defer c.sends.done()
+
+	c.lastFlush.Store(time.Now().UnixNano())
+
+	var errs []error
+	entries := c.limitStreams(c.buffer.Flush())
+	// 分批之前整体排序，保证同一个流的日志在不同批次之间也按时间先后发送
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp < entries[j].Timestamp
+	})
+	for len(entries) > 0 {
+		n := c.batchLen(entries)
+		if err := c.sendEntries(ctx, entries[:n]); err != nil {
+			errs = append(errs, err)
+		}
+		entries = entries[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// batchLen 返回从 entries 开头取出的一个批次的条数
+// 每批最多 BatchSize 条，设置了 MaxBatchBytes 时估计字节数不超过该值，至少包含一条
+func (c *Client) batchLen(entries []pkg.LogEntry) int {
+	n := min(len(entries), c.config.BatchSize)
+	if c.config.MaxBatchBytes <= 0 {
+		return n
+	}
+
+	size := 0
+	for i, entry := range entries[:n] {
+		size += entry.Size()
+		if size > c.config.MaxBatchBytes {
+			return max(i, 1)
+		}
+	}
+	return n
+}
+
+// limitStreams 按 MaxStreamsPerFlush 限制带附加标签的流的个数
+// 超过上限后，新出现的标签集的日志去掉附加标签，并入只按级别划分的流中
+func (c *Client) limitStreams(entries []pkg.LogEntry) []pkg.LogEntry {
+	limit := c.config.MaxStreamsPerFlush
+	if limit <= 0 {
+		return entries
+	}
+
+	streams := make(map[string]struct{})
+	stripped := 0
+	for i := range entries {
+		if len(entries[i].Labels) == 0 {
+			continue
+		}
+		key := streamKey(entries[i], c.levelLabel)
+		if _, ok := streams[key]; ok {
+			continue
+		}
+		if len(streams) < limit {
+			streams[key] = struct{}{}
+			continue
+		}
+		entries[i].Labels = nil
+		stripped++
+	}
+
+	if stripped > 0 {
+		c.errorLog.Printf("Loki flush exceeded MaxStreamsPerFlush (%d), removed labels from %d entries", limit, stripped)
+	}
+	return entries
+}
+
+// triggerFlush 在遵守 MinWaitTime 的前提下触发一次刷新
+// 距离上次刷新已超过 MinWaitTime 时立即在当前协程中刷新；
+// 否则推迟到 MinWaitTime 到期时再次请求工作协程刷新，期间的多次触发合并为一次。
+// 只在工作协程中调用，不会阻塞写日志的调用方
+func (c *Client) triggerFlush() {
+	minWait := time.Second * time.Duration(c.config.MinWaitTime)
+	for {
+		last := c.lastFlush.Load()
+		wait := minWait - time.Since(unixNanoTime(last))
+		if wait > 0 {
+			// 已有等待中的刷新，本次触发合并到其中
+			if c.flushPending.Swap(true) {
+				return
+			}
+			time.AfterFunc(wait, func() {
+				c.flushPending.Store(false)
+				c.requestFlush()
+			})
+			return
+		}
+
+		// 原子地占用本次刷新，并发触发时只有一方立即刷新，其余的重新检查后被推迟
+		if c.lastFlush.CompareAndSwap(last, time.Now().UnixNano()) {
+			c.flush()
+			return
+		}
+	}
+}
+
+// Pause 暂停向Loki发送日志
+// 暂停期间日志仍然正常写入缓冲区，适用于Loki计划内维护等场景，
+// 避免无意义的发送失败和重试。该方法是线程安全的
+func (c *Client) Pause() {
+	c.paused.Store(true)
+}
+
+// Resume 恢复向Loki发送日志，并立即分批发送暂停期间积压的日志
+// 积压的日志由工作协程发送，Resume 等待发送完成后返回。该方法是线程安全的
+func (c *Client) Resume() {
+	if !c.paused.Swap(false) {
 		return
 	}
+	if c.started.Load() && !c.closed.Load() {
+		c.logSendError(c.runInWorker(c.ctx, func() error {
+			c.flush()
+			return nil
+		}))
+	}
+}
+
+// IsPaused 返回是否处于暂停发送状态
+func (c *Client) IsPaused() bool {
+	return c.paused.Load()
+}
+
+// PushBatch 绕过缓冲区立即发送一批日志
+// 适用于需要将一组日志作为整体一起发送的场景。
+// 发送由工作协程执行，与自动刷新串行进行，该方法等待发送完成后返回
+// 参数：
+//   - entries: 要发送的日志条目，低于最低级别的条目会被忽略
+//
+// 返回：
+//   - error: 客户端已关闭或未启动时返回 ErrClientClosed 或 ErrClientNotStarted；
+//     否则返回本批日志的发送错误，为 nil 表示已经送达
+func (c *Client) PushBatch(entries []pkg.LogEntry) error {
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+	if !c.started.Load() {
+		return ErrClientNotStarted
+	}
 
-	// 按日志级别分组
-	levelGroups := make(map[zapcore.Level][][2]string)
+	minLevel := c.MinLevel()
+	filtered := make([]pkg.LogEntry, 0, len(entries))
 	for _, entry := range entries {
-		levelGroups[entry.Level] = append(levelGroups[entry.Level], [2]string{
-			strconv.FormatInt(entry.Timestamp, 10),
-			entry.Message,
-		})
-	}
-
-	// 为每个级别创建单独的流
-	var streams []Stream
-	for level, values := range levelGroups {
-		// 复制标签并添加级别
-		labels := make(map[string]string)
-		for k, v := range c.config.Labels {
-			labels[k] = v
+		if entry.Level >= minLevel {
+			filtered = append(filtered, c.dropInvalidLabels(entry))
 		}
-		// 添加日志级别标签
-		labels["level"] = level.String()
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
 
-		streams = append(streams, Stream{
-			Stream: labels,
-			Values: values,
-		})
+	c.counters.logsPushed.Add(uint64(len(filtered)))
+	for _, entry := range filtered {
+		c.counters.addLevel(entry.Level, 1)
 	}
 
-	req := PushRequest{
-		Streams: streams,
+	// 暂停期间放入缓冲区，恢复后随积压的日志一起发送
+	if c.paused.Load() {
+		for _, entry := range filtered {
+			c.buffer.Add(entry)
+		}
+		return nil
 	}
+	return c.runInWorker(c.ctx, func() error {
+		return c.sendEntries(c.ctx, filtered)
+	})
+}
 
-	// 处理发送错误
-	if err := c.send(req); err != nil {
-		// 这里可以考虑将失败的日志重新加入缓冲区，或者记录错误
-		// 为了避免递归，这里使用标准库的log包记录错误
-		log.Printf("Failed to send logs to Loki: %v", err)
+// sendEntries 发送一批日志
+// 配置了多个 FlushWorkers 时，按流分片后并发发送，同一个流的日志总是在同一个分片中，
+// 因此流内的顺序不受影响。该方法会等待所有分片发送完成后才返回。
+func (c *Client) sendEntries(ctx context.Context, entries []pkg.LogEntry) error {
+	c.sends.add()
+	defer c.sends.done()
+
+	workers := c.config.FlushWorkers
+	if workers <= 1 {
+		return c.sendBatch(ctx, entries)
+	}
+
+	shards := make([][]pkg.LogEntry, workers)
+	for _, entry := range entries {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(c.streamKey(entry)))
+		i := h.Sum32() % uint32(workers)
+		shards[i] = append(shards[i], entry)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, shard []pkg.LogEntry) {
+			defer wg.Done()
+			errs[i] = c.sendBatch(ctx, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// sendBatch 将一批日志发送到所有推送目标
+// 各个目标并发发送、互不影响，某个目标不可用不会阻止其他目标收到日志。
+// 只有所有目标都没有收到的日志才算作丢弃，计入丢弃统计并传给 OnSendError；
+// 单个目标的失败只计入该目标的统计。
+// 所有目标都因限流（429）没有收到的日志不算作丢弃，而是放回缓冲区稍后重新发送。
+// 配置了 MaxConcurrentSends 时先占用推送名额，排队已满时整批放回缓冲区
+// 返回所有失败目标的错误
+func (c *Client) sendBatch(ctx context.Context, entries []pkg.LogEntry) error {
+	queued, err := c.limiter.acquire(ctx, c.closed.Load())
+	if queued {
+		c.counters.queuedSends.Add(1)
+	}
+	if errors.Is(err, ErrSendQueueFull) {
+		c.counters.rejectedSends.Add(1)
+		for _, entry := range entries {
+			c.buffer.Add(entry)
+		}
+		return fmt.Errorf("%w, %d entries returned to buffer", err, len(entries))
+	}
+	if err != nil {
+		c.counters.dropped.Add(uint64(len(entries)))
+		c.drops.record("", DropReasonSendFailure, entries, err)
+		if c.config.OnSendError != nil {
+			c.config.OnSendError(entries, err)
+		}
+		return err
+	}
+	defer c.limiter.release()
+
+	failures := make([]int, len(entries))
+	limits := make([]int, len(entries))
+	limited := make([][]int, len(c.targets))
+	errs := make([]error, len(c.targets))
+	if len(c.targets) == 1 {
+		var failed []int
+		failed, limited[0], errs[0] = c.sendToTarget(ctx, c.targets[0], entries)
+		for _, i := range failed {
+			failures[i]++
+		}
+		for _, i := range limited[0] {
+			limits[i]++
+		}
+	} else {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for i, t := range c.targets {
+			wg.Add(1)
+			go func(i int, t *target) {
+				defer wg.Done()
+				failed, rateLimited, err := c.sendToTarget(ctx, t, entries)
+				errs[i] = err
+				limited[i] = rateLimited
+				mu.Lock()
+				for _, j := range failed {
+					failures[j]++
+				}
+				for _, j := range rateLimited {
+					limits[j]++
+				}
+				mu.Unlock()
+			}(i, t)
+		}
+		wg.Wait()
+	}
+
+	err = errors.Join(errs...)
+	// 关闭之后放回缓冲区的日志不会再被发送，因此按丢弃处理
+	requeue := make([]bool, len(entries))
+	var requeued, dropped []pkg.LogEntry
+	for i, n := range failures {
+		if n != len(c.targets) {
+			continue
+		}
+		if limits[i] == n && !c.closed.Load() {
+			requeue[i] = true
+			requeued = append(requeued, entries[i])
+			continue
+		}
+		dropped = append(dropped, entries[i])
+	}
+	// 被限流但没有放回缓冲区的日志，计入对应目标的丢弃统计
+	for i, indexes := range limited {
+		var missed []pkg.LogEntry
+		for _, j := range indexes {
+			if !requeue[j] {
+				missed = append(missed, entries[j])
+			}
+		}
+		if len(missed) > 0 {
+			c.targets[i].dropped.Add(uint64(len(missed)))
+			c.drops.record(c.targets[i].name, DropReasonSendFailure, missed, errs[i])
+		}
+	}
+	if len(requeued) > 0 {
+		c.requeueRateLimited(requeued)
+	}
+	if len(dropped) > 0 {
+		c.counters.dropped.Add(uint64(len(dropped)))
+		if c.config.OnSendError != nil {
+			c.config.OnSendError(dropped, err)
+		}
+	}
+	return err
+}
+
+// sendToTarget 将日志发送到指定目标
+// 配置了 LevelTenants 时按租户拆分为多个请求
+// 返回：
+//   - []int: 该目标没有收到的日志在 entries 中的下标
+//   - []int: 其中因限流没有收到的日志的下标
+//   - error: 所有请求的错误
+func (c *Client) sendToTarget(ctx context.Context, t *target, entries []pkg.LogEntry) ([]int, []int, error) {
+	groups := make(map[string][]int)
+	var tenants []string
+	for i, entry := range entries {
+		tenant, ok := c.config.LevelTenants[entry.Level]
+		if !ok {
+			tenant = t.tenantID
+		}
+		if _, ok := groups[tenant]; !ok {
+			tenants = append(tenants, tenant)
+		}
+		groups[tenant] = append(groups[tenant], i)
+	}
+
+	var failed, limited []int
+	var errs []error
+	for _, tenant := range tenants {
+		indexes := groups[tenant]
+		group := entries
+		if len(tenants) > 1 {
+			group = make([]pkg.LogEntry, len(indexes))
+			for i, j := range indexes {
+				group[i] = entries[j]
+			}
+		}
+		if err := c.sendToTenant(ctx, t, tenant, group); err != nil {
+			failed = append(failed, indexes...)
+			if isRateLimited(err) {
+				limited = append(limited, indexes...)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return failed, limited, errors.Join(errs...)
+}
+
+// sendToTenant 将日志按级别和附加标签分组为流，作为一个请求发送到指定目标的指定租户
+// 同时记录最近一次发送成功或失败的时间，失败时计入该目标的丢弃统计；
+// 被限流的日志可能被放回缓冲区，由 sendBatch 决定是否计入丢弃统计
+func (c *Client) sendToTenant(ctx context.Context, t *target, tenant string, entries []pkg.LogEntry) error {
+	data, err := c.encodeRequest(entries, t.labels)
+	if err != nil {
+		return err
+	}
+
+	if err := c.sendWithRetry(ctx, t, tenant, data); err != nil {
+		c.lastFailure.Store(time.Now().UnixNano())
+		c.counters.sendFailures.Add(1)
+		t.sendFailures.Add(1)
+		if !isRateLimited(err) {
+			t.dropped.Add(uint64(len(entries)))
+			c.drops.record(t.name, DropReasonSendFailure, entries, err)
+		}
+		err = fmt.Errorf("target %s: %w", t.name, err)
+		c.lastErr.Store(&err)
+		return err
+	}
+	c.lastSuccess.Store(time.Now().UnixNano())
+	c.counters.batchesSent.Add(1)
+	c.counters.entriesSent.Add(uint64(len(entries)))
+	t.batchesSent.Add(1)
+	t.entriesSent.Add(uint64(len(entries)))
+	return nil
+}
+
+// streamKey 返回日志所在流的键，设置了 LabelsFunc 时按其返回的标签集计算
+func (c *Client) streamKey(entry pkg.LogEntry) string {
+	if labels := customLabels(entry, c.config.LabelsFunc, c.warnInvalidLabel); labels != nil {
+		return labelSetKey(labels)
 	}
+	return streamKey(entry, c.levelLabel)
 }
 
-// send 负责将日志请求发送到Loki服务器
+// encodeRequest 按配置的协议将日志编码为推送请求体
+func (c *Client) encodeRequest(entries []pkg.LogEntry, labels map[string]string) ([]byte, error) {
+	opts := EncodeOptions{
+		AddEntryID: c.config.AddEntryID,
+		// 日志来自多个 goroutine，同一个流中的日志可能略微乱序
+		SortByTimestamp: true,
+		TimestampFormat: c.config.TimestampFormat,
+		LevelLabel:      c.config.LevelLabel,
+		LabelsFunc:      c.config.LabelsFunc,
+		warnLabel:       c.warnInvalidLabel,
+	}
+	if c.grpc() {
+		opts.TimestampFormat = TimestampUnixNano
+		return marshalPushRequest(BuildPushRequest(entries, labels, opts))
+	}
+	if c.config.Protocol == ProtocolProtobuf {
+		// logproto 中的时间戳是纳秒精度的 Timestamp 消息
+		opts.TimestampFormat = TimestampUnixNano
+		return encodeProtobuf(BuildPushRequest(entries, labels, opts))
+	}
+
+	data, err := json.Marshal(BuildPushRequest(entries, labels, opts))
+	if err != nil {
+		return nil, fmt.Errorf("marshal request failed: %v", err)
+	}
+	return data, nil
+}
+
+// MinLevel 返回当前的最低日志级别
+func (c *Client) MinLevel() zapcore.Level {
+	return zapcore.Level(c.minLevel.Load())
+}
+
+// SetMinLevel 在运行时修改最低日志级别，只影响之后推送的日志
+func (c *Client) SetMinLevel(level zapcore.Level) {
+	c.minLevel.Store(int32(level))
+}
+
+// IsRunning 返回后台工作协程是否正在运行
+func (c *Client) IsRunning() bool {
+	return c.started.Load() && !c.closed.Load()
+}
+
+// BufferLen 返回缓冲区中等待发送的日志条数
+func (c *Client) BufferLen() int {
+	return c.buffer.Len()
+}
+
+// BufferCap 返回缓冲区的容量
+// 设置了 MaxBufferEntries 时为该上限，否则为批量发送的日志条数
+func (c *Client) BufferCap() int {
+	return c.buffer.Cap()
+}
+
+// BatchSize 返回批量发送的日志条数
+func (c *Client) BatchSize() int {
+	return c.config.BatchSize
+}
+
+// LastSuccess 返回最近一次发送成功的时间，从未成功时返回零值
+func (c *Client) LastSuccess() time.Time {
+	return unixNanoTime(c.lastSuccess.Load())
+}
+
+// LastFailure 返回最近一次发送失败的时间，从未失败时返回零值
+func (c *Client) LastFailure() time.Time {
+	return unixNanoTime(c.lastFailure.Load())
+}
+
+// LastError 返回最近一次发送失败的错误，从未失败时返回 nil
+// 发送成功不会清除该错误，与 LastSuccess 比较 LastFailure 可以判断之后是否已经恢复。
+// 多个协程共用客户端时，错误不一定来自当前协程写入的日志
+func (c *Client) LastError() error {
+	if err := c.lastErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// DroppedEvents 按时间顺序返回最近的日志丢弃事件
+// 只保留最近 DropEventsSize 条，可用于证明日志是否发生过丢失
+func (c *Client) DroppedEvents() []DropEvent {
+	return c.drops.snapshot()
+}
+
+// DroppedCount 返回被丢弃的日志总条数
+// 包括发送失败丢弃的日志和缓冲区超过 MaxBufferEntries 时丢弃的日志
+func (c *Client) DroppedCount() uint64 {
+	return c.counters.dropped.Load() + c.buffer.DroppedCount()
+}
+
+// recordOverflow 将上次记录以来缓冲区溢出丢弃的日志汇总为一条丢弃事件
+// 由工作协程周期性调用，避免逐条记录产生大量事件
+func (c *Client) recordOverflow() {
+	dropped := c.buffer.DroppedCount()
+	recorded := c.overflowRecorded.Load()
+	if dropped <= recorded || !c.overflowRecorded.CompareAndSwap(recorded, dropped) {
+		return
+	}
+	c.drops.recordCount("", DropReasonBufferOverflow, int(dropped-recorded), "", nil)
+}
+
+// unixNanoTime 将Unix纳秒时间戳转换为时间，0 表示零值时间
+func unixNanoTime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// sendWithRetry 发送请求，失败时按配置的次数重试
+// 只有分类器认为可以重试的错误才会重试，两次重试之间的等待时间按指数增长。
+// 被限流（429）时不在这里重试，日志放回缓冲区后按 Retry-After 稍后重新发送
+func (c *Client) sendWithRetry(ctx context.Context, t *target, tenant string, data []byte) error {
+	isRetryable := c.config.IsRetryable
+	if isRetryable == nil {
+		isRetryable = IsRetryable
+	}
+
+	// 幂等键在重试之间保持不变，便于服务端识别重复的推送
+	var idempotencyKey string
+	if c.config.IdempotencyHeader != "" {
+		idempotencyKey = IdempotencyKey(data)
+	}
+
+	// 只压缩一次，重试时复用压缩后的请求体
+	data, err := c.compressBody(data)
+	if err != nil {
+		return err
+	}
+
+	err = c.send(ctx, t, tenant, data, idempotencyKey)
+	for attempt := 0; err != nil && attempt < c.config.MaxRetries && !isRateLimited(err) && isRetryable(err); attempt++ {
+		// 等待重试期间客户端被强制关闭时放弃重试
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryDelay(c.config.RetryBackoff, attempt)):
+		}
+		c.counters.retries.Add(1)
+		err = c.send(ctx, t, tenant, data, idempotencyKey)
+	}
+	return err
+}
+
+// IdempotencyKey 根据编码后的请求体计算幂等键
+// 相同的请求体总是得到相同的键
+func IdempotencyKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// send 负责将编码后的日志请求发送到指定的Loki服务器
 // 参数：
-//   - req: 要发送的日志请求
+//   - t: 推送目标
+//   - tenant: X-Scope-OrgID 请求头的值，为空时不发送
+//   - data: JSON 编码并按配置压缩后的推送请求
+//   - idempotencyKey: 幂等键，为空时不设置幂等请求头
 //
 // 返回：
 //   - error: 发送过程中的错误，如果成功则为nil
-func (c *Client) send(req PushRequest) error {
-	data, err := json.Marshal(req)
+func (c *Client) send(ctx context.Context, t *target, tenant string, data []byte, idempotencyKey string) error {
+	if c.grpc() {
+		return c.sendGRPC(ctx, t, tenant, data, idempotencyKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.pushURL, bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("marshal request failed: %v", err)
+		return fmt.Errorf("create request failed: %w", err)
+	}
+	if c.config.Protocol == ProtocolProtobuf {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.useGzip() {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+	if err := t.auth.apply(req); err != nil {
+		return err
+	}
+	if idempotencyKey != "" {
+		req.Header.Set(c.config.IdempotencyHeader, idempotencyKey)
 	}
 
-	resp, err := c.httpClient.Post(c.config.URL+"/loki/api/v1/push", "application/json", bytes.NewBuffer(data))
+	start := time.Now()
+	resp, err := t.httpClient.Do(req)
+	c.latency.observe(time.Since(start))
 	if err != nil {
-		return fmt.Errorf("send request failed: %v", err)
+		return fmt.Errorf("send request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.recordRateLimit(t, resp)
+	}
+
+	if c.config.ValidateResponse != nil {
+		return c.config.ValidateResponse(resp)
+	}
+
 	if resp.StatusCode != http.StatusNoContent {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -0,0 +1,1179 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestValidateResponse(t *testing.T) {
+	okWithErrorBody := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"status":"error","error":"quota exceeded"}`)
+	}
+	bodyValidator := func(resp *http.Response) error {
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode/100 != 2 || strings.Contains(string(body), `"status":"error"`) {
+			return errors.New("gateway rejected push: " + string(body))
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name      string
+		respond   func(w http.ResponseWriter, r *http.Request)
+		validate  func(*http.Response) error
+		wantError bool
+	}{
+		{"default accepts 204", nil, nil, false},
+		{"default rejects 200", respondStatus(http.StatusOK), nil, true},
+		{"custom rejects 200 with error body", okWithErrorBody, bodyValidator, true},
+		{"custom accepts 200", respondStatus(http.StatusOK), bodyValidator, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeLoki(t, tt.respond)
+			c := newStartedClient(t, ClientConfig{
+				URL:              server.URL,
+				ValidateResponse: tt.validate,
+				OnSendError:      func([]pkg.LogEntry, error) {},
+			})
+
+			_ = c.Info("hello")
+			err := c.Flush()
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Flush() error = %v, wantError %v", err, tt.wantError)
+			}
+
+			stats := c.Stats()
+			if tt.wantError && (stats.SendFailures != 1 || stats.BatchesSent != 0) {
+				t.Errorf("stats = %+v, want one failure", stats)
+			}
+			if !tt.wantError && (stats.SendFailures != 0 || stats.BatchesSent != 1) {
+				t.Errorf("stats = %+v, want one batch sent", stats)
+			}
+		})
+	}
+}
+
+func TestFlushWorkersPreserveStreamOrder(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 1000, FlushWorkers: 4})
+
+	const streams, perStream = 20, 25
+	for i := 0; i < perStream; i++ {
+		for s := 0; s < streams; s++ {
+			_ = c.Push(pkg.LogEntry{
+				Message: strconv.Itoa(i),
+				Level:   zapcore.InfoLevel,
+				Labels:  map[string]string{"stream": strconv.Itoa(s)},
+			})
+		}
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	pushes := server.Pushes()
+	if len(pushes) < 2 {
+		t.Errorf("got %d requests, want streams sharded across several requests", len(pushes))
+	}
+	next := make(map[string]int)
+	for _, line := range server.Lines() {
+		s := line.Labels["stream"]
+		if line.Line != strconv.Itoa(next[s]) {
+			t.Fatalf("stream %s: got line %s, want %d", s, line.Line, next[s])
+		}
+		next[s]++
+	}
+	if len(next) != streams {
+		t.Fatalf("got %d streams, want %d", len(next), streams)
+	}
+	for s, n := range next {
+		if n != perStream {
+			t.Errorf("stream %s: got %d lines, want %d", s, n, perStream)
+		}
+	}
+}
+
+func BenchmarkFlushWorkers(b *testing.B) {
+	// 模拟服务端处理时间与请求体大小成正比（每 KB 100µs）
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		time.Sleep(time.Duration(n/1024) * 100 * time.Microsecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	const streams = 64
+	entries := make([]pkg.LogEntry, 0, streams*50)
+	for i := 0; i < 50; i++ {
+		for s := 0; s < streams; s++ {
+			entries = append(entries, pkg.LogEntry{
+				Timestamp: time.Now().UnixNano(),
+				Message:   "benchmark entry",
+				Level:     zapcore.InfoLevel,
+				Labels:    map[string]string{"stream": strconv.Itoa(s)},
+			})
+		}
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run("workers="+strconv.Itoa(workers), func(b *testing.B) {
+			c, err := NewClient(ClientConfig{URL: server.URL, FlushWorkers: workers})
+			if err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				if err := c.sendEntries(context.Background(), entries); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLevelTenants(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{
+		URL:          server.URL,
+		TenantID:     "app",
+		LevelTenants: map[zapcore.Level]string{zapcore.ErrorLevel: "compliance"},
+	})
+
+	for _, level := range []zapcore.Level{zapcore.InfoLevel, zapcore.ErrorLevel, zapcore.WarnLevel} {
+		_ = c.Push(pkg.LogEntry{Message: level.String(), Level: level})
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	pushes := server.Pushes()
+	if len(pushes) != 2 {
+		t.Fatalf("got %d requests, want one per tenant", len(pushes))
+	}
+	want := map[string]string{"info": "app", "warn": "app", "error": "compliance"}
+	for _, p := range pushes {
+		tenant := p.Header.Get("X-Scope-OrgID")
+		for _, line := range p.Lines {
+			if want[line.Line] != tenant {
+				t.Errorf("%s entry sent to tenant %q, want %q", line.Line, tenant, want[line.Line])
+			}
+			delete(want, line.Line)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("entries not received: %v", want)
+	}
+}
+
+func TestShutdownSummary(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c, err := NewClient(ClientConfig{URL: server.URL, LogShutdownSummary: true})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	c.Start()
+
+	_ = c.Info("one")
+	_ = c.Info("two")
+	_ = c.Error("three")
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	pushes := server.Pushes()
+	if len(pushes) != 1 {
+		t.Fatalf("got %d requests, want the summary in the final push", len(pushes))
+	}
+	var summary string
+	for _, line := range pushes[0].Lines {
+		if strings.HasPrefix(line.Line, "logger shutdown summary ") {
+			summary = strings.TrimPrefix(line.Line, "logger shutdown summary ")
+		}
+	}
+	if summary == "" {
+		t.Fatalf("summary entry missing from %+v", pushes[0].Lines)
+	}
+
+	var got struct {
+		LogsByLevel map[string]uint64 `json:"logs_by_level"`
+		LogsPushed  uint64            `json:"logs_pushed"`
+		Uptime      string            `json:"uptime"`
+	}
+	if err := json.Unmarshal([]byte(summary), &got); err != nil {
+		t.Fatalf("unmarshal summary %q: %v", summary, err)
+	}
+	if got.LogsByLevel["info"] != 2 || got.LogsByLevel["error"] != 1 || got.LogsPushed != 3 {
+		t.Errorf("summary = %+v, want 2 info, 1 error, 3 pushed", got)
+	}
+	if _, err := time.ParseDuration(got.Uptime); err != nil {
+		t.Errorf("uptime %q: %v", got.Uptime, err)
+	}
+}
+
+func TestNoShutdownSummaryByDefault(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c, err := NewClient(ClientConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	c.Start()
+	_ = c.Info("one")
+	_ = c.Stop(context.Background())
+
+	for _, line := range server.Lines() {
+		if strings.HasPrefix(line.Line, "logger shutdown summary") {
+			t.Errorf("unexpected summary entry %q", line.Line)
+		}
+	}
+}
+
+func TestMaxEntryAge(t *testing.T) {
+	const maxAge = 200 * time.Millisecond
+
+	received := make(chan time.Time, 16)
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		received <- time.Now()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 1000, MaxEntryAge: maxAge})
+
+	// 日志条数远小于 BatchSize，MaxWaitTime 为默认的 10 秒，只有 MaxEntryAge 会触发发送
+	for i := 0; i < 5; i++ {
+		pushed := time.Now()
+		_ = c.Push(pkg.LogEntry{Message: strconv.Itoa(i), Level: zapcore.InfoLevel})
+
+		// 检查间隔为 maxAge/4，再留出发送请求的余量
+		select {
+		case at := <-received:
+			if latency := at.Sub(pushed); latency > maxAge+maxAge/2 {
+				t.Errorf("entry %d reached the server after %s, want within %s", i, latency, maxAge)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("entry %d not sent within 2s", i)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if got := len(server.Lines()); got != 5 {
+		t.Errorf("got %d lines, want 5", got)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 10, MaxEntryAge: 20 * time.Millisecond})
+
+	c.Pause()
+	if !c.IsPaused() || !c.Stats().Paused {
+		t.Fatal("client not reported as paused")
+	}
+	for i := 0; i < 35; i++ {
+		_ = c.Push(pkg.LogEntry{Message: strconv.Itoa(i), Level: zapcore.InfoLevel})
+	}
+	// 缓冲区已满且日志超过 MaxEntryAge，未暂停时早已发送
+	time.Sleep(100 * time.Millisecond)
+
+	if got := len(server.Pushes()); got != 0 {
+		t.Fatalf("got %d requests while paused, want 0", got)
+	}
+	if err := c.Flush(); err == nil {
+		t.Error("Flush() while paused error = nil, want error")
+	}
+	if got := c.BufferLen(); got != 35 {
+		t.Errorf("BufferLen() = %d while paused, want 35", got)
+	}
+
+	c.Resume()
+	if c.IsPaused() {
+		t.Error("client still paused after Resume")
+	}
+	pushes := server.Pushes()
+	if len(pushes) != 4 {
+		t.Errorf("got %d requests after Resume, want backlog sent in 4 chunks of BatchSize", len(pushes))
+	}
+	for i, line := range server.Lines() {
+		if line.Line != strconv.Itoa(i) {
+			t.Fatalf("line %d = %q, want %d", i, line.Line, i)
+		}
+	}
+	if got := len(server.Lines()); got != 35 {
+		t.Errorf("got %d lines, want 35", got)
+	}
+}
+
+func TestPauseRespectsBufferCap(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 10, MaxBufferEntries: 20})
+
+	c.Pause()
+	for i := 0; i < 35; i++ {
+		_ = c.Push(pkg.LogEntry{Message: strconv.Itoa(i), Level: zapcore.InfoLevel})
+	}
+	if got := c.DroppedCount(); got != 15 {
+		t.Errorf("DroppedCount() = %d, want 15", got)
+	}
+
+	c.Resume()
+	lines := server.Lines()
+	if len(lines) != 20 {
+		t.Fatalf("got %d lines, want 20", len(lines))
+	}
+	if lines[0].Line != "15" {
+		t.Errorf("first line = %q, want the newest 20 starting at 15", lines[0].Line)
+	}
+}
+
+func TestOnSendError(t *testing.T) {
+	server := newFakeLoki(t, respondStatus(http.StatusBadRequest))
+	output := captureLog(t)
+
+	var gotEntries []pkg.LogEntry
+	var gotErr error
+	c, err := NewClient(ClientConfig{
+		URL: server.URL,
+		OnSendError: func(entries []pkg.LogEntry, err error) {
+			gotEntries = append(gotEntries, entries...)
+			gotErr = err
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	c.Start()
+	_ = c.Info("first")
+	_ = c.Error("second")
+	_ = c.Stop(context.Background())
+
+	if len(gotEntries) != 2 || gotEntries[0].Message != "first" || gotEntries[1].Message != "second" {
+		t.Errorf("callback entries = %+v, want both dropped entries", gotEntries)
+	}
+	var statusErr *StatusError
+	if !errors.As(gotErr, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("callback error = %v, want a 400 StatusError", gotErr)
+	}
+	if output.Len() != 0 {
+		t.Errorf("error logged although OnSendError is set: %s", output)
+	}
+}
+
+func TestLastError(t *testing.T) {
+	server := newFakeLoki(t, respondStatus(http.StatusBadRequest))
+
+	c, err := NewClient(ClientConfig{URL: server.URL, OnSendError: func([]pkg.LogEntry, error) {}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Info("too early"); !errors.Is(err, ErrClientNotStarted) {
+		t.Errorf("Info() before Start error = %v, want ErrClientNotStarted", err)
+	}
+
+	c.Start()
+	// 缓冲成功，发送错误不从写日志的方法返回
+	if err := c.Info("hello"); err != nil {
+		t.Errorf("Info() error = %v, want nil", err)
+	}
+	if err := c.LastError(); err != nil {
+		t.Errorf("LastError() before send = %v, want nil", err)
+	}
+	if err := c.Flush(); err == nil {
+		t.Error("Flush() error = nil, want send error")
+	}
+	var statusErr *StatusError
+	if err := c.LastError(); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("LastError() = %v, want a 400 StatusError", err)
+	}
+
+	_ = c.Stop(context.Background())
+	if err := c.Info("too late"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Info() after Stop error = %v, want ErrClientClosed", err)
+	}
+	if err := c.PushBatch([]pkg.LogEntry{{Message: "too late"}}); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("PushBatch() after Stop error = %v, want ErrClientClosed", err)
+	}
+}
+
+func TestSendErrorLoggedByDefault(t *testing.T) {
+	server := newFakeLoki(t, respondStatus(http.StatusBadRequest))
+	output := captureLog(t)
+
+	c, err := NewClient(ClientConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	c.Start()
+	_ = c.Info("hello")
+	_ = c.Stop(context.Background())
+
+	if !strings.Contains(output.String(), "Failed to send logs to Loki") {
+		t.Errorf("log output = %q, want the send error", output)
+	}
+}
+
+func TestTriggerFlushClaimsSlotOnce(t *testing.T) {
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 1000, MinWaitTime: 1})
+
+	const callers = 20
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_ = c.Push(pkg.LogEntry{Message: strconv.Itoa(i), Level: zapcore.InfoLevel})
+			c.triggerFlush()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	// 只有一次立即刷新，其余的被推迟到 MinWaitTime 之后
+	if got := len(server.Pushes()); got != 1 {
+		t.Errorf("got %d immediate requests from %d concurrent triggers, want 1", got, callers)
+	}
+
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := len(server.Lines()); got != callers {
+		t.Errorf("got %d lines after Stop, want %d", got, callers)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 1000})
+
+	if err := c.Flush(); err != nil || len(server.Pushes()) != 0 {
+		t.Fatalf("Flush() on empty buffer = %v with %d requests, want no request", err, len(server.Pushes()))
+	}
+
+	_ = c.Info("checkpoint")
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if lines := server.Lines(); len(lines) != 1 || lines[0].Line != "checkpoint" {
+		t.Errorf("lines after Flush = %+v, want the buffered entry", lines)
+	}
+	if c.BufferLen() != 0 {
+		t.Errorf("BufferLen() = %d after Flush, want 0", c.BufferLen())
+	}
+	if !c.IsRunning() {
+		t.Error("Flush stopped the client")
+	}
+}
+
+func TestFlushReturnsSendError(t *testing.T) {
+	server := newFakeLoki(t, respondStatus(http.StatusInternalServerError))
+	c := newStartedClient(t, ClientConfig{URL: server.URL, OnSendError: func([]pkg.LogEntry, error) {}})
+
+	_ = c.Info("hello")
+	var statusErr *StatusError
+	if err := c.Flush(); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Flush() error = %v, want a 500 StatusError", err)
+	}
+}
+
+func TestFlushConcurrentWithWorker(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 7, MinWaitTime: 1, MaxEntryAge: 10 * time.Millisecond})
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				_ = c.Push(pkg.LogEntry{Message: strconv.Itoa(w*perWriter + i), Level: zapcore.InfoLevel})
+				if i%10 == 0 {
+					_ = c.Flush()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	seen := make(map[string]int)
+	for _, line := range server.Lines() {
+		seen[line.Line]++
+	}
+	if len(seen) != writers*perWriter {
+		t.Errorf("got %d distinct lines, want %d", len(seen), writers*perWriter)
+	}
+	for line, n := range seen {
+		if n != 1 {
+			t.Errorf("line %s sent %d times, want once", line, n)
+		}
+	}
+}
+
+func TestStopWaitsForInflightSends(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(c *Client) error
+	}{
+		{"Flush", func(c *Client) error {
+			_ = c.Info("hello")
+			return c.Flush()
+		}},
+		{"PushBatch", func(c *Client) error {
+			return c.PushBatch([]pkg.LogEntry{{Message: "hello", Level: zapcore.InfoLevel}})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{}, 1)
+			var completed atomic.Bool
+			server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+				started <- struct{}{}
+				time.Sleep(100 * time.Millisecond)
+				completed.Store(true)
+				w.WriteHeader(http.StatusNoContent)
+			})
+			c := newStartedClient(t, ClientConfig{URL: server.URL})
+
+			go func() { _ = tt.send(c) }()
+			<-started
+
+			if err := c.Stop(context.Background()); err != nil {
+				t.Fatalf("Stop() error = %v", err)
+			}
+			if !completed.Load() {
+				t.Error("Stop returned before the in-flight send completed")
+			}
+		})
+	}
+}
+
+func TestStopTimesOutOnInflightSends(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{URL: server.URL, ShutdownTimeout: 50 * time.Millisecond})
+
+	_ = c.Info("hello")
+	go func() { _ = c.Flush() }()
+	<-started
+
+	begin := time.Now()
+	// Flush 在工作协程中执行，Stop 在等待最后一次刷新时超时
+	err := c.Stop(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Stop() error = %v, want shutdown timeout", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 250*time.Millisecond {
+		t.Errorf("Stop took %s, want about the 50ms ShutdownTimeout", elapsed)
+	}
+}
+
+func TestMaxStreamsPerFlush(t *testing.T) {
+	logs := captureLog(t)
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, MaxStreamsPerFlush: 2})
+
+	for _, route := range []string{"a", "b", "a", "c", "d", "c"} {
+		_ = c.Push(pkg.LogEntry{
+			Message: route,
+			Level:   zapcore.InfoLevel,
+			Labels:  map[string]string{"route": route},
+		})
+	}
+	_ = c.Push(pkg.LogEntry{Message: "plain", Level: zapcore.InfoLevel})
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	lines := server.Lines()
+	if len(lines) != 7 {
+		t.Fatalf("got %d lines, want 7", len(lines))
+	}
+	for _, line := range lines {
+		route, ok := line.Labels["route"]
+		switch line.Line {
+		case "a", "b":
+			if route != line.Line {
+				t.Errorf("entry %q route label = %q, want it kept", line.Line, route)
+			}
+		default:
+			if ok {
+				t.Errorf("entry %q route label = %q, want it removed", line.Line, route)
+			}
+		}
+	}
+	if !strings.Contains(logs.String(), "removed labels from 3 entries") {
+		t.Errorf("log output = %q, want stream limit warning", logs.String())
+	}
+}
+
+func TestSetMinLevel(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, MinLevel: zapcore.WarnLevel})
+
+	_ = c.Push(pkg.LogEntry{Message: "dropped", Level: zapcore.InfoLevel})
+	c.SetMinLevel(zapcore.DebugLevel)
+	_ = c.Push(pkg.LogEntry{Message: "kept", Level: zapcore.InfoLevel})
+	_ = c.PushBatch([]pkg.LogEntry{{Message: "batched", Level: zapcore.DebugLevel}})
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if c.MinLevel() != zapcore.DebugLevel {
+		t.Errorf("MinLevel() = %s, want debug", c.MinLevel())
+	}
+	var got []string
+	for _, line := range server.Lines() {
+		got = append(got, line.Line)
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "batched,kept" {
+		t.Errorf("lines = %v, want entries pushed after SetMinLevel", got)
+	}
+}
+
+func TestStatsCounters(t *testing.T) {
+	var calls atomic.Int32
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		// 每个请求的第一次发送失败，重试成功
+		if calls.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{
+		URL:          server.URL,
+		BatchSize:    3,
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+	})
+
+	for i := 0; i < 5; i++ {
+		_ = c.Push(pkg.LogEntry{Message: strconv.Itoa(i), Level: zapcore.InfoLevel})
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	stats := c.Stats()
+	want := Stats{LogsPushed: 5, BatchesSent: 2, EntriesSent: 5, Retries: 2}
+	got := Stats{
+		LogsPushed:   stats.LogsPushed,
+		BatchesSent:  stats.BatchesSent,
+		EntriesSent:  stats.EntriesSent,
+		Retries:      stats.Retries,
+		SendFailures: stats.SendFailures,
+		Dropped:      stats.Dropped,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestMaxBatchBytes(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 100, MaxBatchBytes: 250, MaxWaitTime: 60})
+
+	// 每条日志的估计大小为 100 字节
+	entry := func(i int) pkg.LogEntry {
+		return pkg.LogEntry{Message: strconv.Itoa(i) + strings.Repeat("x", 80), Level: zapcore.InfoLevel, Timestamp: int64(i + 1)}
+	}
+	for i := 0; i < 3; i++ {
+		_ = c.Push(entry(i))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.Lines()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := len(server.Lines()); got != 3 {
+		t.Fatalf("got %d lines before Flush, want the byte threshold to trigger a send", got)
+	}
+
+	_ = c.Push(pkg.LogEntry{Message: strings.Repeat("z", 500), Level: zapcore.InfoLevel})
+	_ = c.Push(entry(3))
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var sizes []int
+	for _, p := range server.Pushes() {
+		sizes = append(sizes, len(p.Lines))
+	}
+	if want := []int{2, 1, 1, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("request sizes = %v, want %v", sizes, want)
+	}
+}
+
+func TestStreamsSortedByTimestamp(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 40, MaxWaitTime: 60})
+	c.Pause()
+
+	// 时间戳在写入之前取得，多个 goroutine 交错写入时缓冲区中的顺序与时间戳不一致
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				ts := time.Now().UnixNano()
+				runtime.Gosched()
+				_ = c.Push(pkg.LogEntry{Message: strconv.FormatInt(ts, 10), Level: zapcore.InfoLevel, Timestamp: ts})
+			}
+		}()
+	}
+	wg.Wait()
+	// 明确乱序的日志
+	for i := 10; i > 0; i-- {
+		_ = c.Push(pkg.LogEntry{Message: strconv.Itoa(i), Level: zapcore.InfoLevel, Timestamp: int64(i)})
+	}
+	c.Resume()
+
+	pushes := server.Pushes()
+	if len(pushes) < 2 {
+		t.Fatalf("got %d requests, want several batches", len(pushes))
+	}
+	var prev int64
+	total := 0
+	for _, p := range pushes {
+		for _, line := range p.Lines {
+			ts, _ := strconv.ParseInt(line.Timestamp, 10, 64)
+			if ts < prev {
+				t.Fatalf("timestamp %d sent after %d", ts, prev)
+			}
+			prev = ts
+			total++
+		}
+	}
+	if total != 210 {
+		t.Errorf("got %d lines, want 210", total)
+	}
+}
+
+func TestLevelLabel(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	noLevel := ""
+	c := newStartedClient(t, ClientConfig{URL: server.URL, LevelLabel: &noLevel})
+
+	_ = c.Info("a")
+	_ = c.Error("b")
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	lines := server.Lines()
+	if len(lines) != 2 || len(lines[0].Labels) != 0 || len(lines[1].Labels) != 0 {
+		t.Errorf("lines = %+v, want both entries without a level label", lines)
+	}
+
+	invalid := "detected-level"
+	if _, err := NewClient(ClientConfig{URL: server.URL, LevelLabel: &invalid}); err == nil {
+		t.Error("NewClient() error = nil, want invalid level label name")
+	}
+}
+
+func TestFlushContext(t *testing.T) {
+	release := make(chan struct{})
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer close(release)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, OnSendError: func([]pkg.LogEntry, error) {}})
+
+	_ = c.Info("hello")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.FlushContext(ctx); err == nil {
+		t.Error("FlushContext() error = nil, want the deadline error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("FlushContext() took %s, want it bounded by the context", elapsed)
+	}
+}
+
+func TestSeverityAboveError(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL})
+
+	_ = c.Error("e")
+	_ = c.DPanic("d")
+	_ = c.Panic("p")
+	_ = c.Fatal("f")
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, line := range server.Lines() {
+		got[line.Line] = line.Labels[LevelLabel]
+	}
+	want := map[string]string{"e": "error", "d": "dpanic", "p": "panic", "f": "fatal"}
+	for line, level := range want {
+		if got[line] != level {
+			t.Errorf("%q level = %q, want %q", line, got[line], level)
+		}
+	}
+}
+
+func TestMaxWaitTimeBoundsLatency(t *testing.T) {
+	// MaxWaitTime 至少比默认 1 秒的 MinWaitTime 多 1 秒
+	const maxWait = 2 * time.Second
+
+	received := make(chan time.Time, 16)
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		received <- time.Now()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 1000, MaxWaitTime: 2})
+
+	// 最坏的情况：在两次检查之间刚发送过一次，紧接着写入日志。
+	// 检查间隔等于 MaxWaitTime 时，下一次检查发现距上次发送不足 MaxWaitTime 而跳过，
+	// 日志要等接近两倍的 MaxWaitTime
+	time.Sleep(maxWait / 4)
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	pushed := time.Now()
+	_ = c.Push(pkg.LogEntry{Message: "late", Level: zapcore.InfoLevel})
+
+	select {
+	case at := <-received:
+		if latency := at.Sub(pushed); latency > maxWait+100*time.Millisecond {
+			t.Errorf("entry reached the server after %s, want within %s", latency, maxWait)
+		}
+	case <-time.After(3 * maxWait):
+		t.Fatalf("entry not sent within %s", 3*maxWait)
+	}
+}
+
+func TestStartContext(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c, err := NewClient(ClientConfig{URL: server.URL, BatchSize: 1000, MaxWaitTime: 60})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.StartContext(ctx)
+
+	_ = c.Info("a")
+	_ = c.Info("b")
+	cancel()
+
+	select {
+	case <-c.workerDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker did not exit after the context was cancelled")
+	}
+	if got := len(server.Lines()); got != 2 {
+		t.Errorf("got %d lines, want the buffered entries flushed on cancellation", got)
+	}
+	if err := c.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() after cancellation error = %v", err)
+	}
+}
+
+func TestStopBeforeContextCancel(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c, err := NewClient(ClientConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.StartContext(ctx)
+
+	_ = c.Info("a")
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	// Stop 之后取消 context 不会再次停止
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	if got := len(server.Lines()); got != 1 {
+		t.Errorf("got %d lines, want 1", got)
+	}
+}
+
+func TestStopWithoutStart(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c, err := NewClient(ClientConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- c.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Stop() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stop() blocked on a client that was never started")
+	}
+	if err := c.Info("after stop"); err == nil {
+		t.Error("Info() error = nil, want client is closed")
+	}
+
+	// 停止之后启动的工作协程立即退出
+	c.Start()
+	select {
+	case <-c.workerDone:
+	case <-time.After(time.Second):
+		t.Fatal("worker started after Stop did not exit")
+	}
+}
+
+func TestConcurrentStop(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c, err := NewClient(ClientConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.StartContext(ctx)
+	_ = c.Info("a")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = c.Stop(context.Background())
+		}()
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("concurrent Stop calls blocked")
+	}
+	if got := len(server.Lines()); got != 1 {
+		t.Errorf("got %d lines, want 1", got)
+	}
+}
+
+func TestSendsSerializedOnWorker(t *testing.T) {
+	var current, peak atomic.Int32
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		current.Add(-1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 3, MinWaitTime: 1, MaxEntryAge: 10 * time.Millisecond})
+
+	const writers, perWriter = 6, 20
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				msg := strconv.Itoa(w*perWriter + i)
+				switch i % 3 {
+				case 0:
+					_ = c.Push(pkg.LogEntry{Message: msg, Level: zapcore.InfoLevel})
+				case 1:
+					_ = c.Push(pkg.LogEntry{Message: msg, Level: zapcore.InfoLevel})
+					_ = c.Flush()
+				default:
+					_ = c.PushBatch([]pkg.LogEntry{{Message: msg, Level: zapcore.InfoLevel}})
+				}
+			}
+		}(w)
+		if w == 0 {
+			c.Pause()
+			go c.Resume()
+		}
+	}
+	wg.Wait()
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if got := peak.Load(); got != 1 {
+		t.Errorf("peak concurrent requests = %d, want 1", got)
+	}
+	if got := len(server.Lines()); got != writers*perWriter {
+		t.Errorf("got %d lines, want %d", got, writers*perWriter)
+	}
+}
+
+func TestStopWaitsForConcurrentSends(t *testing.T) {
+	tests := []struct {
+		name            string
+		shutdownTimeout time.Duration
+		wantErr         bool
+	}{
+		{"completed", time.Second, false},
+		{"timed out", 30 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{}, 4)
+			var completed atomic.Int32
+			server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+				started <- struct{}{}
+				select {
+				case <-time.After(200 * time.Millisecond):
+					completed.Add(1)
+					w.WriteHeader(http.StatusNoContent)
+				case <-r.Context().Done():
+				}
+			})
+			c := newStartedClient(t, ClientConfig{
+				URL:                server.URL,
+				BatchSize:          1000,
+				FlushWorkers:       4,
+				MaxConcurrentSends: 4,
+				ShutdownTimeout:    tt.shutdownTimeout,
+				OnSendError:        func([]pkg.LogEntry, error) {},
+			})
+
+			pushRoutes(c, 4)
+			go func() { _ = c.Flush() }()
+			for i := 0; i < 4; i++ {
+				<-started
+			}
+
+			err := c.Stop(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Stop() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				// 未完成的发送被取消，其中的日志计为丢弃
+				deadline := time.Now().Add(time.Second)
+				for c.DroppedCount() != 4 && time.Now().Before(deadline) {
+					time.Sleep(5 * time.Millisecond)
+				}
+				if got := c.DroppedCount(); got != 4 {
+					t.Errorf("DroppedCount() = %d, want 4", got)
+				}
+				return
+			}
+			if got := completed.Load(); got != 4 {
+				t.Errorf("Stop returned after %d of 4 concurrent sends completed", got)
+			}
+		})
+	}
+}
+
+func TestLabelsFunc(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{
+		URL:          server.URL,
+		Labels:       map[string]string{"app": "svc"},
+		BatchSize:    1000,
+		FlushWorkers: 4,
+		LabelsFunc: func(entry pkg.LogEntry) map[string]string {
+			return map[string]string{"team": entry.Labels["team"], "severity": entry.Level.CapitalString()}
+		},
+	})
+
+	for i := 0; i < 20; i++ {
+		team := []string{"a", "b", "c"}[i%3]
+		_ = c.Push(pkg.LogEntry{Message: strconv.Itoa(i), Level: zapcore.WarnLevel, Labels: map[string]string{"team": team}})
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	lines := server.Lines()
+	if len(lines) != 20 {
+		t.Fatalf("got %d lines, want 20", len(lines))
+	}
+	last := make(map[string]int)
+	for _, line := range lines {
+		want := map[string]string{"team": line.Labels["team"], "severity": "WARN"}
+		if !reflect.DeepEqual(line.Labels, want) {
+			t.Errorf("line %q labels = %v, want only the LabelsFunc labels", line.Line, line.Labels)
+		}
+		// 同一个流中的日志保持推送顺序
+		n, _ := strconv.Atoi(line.Line)
+		if prev, ok := last[line.Labels["team"]]; ok && n < prev {
+			t.Errorf("stream %q out of order: %d after %d", line.Labels["team"], n, prev)
+		}
+		last[line.Labels["team"]] = n
+	}
+}
+
+// recordingLogger 是记录所有输出的 ErrorLogger
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *recordingLogger) Printf(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingLogger) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.lines, "\n")
+}
+
+func TestErrorLogger(t *testing.T) {
+	stdLog := captureLog(t)
+	errorLog := &recordingLogger{}
+	server := newFakeLoki(t, respondStatus(http.StatusTooManyRequests))
+	c := newStartedClient(t, ClientConfig{URL: server.URL, ErrorLogger: errorLog})
+
+	_ = c.Info("hello")
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	got := errorLog.String()
+	for _, want := range []string{"rate limiting pushes", "Failed to send logs to Loki"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ErrorLogger output = %q, want %q", got, want)
+		}
+	}
+	if stdLog.Len() != 0 {
+		t.Errorf("standard log output = %q, want nothing", stdLog.String())
+	}
+}
@@ -0,0 +1,57 @@
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"sync"
+)
+
+// Compression 定义推送请求体的压缩方式
+type Compression string
+
+const (
+	// CompressionNone 表示不压缩，默认值
+	CompressionNone Compression = "none"
+	// CompressionGzip 表示使用 gzip 压缩，并设置 Content-Encoding: gzip
+	CompressionGzip Compression = "gzip"
+)
+
+// gzipWriters 复用 gzip.Writer，避免每次发送都重新分配压缩状态
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// gzipCompress 使用 gzip 压缩数据
+func gzipCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data) / 4)
+
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	w.Reset(&buf)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("gzip compress failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("gzip compress failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// useGzip 返回是否使用 gzip 压缩请求体
+// protobuf 格式已经使用 snappy 压缩，不再重复压缩；gRPC 推送不使用 snappy，可以用 gzip 压缩消息
+func (c *Client) useGzip() bool {
+	return c.config.Compression == CompressionGzip && (c.grpc() || c.config.Protocol != ProtocolProtobuf)
+}
+
+// compressBody 按配置压缩请求体
+func (c *Client) compressBody(data []byte) ([]byte, error) {
+	if c.useGzip() {
+		return gzipCompress(data)
+	}
+	return data, nil
+}
@@ -0,0 +1,91 @@
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+// typicalBatch 返回一批典型的日志，消息带有 JSON 字段
+func typicalBatch(n int) []pkg.LogEntry {
+	entries := make([]pkg.LogEntry, n)
+	start := time.Now().UnixNano()
+	for i := range entries {
+		entries[i] = pkg.LogEntry{
+			Timestamp: start + int64(i),
+			Message:   fmt.Sprintf(`request handled {"method":"GET","path":"/api/v1/orders/%d","status":200,"duration_ms":%d}`, i, i%50),
+			Level:     zapcore.InfoLevel,
+		}
+	}
+	return entries
+}
+
+func TestGzipCompression(t *testing.T) {
+	for _, compression := range []Compression{"", CompressionNone, CompressionGzip} {
+		t.Run(string(compression), func(t *testing.T) {
+			server := newFakeLoki(t, nil)
+			c := newStartedClient(t, ClientConfig{URL: server.URL, Compression: compression})
+			_ = c.Info("hello")
+			if err := c.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			pushes := server.Pushes()
+			if len(pushes) != 1 || len(pushes[0].Lines) != 1 || pushes[0].Lines[0].Line != "hello" {
+				t.Fatalf("pushes = %+v, want one decodable line", pushes)
+			}
+			encoding := pushes[0].Header.Get("Content-Encoding")
+			if want := map[Compression]string{CompressionGzip: "gzip"}[compression]; encoding != want {
+				t.Errorf("Content-Encoding = %q, want %q", encoding, want)
+			}
+		})
+	}
+}
+
+func TestGzipCompressRoundTrip(t *testing.T) {
+	data, _ := json.Marshal(BuildPushRequest(typicalBatch(100), nil, EncodeOptions{}))
+
+	// 多次压缩，确保复用的 gzip.Writer 状态被正确重置
+	for i := 0; i < 3; i++ {
+		compressed, err := gzipCompress(data)
+		if err != nil {
+			t.Fatalf("gzipCompress() error = %v", err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+		got, err := io.ReadAll(zr)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("round trip mismatch, err = %v", err)
+		}
+		if len(compressed) >= len(data)/2 {
+			t.Errorf("compressed %d bytes to %d, want at least 50%% reduction", len(data), len(compressed))
+		}
+	}
+}
+
+func BenchmarkGzipCompress(b *testing.B) {
+	data, _ := json.Marshal(BuildPushRequest(typicalBatch(100), map[string]string{"app": "svc"}, EncodeOptions{}))
+
+	var compressedSize int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		compressed, err := gzipCompress(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		compressedSize = len(compressed)
+	}
+	b.ReportMetric(float64(len(data)), "raw-bytes")
+	b.ReportMetric(float64(compressedSize), "gzip-bytes")
+	b.ReportMetric(float64(compressedSize)/float64(len(data))*100, "%size")
+}
@@ -0,0 +1,114 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+// newBlockingLoki 启动一个在请求被取消或测试结束前不返回的 Loki 测试服务器
+// started 在每个请求到达时收到通知
+func newBlockingLoki(t *testing.T) (*fakeLoki, <-chan struct{}) {
+	t.Helper()
+
+	started := make(chan struct{}, 16)
+	release := make(chan struct{})
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	// 在关闭服务器之前放行所有请求，避免 Close 等待处理函数
+	t.Cleanup(func() { close(release) })
+	return server, started
+}
+
+func TestStopContextCancelsStuckSend(t *testing.T) {
+	server, started := newBlockingLoki(t)
+	sendErr := make(chan error, 1)
+	c := newStartedClient(t, ClientConfig{
+		URL:         server.URL,
+		OnSendError: func(_ []pkg.LogEntry, err error) { sendErr <- err },
+	})
+
+	_ = c.Info("stuck")
+	go func() { _ = c.Flush() }()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	err := c.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("Stop took %s, want it to return at the ctx deadline", elapsed)
+	}
+
+	select {
+	case err := <-sendErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("send error = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stuck send was not cancelled")
+	}
+}
+
+func TestStopContextAbortsRetryWait(t *testing.T) {
+	server := newFakeLoki(t, respondStatus(http.StatusServiceUnavailable))
+	sendErr := make(chan error, 1)
+	c := newStartedClient(t, ClientConfig{
+		URL:          server.URL,
+		MaxRetries:   5,
+		RetryBackoff: time.Hour,
+		OnSendError:  func(_ []pkg.LogEntry, err error) { sendErr <- err },
+	})
+
+	_ = c.Info("hello")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Stop(ctx); err == nil {
+		t.Error("Stop() error = nil, want the ctx deadline")
+	}
+
+	select {
+	case <-sendErr:
+	case <-time.After(2 * time.Second):
+		t.Fatal("retry wait was not aborted")
+	}
+	if got := len(server.Pushes()); got != 1 {
+		t.Errorf("got %d requests, want no retry after Stop cancelled", got)
+	}
+}
+
+func TestStopDoesNotCancelFinishingSends(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{URL: server.URL})
+
+	_ = c.Info("hello")
+	flushErr := make(chan error, 1)
+	go func() { flushErr <- c.Flush() }()
+	<-started
+
+	// 工作协程很快退出，Flush 中的请求仍在进行，不应被取消
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err := <-flushErr; err != nil {
+		t.Errorf("in-flight Flush error = %v, want it to complete", err)
+	}
+}
@@ -0,0 +1,138 @@
+package loki
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+// 丢弃原因
+const (
+	// DropReasonSendFailure 表示日志因发送失败被丢弃
+	DropReasonSendFailure = "send_failure"
+	// DropReasonBufferOverflow 表示日志因缓冲区超过 MaxBufferEntries 被丢弃
+	DropReasonBufferOverflow = "buffer_overflow"
+)
+
+// DropEvent 记录一次日志丢弃事件
+// 只保存摘要信息，不保存被丢弃的日志内容
+type DropEvent struct {
+	// Time 是丢弃发生的时间
+	Time time.Time `json:"time"`
+	// Target 是发生丢弃的推送目标名称，缓冲区溢出时为空
+	Target string `json:"target"`
+	// Reason 是丢弃原因
+	Reason string `json:"reason"`
+	// Count 是本次丢弃的日志条数
+	Count int `json:"count"`
+	// SampleHash 是被丢弃的第一条日志消息的 SHA-256 摘要（十六进制）
+	// 缓冲区溢出时不保留被丢弃的日志，该字段为空
+	SampleHash string `json:"sample_hash,omitempty"`
+	// Error 是导致丢弃的错误信息
+	Error string `json:"error,omitempty"`
+}
+
+// dropLog 是一个固定容量的丢弃事件环形缓冲区
+// 超过容量时覆盖最早的事件
+type dropLog struct {
+	// mu 用于保护并发访问
+	mu sync.Mutex
+	// events 存储事件，容量固定
+	events []DropEvent
+	// next 是下一次写入的位置
+	next int
+	// full 表示缓冲区是否已经写满过一轮
+	full bool
+	// auditFile 是镜像写入的审计文件路径，为空时不写文件
+	auditFile string
+	// errorLog 记录审计文件写入失败
+	errorLog ErrorLogger
+}
+
+// newDropLog 创建丢弃事件缓冲区
+func newDropLog(size int, auditFile string, errorLog ErrorLogger) *dropLog {
+	if size <= 0 {
+		size = 100
+	}
+	return &dropLog{
+		events:    make([]DropEvent, size),
+		auditFile: auditFile,
+		errorLog:  errorLog,
+	}
+}
+
+// record 记录一次丢弃事件，并在配置了审计文件时追加写入
+func (d *dropLog) record(target, reason string, entries []pkg.LogEntry, err error) {
+	if len(entries) == 0 {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(entries[0].Message))
+	d.recordCount(target, reason, len(entries), hex.EncodeToString(sum[:]), err)
+}
+
+// recordCount 按条数记录一次丢弃事件，用于无法取得被丢弃日志内容的场景
+func (d *dropLog) recordCount(target, reason string, count int, sampleHash string, err error) {
+	event := DropEvent{
+		Time:       time.Now(),
+		Target:     target,
+		Reason:     reason,
+		Count:      count,
+		SampleHash: sampleHash,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	d.events[d.next] = event
+	d.next = (d.next + 1) % len(d.events)
+	if d.next == 0 {
+		d.full = true
+	}
+	d.mu.Unlock()
+
+	if d.auditFile != "" {
+		d.writeAudit(event)
+	}
+}
+
+// snapshot 按时间顺序返回所有已记录的事件
+func (d *dropLog) snapshot() []DropEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.full {
+		return append([]DropEvent(nil), d.events[:d.next]...)
+	}
+	events := make([]DropEvent, 0, len(d.events))
+	events = append(events, d.events[d.next:]...)
+	return append(events, d.events[:d.next]...)
+}
+
+// writeAudit 将事件以 JSON 行的形式追加到审计文件
+func (d *dropLog) writeAudit(event DropEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := os.OpenFile(d.auditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		d.errorLog.Printf("Failed to open drop audit file: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		d.errorLog.Printf("Failed to write drop audit file: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package loki
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+func TestDroppedEventsSendFailure(t *testing.T) {
+	server := newFakeLoki(t, respondStatus(http.StatusBadRequest))
+	audit := filepath.Join(t.TempDir(), "drops.jsonl")
+	c := newStartedClient(t, ClientConfig{
+		URL:           server.URL,
+		DropAuditFile: audit,
+		OnSendError:   func([]pkg.LogEntry, error) {},
+	})
+
+	_ = c.Error("first lost")
+	_ = c.Error("second lost")
+	if err := c.Flush(); err == nil {
+		t.Fatal("Flush() succeeded, want error")
+	}
+
+	events := c.DroppedEvents()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	sum := sha256.Sum256([]byte("first lost"))
+	e := events[0]
+	if e.Reason != DropReasonSendFailure || e.Count != 2 || e.Target != DefaultTargetName ||
+		e.SampleHash != hex.EncodeToString(sum[:]) || e.Error == "" {
+		t.Errorf("event = %+v", e)
+	}
+	if c.DroppedCount() != 2 {
+		t.Errorf("DroppedCount() = %d, want 2", c.DroppedCount())
+	}
+
+	f, err := os.Open(audit)
+	if err != nil {
+		t.Fatalf("open audit file: %v", err)
+	}
+	defer f.Close()
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var logged DropEvent
+		if err := json.Unmarshal(scanner.Bytes(), &logged); err != nil {
+			t.Fatalf("audit line %q: %v", scanner.Text(), err)
+		}
+		if logged.Reason != DropReasonSendFailure || logged.Count != 2 {
+			t.Errorf("audit event = %+v", logged)
+		}
+		lines++
+	}
+	if lines != 1 {
+		t.Errorf("got %d audit lines, want 1", lines)
+	}
+}
+
+func TestDroppedEventsOverflow(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c, err := NewClient(ClientConfig{URL: server.URL, BatchSize: 2, MaxBufferEntries: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+	c.Pause()
+	for i := 0; i < 12; i++ {
+		_ = c.Info("overflow")
+	}
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	events := c.DroppedEvents()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1 summarised overflow event", len(events))
+	}
+	if e := events[0]; e.Reason != DropReasonBufferOverflow || e.Count != 7 || e.SampleHash != "" {
+		t.Errorf("event = %+v", e)
+	}
+	if got := len(server.Lines()); got != 5 {
+		t.Errorf("server received %d lines, want the 5 retained entries", got)
+	}
+}
+
+func TestDropLogRingKeepsNewest(t *testing.T) {
+	d := newDropLog(3, "", log.Default())
+	for i := 1; i <= 5; i++ {
+		d.recordCount("t", DropReasonSendFailure, i, "", nil)
+	}
+
+	events := d.snapshot()
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for i, e := range events {
+		if e.Count != i+3 {
+			t.Errorf("events[%d].Count = %d, want %d", i, e.Count, i+3)
+		}
+	}
+}
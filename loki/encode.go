@@ -0,0 +1,239 @@
+package loki
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+// EntryIDKey 是日志标识在结构化元数据中的键
+const EntryIDKey = "entry_id"
+
+// LevelLabel 是日志级别在 Loki 中默认的标签名
+const LevelLabel = "level"
+
+// resolveLevelLabel 返回级别标签名，label 为 nil 时使用 LevelLabel，为空字符串时不添加级别标签
+func resolveLevelLabel(label *string) string {
+	if label == nil {
+		return LevelLabel
+	}
+	return *label
+}
+
+// TimestampFormat 定义日志时间戳在推送请求中的格式
+// 注意Loki原生的推送接口只接受 TimestampUnixNano，
+// 其他格式只适用于兼容Loki推送格式、但对时间戳有不同要求的下游接收端
+type TimestampFormat string
+
+const (
+	// TimestampUnixNano 是Unix纳秒时间戳字符串，Loki推送接口要求的格式，默认值
+	TimestampUnixNano TimestampFormat = "unixnano"
+	// TimestampUnixMillis 是Unix毫秒时间戳字符串
+	TimestampUnixMillis TimestampFormat = "unixmillis"
+	// TimestampRFC3339Nano 是 RFC3339 格式的时间字符串，精确到纳秒
+	TimestampRFC3339Nano TimestampFormat = "rfc3339nano"
+)
+
+// formatTimestamp 按格式将Unix纳秒时间戳转换为字符串
+func formatTimestamp(ns int64, format TimestampFormat) string {
+	switch format {
+	case TimestampUnixMillis:
+		return strconv.FormatInt(ns/int64(time.Millisecond), 10)
+	case TimestampRFC3339Nano:
+		return time.Unix(0, ns).UTC().Format(time.RFC3339Nano)
+	default:
+		return strconv.FormatInt(ns, 10)
+	}
+}
+
+// EncodeOptions 定义将日志转换为推送请求时的选项
+type EncodeOptions struct {
+	// AddEntryID 定义是否为每条日志附加 entry_id 结构化元数据
+	AddEntryID bool
+	// SortByTimestamp 定义是否将每个流中的日志按时间戳升序排列
+	SortByTimestamp bool
+	// Dedup 定义是否去掉同一个流中时间戳和消息都相同的重复日志
+	Dedup bool
+	// TimestampFormat 定义时间戳格式，为空时使用 TimestampUnixNano
+	TimestampFormat TimestampFormat
+	// LevelLabel 定义级别标签名，为 nil 时使用 LevelLabel
+	// 指向空字符串时不添加级别标签，不同级别的日志不再拆分为不同的流
+	LevelLabel *string
+	// LabelsFunc 设置后完全决定每条日志的流标签，参见 ClientConfig.LabelsFunc
+	LabelsFunc func(entry pkg.LogEntry) map[string]string
+	// warnLabel 在 LabelsFunc 返回的标签中有非法标签名时调用，由客户端设置
+	warnLabel func(name string)
+}
+
+// BuildPushRequest 将一批日志转换为Loki推送请求
+// 这是一个纯函数，不依赖客户端状态
+// 主要步骤：
+// 1. 按日志级别和附加标签将日志分组为流，流的顺序与首次出现的顺序一致，不添加级别标签时只按附加标签分组
+// 2. 每个流的标签由 baseLabels、日志的附加标签和级别标签合并而成；
+// 设置了 LabelsFunc 时改为按其返回的标签集分组，并直接作为流的标签
+// 3. 按选项对每个流中的日志排序、去重，并附加 entry_id
+//
+// 参数：
+//   - entries: 要发送的日志条目
+//   - baseLabels: 所有流共有的标签
+//   - opts: 转换选项
+//
+// 返回：
+//   - PushRequest: 可以直接发送的推送请求
+func BuildPushRequest(entries []pkg.LogEntry, baseLabels map[string]string, opts EncodeOptions) PushRequest {
+	levelLabel := resolveLevelLabel(opts.LevelLabel)
+
+	// 按日志级别和附加标签分组
+	groups := make(map[string][]pkg.LogEntry)
+	// custom 是 LabelsFunc 为各个分组生成的标签
+	custom := make(map[string]map[string]string)
+	var keys []string
+	for _, entry := range entries {
+		key := streamKey(entry, levelLabel)
+		if labels := customLabels(entry, opts.LabelsFunc, opts.warnLabel); labels != nil {
+			key = labelSetKey(labels)
+			custom[key] = labels
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], entry)
+	}
+
+	// 为每个分组创建单独的流
+	streams := make([]Stream, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+		if opts.SortByTimestamp {
+			sort.SliceStable(group, func(i, j int) bool {
+				return group[i].Timestamp < group[j].Timestamp
+			})
+		}
+		if opts.Dedup {
+			group = dedupEntries(group)
+		}
+
+		values := make([]Value, 0, len(group))
+		for _, entry := range group {
+			values = append(values, Value{
+				Timestamp: formatTimestamp(entry.Timestamp, opts.TimestampFormat),
+				Line:      entry.Message,
+				Metadata:  entryMetadata(entry, opts),
+			})
+		}
+
+		labels, ok := custom[key]
+		if !ok {
+			labels = streamLabels(group[0], baseLabels, levelLabel)
+		}
+		streams = append(streams, Stream{
+			Stream: labels,
+			Values: values,
+		})
+	}
+
+	return PushRequest{
+		Streams: streams,
+	}
+}
+
+// streamLabels 合并默认标签、日志的附加标签和级别标签
+// levelLabel 为空时不添加级别标签
+func streamLabels(entry pkg.LogEntry, baseLabels map[string]string, levelLabel string) map[string]string {
+	// 复制标签并添加级别
+	labels := make(map[string]string, len(baseLabels)+len(entry.Labels)+1)
+	for k, v := range baseLabels {
+		labels[k] = v
+	}
+	for k, v := range entry.Labels {
+		labels[k] = v
+	}
+	// 添加日志级别标签
+	if levelLabel != "" {
+		labels[levelLabel] = entry.Level.String()
+	}
+	return labels
+}
+
+// customLabels 返回 LabelsFunc 为日志生成的标签，跳过其中的非法标签名
+// 没有设置 LabelsFunc 或返回空标签集时返回 nil，表示按默认方式生成标签
+func customLabels(entry pkg.LogEntry, labelsFunc func(pkg.LogEntry) map[string]string, warn func(name string)) map[string]string {
+	if labelsFunc == nil {
+		return nil
+	}
+	labels := validLabels(labelsFunc(entry), warn)
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// dedupEntries 去掉时间戳和消息都相同的重复日志，保留第一次出现的条目
+func dedupEntries(entries []pkg.LogEntry) []pkg.LogEntry {
+	type key struct {
+		timestamp int64
+		message   string
+	}
+
+	seen := make(map[key]struct{}, len(entries))
+	result := make([]pkg.LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		k := key{entry.Timestamp, entry.Message}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// entryMetadata 返回日志条目需要发送的结构化元数据
+// 开启 AddEntryID 时会附加 entry_id
+func entryMetadata(entry pkg.LogEntry, opts EncodeOptions) map[string]string {
+	if !opts.AddEntryID {
+		return entry.Metadata
+	}
+
+	metadata := make(map[string]string, len(entry.Metadata)+1)
+	for k, v := range entry.Metadata {
+		metadata[k] = v
+	}
+	metadata[EntryIDKey] = entry.ID()
+	return metadata
+}
+
+// streamKey 根据日志级别和附加标签生成分组键
+// 标签按键排序，保证相同的标签集得到相同的键；levelLabel 为空时不同级别的日志属于同一个流
+func streamKey(entry pkg.LogEntry, levelLabel string) string {
+	level := entry.Level.String()
+	if levelLabel == "" {
+		level = ""
+	}
+	return level + labelSetKey(entry.Labels)
+}
+
+// labelSetKey 返回标签集的键，标签按键排序，保证相同的标签集得到相同的键
+func labelSetKey(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, k := range names {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
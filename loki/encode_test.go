@@ -0,0 +1,261 @@
+package loki
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestEntryIDMetadata(t *testing.T) {
+	entries := []pkg.LogEntry{
+		{Timestamp: 1, Message: "same", Level: zapcore.InfoLevel, Metadata: map[string]string{"trace_id": "t"}},
+		{Timestamp: 1, Message: "same", Level: zapcore.InfoLevel},
+		{Timestamp: 2, Message: "other", Level: zapcore.InfoLevel},
+	}
+
+	req := BuildPushRequest(entries, nil, EncodeOptions{AddEntryID: true})
+	values := req.Streams[0].Values
+	if len(values) != 3 {
+		t.Fatalf("got %d values, want 3", len(values))
+	}
+	if values[0].Metadata[EntryIDKey] != entries[0].ID() || values[0].Metadata["trace_id"] != "t" {
+		t.Errorf("metadata = %v, want entry_id and existing trace_id", values[0].Metadata)
+	}
+	if values[0].Metadata[EntryIDKey] != values[1].Metadata[EntryIDKey] {
+		t.Error("identical entries got different entry_id")
+	}
+	if values[0].Metadata[EntryIDKey] == values[2].Metadata[EntryIDKey] {
+		t.Error("different entries share an entry_id")
+	}
+	if _, ok := entries[0].Metadata[EntryIDKey]; ok {
+		t.Error("BuildPushRequest modified the caller's metadata")
+	}
+
+	plain := BuildPushRequest(entries, nil, EncodeOptions{})
+	if _, ok := plain.Streams[0].Values[1].Metadata[EntryIDKey]; ok {
+		t.Error("entry_id added although AddEntryID is false")
+	}
+}
+
+func TestBuildPushRequest(t *testing.T) {
+	info := zapcore.InfoLevel
+	errLevel := zapcore.ErrorLevel
+	base := map[string]string{"app": "svc", "env": "prod"}
+	severity, noLevel := "severity", ""
+
+	tests := []struct {
+		name    string
+		entries []pkg.LogEntry
+		opts    EncodeOptions
+		want    []Stream
+	}{
+		{
+			name:    "empty",
+			entries: nil,
+			want:    []Stream{},
+		},
+		{
+			name: "group by level in first-seen order",
+			entries: []pkg.LogEntry{
+				{Timestamp: 1, Message: "a", Level: errLevel},
+				{Timestamp: 2, Message: "b", Level: info},
+				{Timestamp: 3, Message: "c", Level: errLevel},
+			},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "error"}, Values: []Value{{Timestamp: "1", Line: "a"}, {Timestamp: "3", Line: "c"}}},
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "info"}, Values: []Value{{Timestamp: "2", Line: "b"}}},
+			},
+		},
+		{
+			name: "entry labels split streams and override base labels",
+			entries: []pkg.LogEntry{
+				{Timestamp: 1, Message: "a", Level: info, Labels: map[string]string{"env": "dev", "module": "x"}},
+				{Timestamp: 2, Message: "b", Level: info, Labels: map[string]string{"module": "x", "env": "dev"}},
+				{Timestamp: 3, Message: "c", Level: info, Labels: map[string]string{"module": "y"}},
+			},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "dev", "module": "x", "level": "info"}, Values: []Value{{Timestamp: "1", Line: "a"}, {Timestamp: "2", Line: "b"}}},
+				{Stream: map[string]string{"app": "svc", "env": "prod", "module": "y", "level": "info"}, Values: []Value{{Timestamp: "3", Line: "c"}}},
+			},
+		},
+		{
+			name: "level label cannot be overridden",
+			entries: []pkg.LogEntry{
+				{Timestamp: 1, Message: "a", Level: info, Labels: map[string]string{"level": "fake"}},
+			},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "info"}, Values: []Value{{Timestamp: "1", Line: "a"}}},
+			},
+		},
+		{
+			name: "unsorted by default",
+			entries: []pkg.LogEntry{
+				{Timestamp: 3, Message: "c", Level: info},
+				{Timestamp: 1, Message: "a", Level: info},
+			},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "info"}, Values: []Value{{Timestamp: "3", Line: "c"}, {Timestamp: "1", Line: "a"}}},
+			},
+		},
+		{
+			name: "sort is stable",
+			entries: []pkg.LogEntry{
+				{Timestamp: 3, Message: "c", Level: info},
+				{Timestamp: 1, Message: "a1", Level: info},
+				{Timestamp: 1, Message: "a2", Level: info},
+			},
+			opts: EncodeOptions{SortByTimestamp: true},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "info"}, Values: []Value{{Timestamp: "1", Line: "a1"}, {Timestamp: "1", Line: "a2"}, {Timestamp: "3", Line: "c"}}},
+			},
+		},
+		{
+			name: "dedup within a stream only",
+			entries: []pkg.LogEntry{
+				{Timestamp: 1, Message: "a", Level: info},
+				{Timestamp: 1, Message: "a", Level: info},
+				{Timestamp: 1, Message: "b", Level: info},
+				{Timestamp: 1, Message: "a", Level: errLevel},
+			},
+			opts: EncodeOptions{Dedup: true},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "info"}, Values: []Value{{Timestamp: "1", Line: "a"}, {Timestamp: "1", Line: "b"}}},
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "error"}, Values: []Value{{Timestamp: "1", Line: "a"}}},
+			},
+		},
+		{
+			name: "dedup after sort removes non-adjacent duplicates",
+			entries: []pkg.LogEntry{
+				{Timestamp: 2, Message: "a", Level: info},
+				{Timestamp: 1, Message: "b", Level: info},
+				{Timestamp: 2, Message: "a", Level: info},
+			},
+			opts: EncodeOptions{SortByTimestamp: true, Dedup: true},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "info"}, Values: []Value{{Timestamp: "1", Line: "b"}, {Timestamp: "2", Line: "a"}}},
+			},
+		},
+		{
+			name: "metadata passed through",
+			entries: []pkg.LogEntry{
+				{Timestamp: 1, Message: "a", Level: info, Metadata: map[string]string{"trace_id": "t"}},
+			},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "info"}, Values: []Value{{Timestamp: "1", Line: "a", Metadata: map[string]string{"trace_id": "t"}}}},
+			},
+		},
+		{
+			name: "custom level label",
+			entries: []pkg.LogEntry{
+				{Timestamp: 1, Message: "a", Level: info},
+			},
+			opts: EncodeOptions{LevelLabel: &severity},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod", "severity": "info"}, Values: []Value{{Timestamp: "1", Line: "a"}}},
+			},
+		},
+		{
+			name: "level label disabled merges levels",
+			entries: []pkg.LogEntry{
+				{Timestamp: 1, Message: "a", Level: errLevel},
+				{Timestamp: 2, Message: "b", Level: info},
+				{Timestamp: 3, Message: "c", Level: info, Labels: map[string]string{"zone": "a"}},
+			},
+			opts: EncodeOptions{LevelLabel: &noLevel},
+			want: []Stream{
+				{Stream: map[string]string{"app": "svc", "env": "prod"}, Values: []Value{{Timestamp: "1", Line: "a"}, {Timestamp: "2", Line: "b"}}},
+				{Stream: map[string]string{"app": "svc", "env": "prod", "zone": "a"}, Values: []Value{{Timestamp: "3", Line: "c"}}},
+			},
+		},
+		{
+			name: "labels func determines streams",
+			entries: []pkg.LogEntry{
+				{Timestamp: 1, Message: "a", Level: errLevel, Labels: map[string]string{"route": "pay"}},
+				{Timestamp: 2, Message: "b", Level: info, Labels: map[string]string{"route": "cart"}},
+				{Timestamp: 3, Message: "c", Level: info, Labels: map[string]string{"route": "pay"}},
+				{Timestamp: 4, Message: "d", Level: info},
+			},
+			opts: EncodeOptions{LabelsFunc: func(entry pkg.LogEntry) map[string]string {
+				if entry.Labels["route"] == "" {
+					return nil
+				}
+				return map[string]string{"service": entry.Labels["route"], "bad-name": "x"}
+			}},
+			want: []Stream{
+				{Stream: map[string]string{"service": "pay"}, Values: []Value{{Timestamp: "1", Line: "a"}, {Timestamp: "3", Line: "c"}}},
+				{Stream: map[string]string{"service": "cart"}, Values: []Value{{Timestamp: "2", Line: "b"}}},
+				{Stream: map[string]string{"app": "svc", "env": "prod", "level": "info"}, Values: []Value{{Timestamp: "4", Line: "d"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildPushRequest(tt.entries, base, tt.opts)
+			if !reflect.DeepEqual(got.Streams, tt.want) {
+				t.Errorf("BuildPushRequest() streams =\n%+v\nwant\n%+v", got.Streams, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPushRequestDoesNotModifyInput(t *testing.T) {
+	base := map[string]string{"app": "svc"}
+	entries := []pkg.LogEntry{
+		{Timestamp: 2, Message: "b", Level: zapcore.InfoLevel, Labels: map[string]string{"module": "x"}},
+		{Timestamp: 1, Message: "a", Level: zapcore.InfoLevel, Labels: map[string]string{"module": "x"}},
+	}
+
+	BuildPushRequest(entries, base, EncodeOptions{SortByTimestamp: true, Dedup: true})
+	if len(base) != 1 || len(entries[0].Labels) != 1 {
+		t.Errorf("labels modified: base = %v, entry = %v", base, entries[0].Labels)
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	const ns = int64(1700000000123456789)
+	tests := []struct {
+		format TimestampFormat
+		want   string
+	}{
+		{"", "1700000000123456789"},
+		{TimestampUnixNano, "1700000000123456789"},
+		{TimestampUnixMillis, "1700000000123"},
+		{TimestampRFC3339Nano, "2023-11-14T22:13:20.123456789Z"},
+	}
+	for _, tt := range tests {
+		if got := formatTimestamp(ns, tt.format); got != tt.want {
+			t.Errorf("formatTimestamp(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestTimestampFormatInPush(t *testing.T) {
+	const ns = int64(1700000000123456789)
+	tests := []struct {
+		format TimestampFormat
+		want   string
+	}{
+		{"", "1700000000123456789"},
+		{TimestampUnixMillis, "1700000000123"},
+		{TimestampRFC3339Nano, "2023-11-14T22:13:20.123456789Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			server := newFakeLoki(t, nil)
+			c := newStartedClient(t, ClientConfig{URL: server.URL, TimestampFormat: tt.format})
+			_ = c.Push(pkg.LogEntry{Timestamp: ns, Message: "hello", Level: zapcore.InfoLevel})
+			if err := c.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			lines := server.Lines()
+			if len(lines) != 1 || lines[0].Timestamp != tt.want {
+				t.Errorf("lines = %+v, want timestamp %q", lines, tt.want)
+			}
+		})
+	}
+}
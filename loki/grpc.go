@@ -0,0 +1,146 @@
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Transport 定义推送请求的传输方式
+type Transport string
+
+const (
+	// TransportHTTP 使用Loki的 HTTP 推送接口，默认值
+	TransportHTTP Transport = "http"
+	// TransportGRPC 通过 gRPC 调用 logproto.Pusher/Push
+	// 请求体是未压缩的 logproto.PushRequest，省去了 JSON 编码和 snappy 压缩的开销。
+	// 直接在 HTTP/2 上实现 gRPC 的一元调用，不依赖 grpc-go
+	TransportGRPC Transport = "grpc"
+)
+
+// GRPCPushPath 是 gRPC 推送方法的路径
+const GRPCPushPath = "/logproto.Pusher/Push"
+
+// GRPCStatusError 表示 gRPC 调用返回了非 OK 的状态
+// Loki 通过 httpgrpc 把 HTTP 状态码作为 gRPC 状态码返回，这种情况下返回的是 StatusError，
+// 与 HTTP 推送接口的重试和限流处理一致；其他状态码返回 GRPCStatusError
+type GRPCStatusError struct {
+	// Code 是 gRPC 状态码
+	Code int
+	// Message 是 grpc-message 中的错误信息
+	Message string
+}
+
+// Error 实现 error 接口
+func (e *GRPCStatusError) Error() string {
+	return fmt.Sprintf("unexpected grpc status: %d, message: %s", e.Code, e.Message)
+}
+
+// retryable 判断 gRPC 状态码是否值得重试
+// 可以重试的状态码：DEADLINE_EXCEEDED、RESOURCE_EXHAUSTED、ABORTED、UNAVAILABLE
+func (e *GRPCStatusError) retryable() bool {
+	switch e.Code {
+	case 4, 8, 10, 14:
+		return true
+	default:
+		return false
+	}
+}
+
+// validTransport 判断传输方式是否受支持，空字符串表示默认的 HTTP
+func validTransport(t Transport) bool {
+	return t == "" || t == TransportHTTP || t == TransportGRPC
+}
+
+// grpc 返回是否通过 gRPC 推送
+func (c *Client) grpc() bool {
+	return c.config.Transport == TransportGRPC
+}
+
+// sendGRPC 将编码后的推送请求作为一次 gRPC 一元调用发送到指定目标
+// 租户、认证和幂等键作为请求头发送，即 gRPC 的 metadata。
+// 参数 data 是按配置压缩后的 logproto.PushRequest，ValidateResponse 不生效
+func (c *Client) sendGRPC(ctx context.Context, t *target, tenant string, data []byte, idempotencyKey string) error {
+	// gRPC 消息帧：1 字节压缩标志 + 4 字节大端长度 + 消息
+	frame := make([]byte, 5, 5+len(data))
+	if c.useGzip() {
+		frame[0] = 1
+	}
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	frame = append(frame, data...)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.pushURL, bytes.NewReader(frame))
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	if c.useGzip() {
+		req.Header.Set("Grpc-Encoding", "gzip")
+	}
+	if tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+	if err := t.auth.apply(req); err != nil {
+		return err
+	}
+	if idempotencyKey != "" {
+		req.Header.Set(c.config.IdempotencyHeader, idempotencyKey)
+	}
+
+	start := time.Now()
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		c.latency.observe(time.Since(start))
+		return fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = grpcStatus(resp)
+	c.latency.observe(time.Since(start))
+	if isRateLimited(err) {
+		c.recordRateLimit(t, resp)
+	}
+	return err
+}
+
+// grpcStatus 读取 gRPC 响应并返回调用结果
+// 状态在响应体之后的 trailer 中；没有响应消息时服务端可能只返回请求头，此时状态在请求头中
+func grpcStatus(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	// 响应消息是空的 PushResponse，读完响应体之后 trailer 才可用
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("read response failed: %w", err)
+	}
+
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status == "" {
+		return fmt.Errorf("invalid grpc response: missing grpc-status")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return fmt.Errorf("invalid grpc status %q", status)
+	}
+	if code == 0 {
+		return nil
+	}
+	if m, err := url.PathUnescape(message); err == nil {
+		message = m
+	}
+	if code >= 100 && code < 600 {
+		return &StatusError{StatusCode: code, Body: message}
+	}
+	return &GRPCStatusError{Code: code, Message: message}
+}
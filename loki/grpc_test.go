@@ -0,0 +1,258 @@
+//go:build go1.24
+
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeGRPCLoki 是通过明文 HTTP/2 接收 gRPC 推送的 Loki 测试服务器
+type fakeGRPCLoki struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	pushes []grpcPush
+	// status 是返回的 grpc-status 和 grpc-message
+	status, message string
+}
+
+// grpcPush 是测试服务器收到的一次 gRPC 调用
+type grpcPush struct {
+	// Proto 是请求的 HTTP 协议版本
+	Proto string
+	// Path 是请求路径，即 gRPC 方法名
+	Path string
+	// Header 是请求头，即 gRPC 的 metadata
+	Header http.Header
+	// Lines 是请求中的所有日志
+	Lines []pushedLine
+}
+
+// newFakeGRPCLoki 启动一个 gRPC 测试服务器，status 为空时返回 OK
+func newFakeGRPCLoki(t *testing.T, status, message string) *fakeGRPCLoki {
+	t.Helper()
+
+	f := &fakeGRPCLoki{status: status, message: message}
+	f.Server = httptest.NewUnstartedServer(http.HandlerFunc(f.handle))
+	f.Server.Config.Protocols = new(http.Protocols)
+	f.Server.Config.Protocols.SetUnencryptedHTTP2(true)
+	f.Start()
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeGRPCLoki) handle(w http.ResponseWriter, r *http.Request) {
+	push := grpcPush{Proto: r.Proto, Path: r.URL.Path, Header: r.Header.Clone()}
+	lines, err := decodeGRPCFrame(r)
+	if err != nil {
+		w.Header().Set("Grpc-Status", "13")
+		w.Header().Set("Grpc-Message", err.Error())
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	push.Lines = lines
+
+	f.mu.Lock()
+	f.pushes = append(f.pushes, push)
+	f.mu.Unlock()
+
+	status := f.status
+	if status == "" {
+		status = "0"
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	// 空的 PushResponse
+	_, _ = w.Write([]byte{0, 0, 0, 0, 0})
+	w.Header().Set("Grpc-Status", status)
+	w.Header().Set("Grpc-Message", f.message)
+}
+
+// decodeGRPCFrame 解码请求中的 gRPC 消息帧，按 grpc-encoding 解压
+func decodeGRPCFrame(r *http.Request) ([]pushedLine, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		return nil, errors.New("invalid grpc frame")
+	}
+	data := body[5:]
+	if body[0] == 1 {
+		if r.Header.Get("Grpc-Encoding") != "gzip" {
+			return nil, errors.New("compressed frame without grpc-encoding")
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	return decodePushRequest(data)
+}
+
+// Pushes 返回收到的所有 gRPC 调用
+func (f *fakeGRPCLoki) Pushes() []grpcPush {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]grpcPush(nil), f.pushes...)
+}
+
+func TestGRPCTransport(t *testing.T) {
+	for _, compression := range []Compression{CompressionNone, CompressionGzip} {
+		t.Run(string(compression), func(t *testing.T) {
+			server := newFakeGRPCLoki(t, "", "")
+			c := newStartedClient(t, ClientConfig{
+				URL:         server.URL,
+				Labels:      map[string]string{"app": "api"},
+				TenantID:    "team-a",
+				Transport:   TransportGRPC,
+				Compression: compression,
+				// 对 gRPC 推送不生效
+				PushPath: "/custom/push",
+				Protocol: ProtocolProtobuf,
+			})
+
+			_ = c.Push(pkg.LogEntry{Timestamp: 1700000000123456789, Message: "first", Level: zapcore.InfoLevel,
+				Metadata: map[string]string{"trace_id": "t1"}})
+			_ = c.Push(pkg.LogEntry{Timestamp: 1700000001000000000, Message: "second", Level: zapcore.ErrorLevel})
+			if err := c.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			pushes := server.Pushes()
+			if len(pushes) != 1 {
+				t.Fatalf("got %d calls, want 1", len(pushes))
+			}
+			p := pushes[0]
+			if p.Proto != "HTTP/2.0" || p.Path != GRPCPushPath {
+				t.Errorf("call = %s %s, want HTTP/2.0 %s", p.Proto, p.Path, GRPCPushPath)
+			}
+			if got := p.Header.Get("Content-Type"); got != "application/grpc" {
+				t.Errorf("Content-Type = %q, want application/grpc", got)
+			}
+			if got := p.Header.Get("X-Scope-OrgID"); got != "team-a" {
+				t.Errorf("X-Scope-OrgID = %q, want team-a", got)
+			}
+			wantEncoding := ""
+			if compression == CompressionGzip {
+				wantEncoding = "gzip"
+			}
+			if got := p.Header.Get("Grpc-Encoding"); got != wantEncoding {
+				t.Errorf("Grpc-Encoding = %q, want %q", got, wantEncoding)
+			}
+
+			want := []pushedLine{
+				{Labels: map[string]string{"app": "api", "level": "info"}, Timestamp: "1700000000123456789", Line: "first",
+					Metadata: map[string]string{"trace_id": "t1"}},
+				{Labels: map[string]string{"app": "api", "level": "error"}, Timestamp: "1700000001000000000", Line: "second"},
+			}
+			if !reflect.DeepEqual(p.Lines, want) {
+				t.Errorf("decoded lines =\n%+v\nwant\n%+v", p.Lines, want)
+			}
+		})
+	}
+}
+
+func TestGRPCTransportError(t *testing.T) {
+	server := newFakeGRPCLoki(t, "400", "entry%20too%20far%20behind")
+	c := newStartedClient(t, ClientConfig{
+		URL:         server.URL,
+		Transport:   TransportGRPC,
+		OnSendError: func([]pkg.LogEntry, error) {},
+	})
+
+	_ = c.Info("hello")
+	err := c.Flush()
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest || statusErr.Body != "entry too far behind" {
+		t.Errorf("Flush() error = %v, want a 400 StatusError with the decoded message", err)
+	}
+	if c.Stats().EntriesSent != 0 {
+		t.Errorf("EntriesSent = %d, want 0", c.Stats().EntriesSent)
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    http.Header
+		trailer   http.Header
+		code      int
+		wantErr   bool
+		wantHTTP  int
+		wantGRPC  int
+		retryable bool
+	}{
+		{name: "ok", trailer: http.Header{"Grpc-Status": {"0"}}, code: http.StatusOK},
+		{name: "trailers only", header: http.Header{"Grpc-Status": {"0"}}, code: http.StatusOK},
+		{name: "unavailable", trailer: http.Header{"Grpc-Status": {"14"}}, code: http.StatusOK,
+			wantErr: true, wantGRPC: 14, retryable: true},
+		{name: "invalid argument", trailer: http.Header{"Grpc-Status": {"3"}}, code: http.StatusOK,
+			wantErr: true, wantGRPC: 3},
+		{name: "rate limited", header: http.Header{"Grpc-Status": {"429"}}, code: http.StatusOK,
+			wantErr: true, wantHTTP: http.StatusTooManyRequests, retryable: true},
+		{name: "proxy error", code: http.StatusBadGateway, wantErr: true, wantHTTP: http.StatusBadGateway, retryable: true},
+		{name: "missing status", code: http.StatusOK, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			err := grpcStatus(&http.Response{
+				StatusCode: tt.code,
+				Header:     header,
+				Trailer:    tt.trailer,
+				Body:       io.NopCloser(strings.NewReader("")),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("grpcStatus() error = %v, want error %v", err, tt.wantErr)
+			}
+			var statusErr *StatusError
+			if tt.wantHTTP != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantHTTP) {
+				t.Errorf("grpcStatus() error = %v, want StatusError %d", err, tt.wantHTTP)
+			}
+			var grpcErr *GRPCStatusError
+			if tt.wantGRPC != 0 && (!errors.As(err, &grpcErr) || grpcErr.Code != tt.wantGRPC) {
+				t.Errorf("grpcStatus() error = %v, want GRPCStatusError %d", err, tt.wantGRPC)
+			}
+			if err != nil && IsRetryable(err) != tt.retryable {
+				t.Errorf("IsRetryable(%v) = %v, want %v", err, !tt.retryable, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestGRPCTransportConfig(t *testing.T) {
+	if _, err := NewClient(ClientConfig{URL: "http://loki:9095", Transport: "udp"}); err == nil {
+		t.Error("NewClient() with unknown transport error = nil, want error")
+	}
+
+	c, err := NewClient(ClientConfig{URL: "http://loki:9095", Transport: TransportGRPC})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Ping(context.Background()); err == nil {
+		t.Error("Ping() with grpc transport error = nil, want error")
+	}
+}
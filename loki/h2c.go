@@ -0,0 +1,15 @@
+//go:build go1.24
+
+package loki
+
+import "net/http"
+
+// newGRPCHTTPClient 返回 gRPC 推送默认使用的 HTTP 客户端
+// gRPC 要求 HTTP/2：http 地址使用明文 HTTP/2（h2c），https 地址通过 TLS 协商 HTTP/2
+func newGRPCHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Protocols = new(http.Protocols)
+	transport.Protocols.SetHTTP2(true)
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: transport}, nil
+}
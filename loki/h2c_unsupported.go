@@ -0,0 +1,14 @@
+//go:build !go1.24
+
+package loki
+
+import (
+	"errors"
+	"net/http"
+)
+
+// newGRPCHTTPClient 在 Go 1.24 之前的版本中不可用，标准库不支持明文 HTTP/2
+// 需要 gRPC 推送时升级 Go，或者通过 HTTPClient 传入支持 HTTP/2 的客户端
+func newGRPCHTTPClient() (*http.Client, error) {
+	return nil, errors.New("grpc transport requires Go 1.24 or a custom HTTPClient with HTTP/2 support")
+}
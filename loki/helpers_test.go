@@ -0,0 +1,191 @@
+package loki
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// pushedLine 是测试服务器收到的一条日志
+type pushedLine struct {
+	// Labels 是日志所在流的标签
+	Labels map[string]string
+	// Timestamp 是时间戳字符串
+	Timestamp string
+	// Line 是日志内容
+	Line string
+	// Metadata 是结构化元数据
+	Metadata map[string]string
+}
+
+// pushRecord 是测试服务器收到的一次推送请求
+type pushRecord struct {
+	// Path 是请求路径
+	Path string
+	// Header 是请求头
+	Header http.Header
+	// Lines 是请求中的所有日志，按流的顺序排列
+	Lines []pushedLine
+}
+
+// fakeLoki 是记录推送请求的 Loki 测试服务器
+type fakeLoki struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	pushes  []pushRecord
+	respond func(w http.ResponseWriter, r *http.Request)
+}
+
+// newFakeLoki 启动一个 Loki 测试服务器
+// respond 为 nil 时总是返回 204，否则由 respond 写入响应，请求内容仍会被记录
+func newFakeLoki(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) *fakeLoki {
+	t.Helper()
+
+	f := &fakeLoki{respond: respond}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeLoki) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == QueryRangePath {
+		f.query(w, r)
+		return
+	}
+
+	record := pushRecord{Path: r.URL.Path, Header: r.Header.Clone()}
+	if r.Header.Get("Content-Type") == "application/x-protobuf" {
+		data, _ := io.ReadAll(r.Body)
+		lines, err := decodeProtobufPush(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		record.Lines = lines
+		f.record(w, r, record)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+
+	var req struct {
+		Streams []struct {
+			Stream map[string]string   `json:"stream"`
+			Values [][]json.RawMessage `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, s := range req.Streams {
+		for _, v := range s.Values {
+			line := pushedLine{Labels: s.Stream}
+			_ = json.Unmarshal(v[0], &line.Timestamp)
+			_ = json.Unmarshal(v[1], &line.Line)
+			if len(v) > 2 {
+				_ = json.Unmarshal(v[2], &line.Metadata)
+			}
+			record.Lines = append(record.Lines, line)
+		}
+	}
+	f.record(w, r, record)
+}
+
+// record 保存收到的推送请求并写入响应
+func (f *fakeLoki) record(w http.ResponseWriter, r *http.Request, record pushRecord) {
+	f.mu.Lock()
+	f.pushes = append(f.pushes, record)
+	f.mu.Unlock()
+
+	if f.respond != nil {
+		f.respond(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// query 响应范围查询，忽略查询语句和时间范围，按收到的顺序返回所有日志
+// 每条日志作为一个单独的流返回，limit 限制返回的条数
+func (f *fakeLoki) query(w http.ResponseWriter, r *http.Request) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][]interface{}   `json:"values"`
+	}
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string   `json:"resultType"`
+			Result     []stream `json:"result"`
+		} `json:"data"`
+	}
+	result.Status = "success"
+	result.Data.ResultType = "streams"
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	for _, line := range f.Lines() {
+		if limit > 0 && len(result.Data.Result) >= limit {
+			break
+		}
+		value := []interface{}{line.Timestamp, line.Line}
+		if len(line.Metadata) > 0 {
+			value = append(value, line.Metadata)
+		}
+		result.Data.Result = append(result.Data.Result, stream{Stream: line.Labels, Values: [][]interface{}{value}})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// Pushes 返回收到的所有推送请求
+func (f *fakeLoki) Pushes() []pushRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]pushRecord(nil), f.pushes...)
+}
+
+// Lines 返回所有推送请求中的日志
+func (f *fakeLoki) Lines() []pushedLine {
+	var lines []pushedLine
+	for _, p := range f.Pushes() {
+		lines = append(lines, p.Lines...)
+	}
+	return lines
+}
+
+// newStartedClient 创建并启动客户端，测试结束时自动停止
+func newStartedClient(t *testing.T, config ClientConfig) *Client {
+	t.Helper()
+
+	c, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	c.Start()
+	t.Cleanup(func() { _ = c.Stop(context.Background()) })
+	return c
+}
+
+// respondStatus 返回一个总是写入指定状态码的响应函数
+func respondStatus(code int) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}
+}
@@ -0,0 +1,48 @@
+package loki
+
+import "sync"
+
+// inflight 跟踪正在进行的发送，用于 Stop 等待所有发送完成
+// 与 sync.WaitGroup 不同，计数归零后可以再次增加，且可以在 select 中带超时等待
+type inflight struct {
+	mu sync.Mutex
+	// count 是正在进行的发送数量
+	count int
+	// idle 在 count 归零时关闭
+	idle chan struct{}
+}
+
+// add 登记一次开始的发送
+func (f *inflight) add() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.count == 0 {
+		f.idle = make(chan struct{})
+	}
+	f.count++
+}
+
+// done 登记一次结束的发送
+func (f *inflight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count--
+	if f.count == 0 {
+		close(f.idle)
+	}
+}
+
+// wait 返回一个在当前所有发送完成后关闭的通道
+func (f *inflight) wait() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.count == 0 {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+	return f.idle
+}
@@ -0,0 +1,83 @@
+package loki
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+// ValidLabelName 判断标签名是否符合Loki的要求，即匹配 [a-zA-Z_][a-zA-Z0-9_]*
+// Loki会拒绝包含非法标签名的整个推送请求
+func ValidLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validateLabelNames 检查标签集中的标签名，有非法标签名时返回列出所有非法标签名的错误
+func validateLabelNames(labels map[string]string) error {
+	var invalid []string
+	for name := range labels {
+		if !ValidLabelName(name) {
+			invalid = append(invalid, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	sort.Strings(invalid)
+	return fmt.Errorf("invalid label names %s: must match [a-zA-Z_][a-zA-Z0-9_]*", strings.Join(invalid, ", "))
+}
+
+// dropInvalidLabels 去掉日志附加标签中的非法标签名
+// 附加标签来自字段等动态取值，无法在创建客户端时检查，
+// 因此跳过非法的标签而不是让整个请求被Loki拒绝，并为每个标签名记录一次警告
+func (c *Client) dropInvalidLabels(entry pkg.LogEntry) pkg.LogEntry {
+	entry.Labels = validLabels(entry.Labels, c.warnInvalidLabel)
+	return entry
+}
+
+// warnInvalidLabel 通过 ErrorLogger 记录被跳过的非法标签名，同一个客户端对每个标签名只警告一次
+func (c *Client) warnInvalidLabel(name string) {
+	if _, warned := c.labelWarnings.LoadOrStore(name, struct{}{}); !warned {
+		c.errorLog.Printf("Skipping invalid Loki label name %q: must match [a-zA-Z_][a-zA-Z0-9_]*", name)
+	}
+}
+
+// validLabels 返回去掉非法标签名之后的标签，全部合法时原样返回
+// warn 不为 nil 时对每个被跳过的标签名调用
+func validLabels(labels map[string]string, warn func(name string)) map[string]string {
+	valid := true
+	for name := range labels {
+		if !ValidLabelName(name) {
+			valid = false
+			break
+		}
+	}
+	if valid {
+		return labels
+	}
+
+	result := make(map[string]string, len(labels))
+	for name, value := range labels {
+		if ValidLabelName(name) {
+			result[name] = value
+			continue
+		}
+		if warn != nil {
+			warn(name)
+		}
+	}
+	return result
+}
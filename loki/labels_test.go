@@ -0,0 +1,112 @@
+package loki
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestValidLabelName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"app", true},
+		{"_private", true},
+		{"service_name2", true},
+		{"Env", true},
+		{"", false},
+		{"service-name", false},
+		{"k8s.namespace", false},
+		{"1st", false},
+		{"with space", false},
+		{"naïve", false},
+	}
+	for _, tt := range tests {
+		if got := ValidLabelName(tt.name); got != tt.want {
+			t.Errorf("ValidLabelName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientRejectsInvalidLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ClientConfig
+		wantErr string
+	}{
+		{
+			name:    "static labels",
+			config:  ClientConfig{URL: "http://loki", Labels: map[string]string{"app": "svc", "service-name": "x", "0zone": "a"}},
+			wantErr: `invalid label names "0zone", "service-name"`,
+		},
+		{
+			name: "target labels",
+			config: ClientConfig{
+				URL:     "http://loki",
+				Targets: []Target{{Name: "central", URL: "http://central", Labels: map[string]string{"k8s.ns": "prod"}}},
+			},
+			wantErr: `target central: invalid label names "k8s.ns"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewClient() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvalidDynamicLabelsSkipped(t *testing.T) {
+	stdLog := captureLog(t)
+	logs := &recordingLogger{}
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, ErrorLogger: logs})
+
+	labels := map[string]string{"route": "pay", "http.method": "GET", "9lives": "x"}
+	_ = c.Push(pkg.LogEntry{Message: "push", Level: zapcore.InfoLevel, Labels: labels})
+	_ = c.PushBatch([]pkg.LogEntry{{Message: "batch", Level: zapcore.InfoLevel, Labels: labels}})
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v, want invalid labels skipped instead of a rejected push", err)
+	}
+
+	lines := server.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		if line.Labels["route"] != "pay" {
+			t.Errorf("line %q labels = %v, want valid label kept", line.Line, line.Labels)
+		}
+		for name := range line.Labels {
+			if !ValidLabelName(name) {
+				t.Errorf("line %q has invalid label %q", line.Line, name)
+			}
+		}
+	}
+	if len(labels) != 3 {
+		t.Errorf("caller's labels modified: %v", labels)
+	}
+	for _, name := range []string{"http.method", "9lives"} {
+		if n := strings.Count(logs.String(), strconv.Quote(name)); n != 1 {
+			t.Errorf("warning for %q logged %d times, want once", name, n)
+		}
+	}
+
+	if stdLog.Len() != 0 {
+		t.Errorf("standard log output = %q, want warnings on ErrorLogger only", stdLog.String())
+	}
+
+	// 每个客户端各自警告一次，之前的客户端不会让后来的客户端保持沉默
+	otherLogs := &recordingLogger{}
+	other := newStartedClient(t, ClientConfig{URL: server.URL, ErrorLogger: otherLogs})
+	_ = other.Push(pkg.LogEntry{Message: "other", Level: zapcore.InfoLevel, Labels: labels})
+	if n := strings.Count(otherLogs.String(), strconv.Quote("http.method")); n != 1 {
+		t.Errorf("second client logged the warning %d times, want once", n)
+	}
+}
@@ -0,0 +1,68 @@
+package loki
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// sendLatencyBuckets 是发送耗时直方图的桶上限，与 Prometheus 默认的桶一致
+var sendLatencyBuckets = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyStats 是发送耗时直方图的快照
+// 每次 HTTP 推送请求（包括重试）记录一次耗时，无论成功与否
+type LatencyStats struct {
+	// Buckets 是各个桶的上限，按升序排列
+	Buckets []time.Duration
+	// Counts 是耗时不超过对应桶上限的请求数，是累计值，与 Buckets 一一对应
+	Counts []uint64
+	// Count 是请求总数
+	Count uint64
+	// Sum 是所有请求的耗时之和
+	Sum time.Duration
+}
+
+// latencyHistogram 是线程安全的发送耗时直方图
+type latencyHistogram struct {
+	// counts 是落在每个桶中的请求数，最后一个是超过所有桶上限的请求数
+	counts [len(sendLatencyBuckets) + 1]atomic.Uint64
+	// sum 是耗时之和（纳秒）
+	sum atomic.Int64
+}
+
+// observe 记录一次请求耗时
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := 0
+	for i < len(sendLatencyBuckets) && d > sendLatencyBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// snapshot 返回直方图的快照
+func (h *latencyHistogram) snapshot() LatencyStats {
+	stats := LatencyStats{
+		Buckets: append([]time.Duration(nil), sendLatencyBuckets[:]...),
+		Counts:  make([]uint64, len(sendLatencyBuckets)),
+		Sum:     time.Duration(h.sum.Load()),
+	}
+	for i := range h.counts {
+		stats.Count += h.counts[i].Load()
+		if i < len(stats.Counts) {
+			stats.Counts[i] = stats.Count
+		}
+	}
+	return stats
+}
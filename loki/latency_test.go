@@ -0,0 +1,54 @@
+package loki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	for _, d := range []time.Duration{time.Millisecond, 5 * time.Millisecond, 40 * time.Millisecond, 3 * time.Second, time.Minute} {
+		h.observe(d)
+	}
+
+	stats := h.snapshot()
+	if stats.Count != 5 {
+		t.Errorf("Count = %d, want 5", stats.Count)
+	}
+	if want := time.Minute + 3*time.Second + 46*time.Millisecond; stats.Sum != want {
+		t.Errorf("Sum = %s, want %s", stats.Sum, want)
+	}
+	want := map[time.Duration]uint64{
+		5 * time.Millisecond:  2, // 上限是闭区间
+		50 * time.Millisecond: 3,
+		time.Second:           3,
+		5 * time.Second:       4,
+		10 * time.Second:      4, // 超过所有桶上限的请求只计入 Count
+	}
+	for i, upper := range stats.Buckets {
+		if n, ok := want[upper]; ok && stats.Counts[i] != n {
+			t.Errorf("bucket %s = %d, want %d", upper, stats.Counts[i], n)
+		}
+	}
+}
+
+func TestSendLatencyInStats(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL})
+
+	_ = c.Push(pkg.LogEntry{Message: "hello", Level: zapcore.InfoLevel})
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	latency := c.Stats().SendLatency
+	if latency.Count != 1 || latency.Sum <= 0 {
+		t.Errorf("SendLatency = %+v, want one observed request", latency)
+	}
+	if len(latency.Buckets) != len(latency.Counts) || latency.Counts[len(latency.Counts)-1] > latency.Count {
+		t.Errorf("SendLatency buckets inconsistent: %+v", latency)
+	}
+}
@@ -0,0 +1,65 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrSendQueueFull 表示等待发送的批次达到 MaxQueuedSends，本批日志被放回缓冲区
+var ErrSendQueueFull = errors.New("send queue is full")
+
+// sendLimiter 限制同时进行的推送数量
+// 超出 MaxConcurrentSends 的推送排队等待，排队数量超过 MaxQueuedSends 时拒绝
+type sendLimiter struct {
+	// slots 是信号量，容量为 MaxConcurrentSends
+	slots chan struct{}
+	// maxQueued 是最多排队等待的推送数量，0 表示不限制
+	maxQueued int
+	// queued 是正在排队等待的推送数量
+	queued atomic.Int64
+}
+
+// newSendLimiter 创建推送限制器，maxConcurrent 小于等于 0 时返回 nil，表示不限制
+func newSendLimiter(maxConcurrent, maxQueued int) *sendLimiter {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	return &sendLimiter{slots: make(chan struct{}, maxConcurrent), maxQueued: maxQueued}
+}
+
+// acquire 占用一个推送名额，没有空闲名额时排队等待
+// force 为 true 时不受排队数量限制，用于关闭前的最后一次发送。
+// 返回：
+//   - bool: 是否经过了排队
+//   - error: 排队已满时返回 ErrSendQueueFull，ctx 结束时返回 ctx 的错误
+func (l *sendLimiter) acquire(ctx context.Context, force bool) (bool, error) {
+	if l == nil {
+		return false, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return false, nil
+	default:
+	}
+
+	n := l.queued.Add(1)
+	defer l.queued.Add(-1)
+	if !force && l.maxQueued > 0 && n > int64(l.maxQueued) {
+		return false, ErrSendQueueFull
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return true, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// release 释放 acquire 占用的名额
+func (l *sendLimiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
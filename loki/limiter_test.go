@@ -0,0 +1,96 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+// peakServer 返回记录最大并发请求数的假Loki，每个请求耗时 delay
+func peakServer(t *testing.T, delay time.Duration) (*fakeLoki, *atomic.Int32) {
+	var current, peak atomic.Int32
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		current.Add(-1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return server, &peak
+}
+
+// pushRoutes 推送 n 条分属不同流的日志，使其分散到各个分片
+func pushRoutes(c *Client, n int) {
+	for i := 0; i < n; i++ {
+		_ = c.Push(pkg.LogEntry{
+			Message: strconv.Itoa(i),
+			Level:   zapcore.InfoLevel,
+			Labels:  map[string]string{"route": strconv.Itoa(i)},
+		})
+	}
+}
+
+func TestMaxConcurrentSends(t *testing.T) {
+	server, peak := peakServer(t, 20*time.Millisecond)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 1000, FlushWorkers: 8, MaxConcurrentSends: 2})
+
+	pushRoutes(c, 32)
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("peak concurrent requests = %d, want at most 2", got)
+	}
+	if got := len(server.Lines()); got != 32 {
+		t.Errorf("got %d lines, want 32", got)
+	}
+	if got := c.Stats().QueuedSends; got == 0 {
+		t.Error("Stats().QueuedSends = 0, want queued sends counted")
+	}
+}
+
+func TestMaxQueuedSends(t *testing.T) {
+	server, peak := peakServer(t, 50*time.Millisecond)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, BatchSize: 1000, FlushWorkers: 4, MaxConcurrentSends: 1, MaxQueuedSends: 1})
+
+	pushRoutes(c, 32)
+	err := c.Flush()
+	if !errors.Is(err, ErrSendQueueFull) {
+		t.Fatalf("Flush() error = %v, want ErrSendQueueFull", err)
+	}
+
+	stats := c.Stats()
+	if stats.RejectedSends == 0 {
+		t.Error("Stats().RejectedSends = 0, want rejected sends counted")
+	}
+	if stats.Dropped != 0 {
+		t.Errorf("Stats().Dropped = %d, want rejected entries kept", stats.Dropped)
+	}
+	if got := len(server.Lines()) + c.BufferLen(); got != 32 {
+		t.Errorf("sent + buffered = %d, want 32", got)
+	}
+
+	// 关闭前的最后一次发送不受排队数量限制
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := len(server.Lines()); got != 32 {
+		t.Errorf("got %d lines after Stop, want 32", got)
+	}
+	if got := peak.Load(); got > 1 {
+		t.Errorf("peak concurrent requests = %d, want 1", got)
+	}
+}
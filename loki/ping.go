@@ -0,0 +1,58 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ReadyPath 是Loki就绪检查接口的路径
+const ReadyPath = "/ready"
+
+// Ping 检查所有推送目标的Loki是否就绪
+// 依次请求每个目标的 /ready 接口，使用与推送相同的 HTTP 客户端、租户和认证方式，
+// 状态码不是 200 或请求失败时返回错误，多个目标失败时返回合并的错误。
+// 适合在服务的就绪检查中调用，尽早发现地址错误、认证失败等配置问题；
+// 不经过缓冲区，客户端未启动或已暂停时同样可以调用。使用 gRPC 推送时不可用
+func (c *Client) Ping(ctx context.Context) error {
+	if c.grpc() {
+		return fmt.Errorf("ping is not supported with grpc transport")
+	}
+	var errs []error
+	for _, t := range c.targets {
+		if err := c.ping(ctx, t); err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", t.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ping 请求指定目标的就绪检查接口
+func (c *Client) ping(ctx context.Context, t *target) error {
+	u := strings.TrimSuffix(t.baseURL, "/") + ReadyPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+	if t.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", t.tenantID)
+	}
+	if err := t.auth.apply(req); err != nil {
+		return err
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newReadyServer 启动一个只响应就绪检查的测试服务器
+// 设置了 token 时，请求没有携带该 Bearer 令牌则返回 401
+func newReadyServer(t *testing.T, status int, token string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ReadyPath || r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("ready\n"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		token      string
+		auth       Auth
+		wantStatus int
+	}{
+		{"ready", http.StatusOK, "", Auth{}, 0},
+		{"not ready", http.StatusServiceUnavailable, "", Auth{}, http.StatusServiceUnavailable},
+		{"authenticated", http.StatusOK, "secret", Auth{BearerToken: "secret"}, 0},
+		{"wrong credentials", http.StatusOK, "secret", Auth{BearerToken: "wrong"}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newReadyServer(t, tt.status, tt.token)
+			// URL 末尾的斜杠不影响就绪检查的地址
+			c, err := NewClient(ClientConfig{URL: server.URL + "/", Auth: tt.auth})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			err = c.Ping(context.Background())
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Errorf("Ping() error = %v, want nil", err)
+				}
+				return
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+				t.Errorf("Ping() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPingTargets(t *testing.T) {
+	ready := newReadyServer(t, http.StatusOK, "")
+	down := newReadyServer(t, http.StatusServiceUnavailable, "")
+	c, err := NewClient(ClientConfig{
+		URL: ready.URL,
+		Targets: []Target{
+			{Name: "backup", URL: down.URL},
+			{Name: "unreachable", URL: "http://127.0.0.1:1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	err = c.Ping(context.Background())
+	if err == nil {
+		t.Fatal("Ping() error = nil, want errors from failing targets")
+	}
+	msg := err.Error()
+	for _, want := range []string{"target backup", "target unreachable"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Ping() error = %q, want %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, "target "+DefaultTargetName) {
+		t.Errorf("Ping() error = %q, want the ready default target not reported", msg)
+	}
+}
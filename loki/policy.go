@@ -0,0 +1,54 @@
+package loki
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LabelPolicy 检查标签集是否符合团队的标签规范
+// 返回所有违规项的描述，没有违规时返回空切片
+type LabelPolicy func(labels map[string]string) []string
+
+// PermissiveLabelPolicy 是默认的标签规范，接受任何标签集
+func PermissiveLabelPolicy(map[string]string) []string {
+	return nil
+}
+
+// NewLabelPolicy 创建一个要求包含指定标签、禁止使用指定标签的规范
+// 参数：
+//   - required: 必须存在且取值非空的标签
+//   - forbidden: 禁止使用的标签，通常是 user_id 这类高基数标签
+//
+// 返回：
+//   - LabelPolicy: 标签规范
+func NewLabelPolicy(required, forbidden []string) LabelPolicy {
+	return func(labels map[string]string) []string {
+		var violations []string
+		for _, name := range required {
+			if labels[name] == "" {
+				violations = append(violations, fmt.Sprintf("missing required label %q", name))
+			}
+		}
+		for _, name := range forbidden {
+			if _, ok := labels[name]; ok {
+				violations = append(violations, fmt.Sprintf("forbidden label %q", name))
+			}
+		}
+		return violations
+	}
+}
+
+// checkLabelPolicy 使用规范检查标签集，有违规时返回列出所有违规项的错误
+func checkLabelPolicy(policy LabelPolicy, labels map[string]string) error {
+	if policy == nil {
+		return nil
+	}
+
+	violations := policy(labels)
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.Strings(violations)
+	return fmt.Errorf("label policy violated: %s", strings.Join(violations, "; "))
+}
@@ -0,0 +1,51 @@
+package loki
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelPolicy(t *testing.T) {
+	policy := NewLabelPolicy([]string{"team"}, []string{"user_id"})
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []string
+	}{
+		{"compliant", map[string]string{"team": "payments", "app": "api"}, nil},
+		{"missing team", map[string]string{"app": "api"}, []string{`missing required label "team"`}},
+		{"empty team", map[string]string{"team": ""}, []string{`missing required label "team"`}},
+		{"forbidden user_id", map[string]string{"team": "payments", "user_id": "42"}, []string{`forbidden label "user_id"`}},
+		{"both violations", map[string]string{"user_id": "42"}, []string{`missing required label "team"`, `forbidden label "user_id"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(ClientConfig{URL: "http://loki", Labels: tt.labels, LabelPolicy: policy})
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("NewClient() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("NewClient() error = nil, want policy violation")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPermissiveLabelPolicy(t *testing.T) {
+	labels := map[string]string{"user_id": "42"}
+	for _, policy := range []LabelPolicy{nil, PermissiveLabelPolicy} {
+		if _, err := NewClient(ClientConfig{URL: "http://loki", Labels: labels, LabelPolicy: policy}); err != nil {
+			t.Errorf("NewClient() error = %v, want any labels accepted", err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package loki
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+)
+
+// Protocol 定义推送请求的编码格式
+type Protocol string
+
+const (
+	// ProtocolJSON 使用 JSON 编码，默认值
+	ProtocolJSON Protocol = "json"
+	// ProtocolProtobuf 使用 snappy 压缩的 logproto 编码，Content-Type 为 application/x-protobuf
+	// 这是Loki原生的推送格式，编码更快、请求体更小，适合日志量大的服务。
+	// 该格式自带压缩，不再使用 Compression 指定的压缩方式，时间戳总是纳秒精度
+	ProtocolProtobuf Protocol = "protobuf"
+)
+
+// protobuf 线路类型
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// encodeProtobuf 将推送请求编码为 snappy 压缩的 logproto.PushRequest，用于 HTTP 推送接口
+func encodeProtobuf(req PushRequest) ([]byte, error) {
+	buf, err := marshalPushRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, buf), nil
+}
+
+// marshalPushRequest 将推送请求编码为未压缩的 logproto.PushRequest
+// 对应的 proto 定义：
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter {
+//	  google.protobuf.Timestamp timestamp = 1;
+//	  string line = 2;
+//	  repeated LabelPairAdapter structuredMetadata = 3;
+//	}
+//	message LabelPairAdapter { string name = 1; string value = 2; }
+//
+// 请求中的时间戳必须是 TimestampUnixNano 格式
+func marshalPushRequest(req PushRequest) ([]byte, error) {
+	var buf []byte
+	for _, stream := range req.Streams {
+		msg, err := appendStream(nil, stream)
+		if err != nil {
+			return nil, err
+		}
+		buf = appendBytesField(buf, 1, msg)
+	}
+	return buf, nil
+}
+
+// appendStream 编码一个 StreamAdapter
+func appendStream(buf []byte, stream Stream) ([]byte, error) {
+	buf = appendBytesField(buf, 1, []byte(formatLabels(stream.Stream)))
+
+	var entry, ts, pair []byte
+	for _, value := range stream.Values {
+		ns, err := strconv.ParseInt(value.Timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q: %v", value.Timestamp, err)
+		}
+
+		// google.protobuf.Timestamp，零值字段省略
+		ts = ts[:0]
+		if seconds := ns / 1e9; seconds != 0 {
+			ts = appendVarintField(ts, 1, uint64(seconds))
+		}
+		if nanos := ns % 1e9; nanos != 0 {
+			ts = appendVarintField(ts, 2, uint64(nanos))
+		}
+
+		entry = entry[:0]
+		entry = appendBytesField(entry, 1, ts)
+		entry = appendBytesField(entry, 2, []byte(value.Line))
+		for _, name := range sortedKeys(value.Metadata) {
+			pair = pair[:0]
+			pair = appendBytesField(pair, 1, []byte(name))
+			pair = appendBytesField(pair, 2, []byte(value.Metadata[name]))
+			entry = appendBytesField(entry, 3, pair)
+		}
+		buf = appendBytesField(buf, 2, entry)
+	}
+	return buf, nil
+}
+
+// formatLabels 将标签集格式化为Loki要求的字符串，例如 {app="api", level="info"}
+// 标签按名称排序，值按 Go 字符串字面量的规则转义
+func formatLabels(labels map[string]string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range sortedKeys(labels) {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// sortedKeys 返回按字典序排列的键
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appendVarintField 追加一个 varint 类型的字段
+func appendVarintField(buf []byte, field int, v uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(buf, v)
+}
+
+// appendBytesField 追加一个长度前缀类型的字段，用于字符串和嵌套消息
+func appendBytesField(buf []byte, field int, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
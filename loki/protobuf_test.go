@@ -0,0 +1,253 @@
+package loki
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bt-smart/btlog/pkg"
+	"github.com/golang/snappy"
+	"go.uber.org/zap/zapcore"
+)
+
+// protoField 是解码出的一个 protobuf 字段
+type protoField struct {
+	num   int
+	value uint64
+	data  []byte
+}
+
+// decodeProtoFields 解码一条 protobuf 消息的所有字段，只支持 varint 和长度前缀类型
+func decodeProtoFields(data []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid field key")
+		}
+		data = data[n:]
+
+		field := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			field.value, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errors.New("invalid varint")
+			}
+			data = data[n:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, errors.New("invalid length")
+			}
+			field.data = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", key&7)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// decodeProtobufPush 解码 snappy 压缩的 logproto.PushRequest
+func decodeProtobufPush(body []byte) ([]pushedLine, error) {
+	data, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return decodePushRequest(data)
+}
+
+// decodePushRequest 解码未压缩的 logproto.PushRequest
+func decodePushRequest(data []byte) ([]pushedLine, error) {
+	streams, err := decodeProtoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []pushedLine
+	for _, s := range streams {
+		fields, err := decodeProtoFields(s.data)
+		if err != nil {
+			return nil, err
+		}
+		var labels map[string]string
+		for _, f := range fields {
+			switch f.num {
+			case 1:
+				if labels, err = parseLabels(string(f.data)); err != nil {
+					return nil, err
+				}
+			case 2:
+				line, err := decodeProtoEntry(f.data)
+				if err != nil {
+					return nil, err
+				}
+				line.Labels = labels
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// decodeProtoEntry 解码一个 EntryAdapter
+func decodeProtoEntry(data []byte) (pushedLine, error) {
+	var line pushedLine
+	fields, err := decodeProtoFields(data)
+	if err != nil {
+		return line, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			ts, err := decodeProtoFields(f.data)
+			if err != nil {
+				return line, err
+			}
+			var seconds, nanos int64
+			for _, t := range ts {
+				if t.num == 1 {
+					seconds = int64(t.value)
+				} else {
+					nanos = int64(t.value)
+				}
+			}
+			line.Timestamp = strconv.FormatInt(seconds*1e9+nanos, 10)
+		case 2:
+			line.Line = string(f.data)
+		case 3:
+			pair, err := decodeProtoFields(f.data)
+			if err != nil || len(pair) != 2 {
+				return line, fmt.Errorf("invalid label pair: %v", err)
+			}
+			if line.Metadata == nil {
+				line.Metadata = make(map[string]string)
+			}
+			line.Metadata[string(pair[0].data)] = string(pair[1].data)
+		}
+	}
+	return line, nil
+}
+
+// parseLabels 解析 {a="b", c="d"} 格式的标签字符串
+func parseLabels(s string) (map[string]string, error) {
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("invalid labels %q", s)
+	}
+	s = s[1 : len(s)-1]
+	labels := make(map[string]string)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("invalid labels %q", s)
+		}
+		name := s[:eq]
+		quoted, err := strconv.QuotedPrefix(s[eq+1:])
+		if err != nil {
+			return nil, err
+		}
+		labels[name], _ = strconv.Unquote(quoted)
+		s = strings.TrimPrefix(s[eq+1+len(quoted):], ", ")
+	}
+	return labels, nil
+}
+
+func TestFormatLabels(t *testing.T) {
+	labels := map[string]string{"level": "info", "app": "api", "path": `C:\\tmp "x"`}
+	got := formatLabels(labels)
+	want := `{app="api", level="info", path="C:\\\\tmp \"x\""}`
+	if got != want {
+		t.Errorf("formatLabels() = %s, want %s", got, want)
+	}
+	parsed, err := parseLabels(got)
+	if err != nil || !reflect.DeepEqual(parsed, labels) {
+		t.Errorf("parseLabels() = %v, %v, want %v", parsed, err, labels)
+	}
+	if got := formatLabels(nil); got != "{}" {
+		t.Errorf("formatLabels(nil) = %s, want {}", got)
+	}
+}
+
+func TestProtobufProtocol(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{
+		URL:         server.URL,
+		Labels:      map[string]string{"app": "api"},
+		Protocol:    ProtocolProtobuf,
+		Compression: CompressionGzip,
+		AddEntryID:  true,
+	})
+
+	entries := []pkg.LogEntry{
+		{Timestamp: 1700000000123456789, Message: "first", Level: zapcore.InfoLevel, Metadata: map[string]string{"trace_id": "t1"}},
+		{Timestamp: 1700000001000000000, Message: "second", Level: zapcore.ErrorLevel},
+		{Timestamp: 5, Message: "early", Level: zapcore.InfoLevel},
+	}
+	for _, e := range entries {
+		_ = c.Push(e)
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	pushes := server.Pushes()
+	if len(pushes) != 1 {
+		t.Fatalf("got %d requests, want 1", len(pushes))
+	}
+	if got := pushes[0].Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none since snappy is built in", got)
+	}
+
+	want := []pushedLine{
+		{Labels: map[string]string{"app": "api", "level": "info"}, Timestamp: "5", Line: "early",
+			Metadata: map[string]string{EntryIDKey: entries[2].ID()}},
+		{Labels: map[string]string{"app": "api", "level": "info"}, Timestamp: "1700000000123456789", Line: "first",
+			Metadata: map[string]string{"trace_id": "t1", EntryIDKey: entries[0].ID()}},
+		{Labels: map[string]string{"app": "api", "level": "error"}, Timestamp: "1700000001000000000", Line: "second",
+			Metadata: map[string]string{EntryIDKey: entries[1].ID()}},
+	}
+	if !reflect.DeepEqual(pushes[0].Lines, want) {
+		t.Errorf("decoded lines =\n%+v\nwant\n%+v", pushes[0].Lines, want)
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	entries := typicalBatch(1000)
+	labels := map[string]string{"app": "svc", "env": "prod"}
+	c := &Client{}
+
+	for _, protocol := range []Protocol{ProtocolJSON, ProtocolProtobuf} {
+		b.Run(string(protocol), func(b *testing.B) {
+			c.config.Protocol = protocol
+			var size int
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				data, err := c.encodeRequest(entries, labels)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "bytes")
+		})
+	}
+
+	// 作为对照，JSON 加 gzip 的大小
+	b.Run("json+gzip", func(b *testing.B) {
+		c.config.Protocol = ProtocolJSON
+		var size int
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			data, _ := c.encodeRequest(entries, labels)
+			compressed, _ := gzipCompress(data)
+			size = len(compressed)
+		}
+		b.ReportMetric(float64(size), "bytes")
+	})
+}
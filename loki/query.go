@@ -0,0 +1,179 @@
+package loki
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+// QueryRangePath 是Loki范围查询接口的路径
+const QueryRangePath = "/loki/api/v1/query_range"
+
+// queryResponse 是范围查询接口的响应
+type queryResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Stream map[string]string   `json:"stream"`
+			Values [][]json.RawMessage `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// QueryRange 在 ClientConfig.URL 指定的Loki上执行 LogQL 日志查询，返回 [start, end] 内的日志
+// 主要用于集成测试和小工具，确认推送的日志可以被查询到。
+// 查询使用默认目标的 HTTP 客户端、租户和认证方式，不经过缓冲区和重试。使用 gRPC 推送时不可用
+//
+// 返回的日志按时间戳升序排列：
+//   - Labels 是日志所在流的全部标签，包括默认标签，但不包括级别标签
+//   - Level 由级别标签解析，没有级别标签或无法解析时为 Info
+//   - Metadata 是结构化元数据，Loki 没有返回时为 nil
+//
+// 参数：
+//   - ctx: 控制请求的 context
+//   - logQL: 日志查询语句，例如 {app="demo"} |= "error"，不支持指标查询
+//   - start、end: 查询的时间范围
+//   - limit: 最多返回的日志条数，小于等于 0 时使用Loki的默认值
+func (c *Client) QueryRange(ctx context.Context, logQL string, start, end time.Time, limit int) ([]pkg.LogEntry, error) {
+	if c.grpc() {
+		return nil, fmt.Errorf("query is not supported with grpc transport")
+	}
+	t := c.targets[0]
+	params := url.Values{}
+	params.Set("query", logQL)
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	params.Set("direction", "forward")
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	u := strings.TrimSuffix(c.config.URL, "/") + QueryRangePath + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %w", err)
+	}
+	// 显式请求 gzip 时 http.Transport 不会自动解压，由 queryBody 解压
+	req.Header.Set("Accept-Encoding", "gzip")
+	if t.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", t.tenantID)
+	}
+	if err := t.auth.apply(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := queryBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+
+	var result queryResponse
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode query response failed: %w", err)
+	}
+	return c.queryEntries(result)
+}
+
+// queryBody 返回响应体，Content-Encoding 为 gzip 时返回解压后的内容
+func queryBody(resp *http.Response) (io.ReadCloser, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.NopCloser(resp.Body), nil
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("decompress query response failed: %w", err)
+	}
+	return zr, nil
+}
+
+// queryEntries 将查询结果中的流转换为按时间戳排序的日志
+func (c *Client) queryEntries(result queryResponse) ([]pkg.LogEntry, error) {
+	if result.Status != "success" {
+		return nil, fmt.Errorf("query failed with status %q", result.Status)
+	}
+	if result.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("unsupported query result type %q, only log queries are supported", result.Data.ResultType)
+	}
+
+	var entries []pkg.LogEntry
+	for _, stream := range result.Data.Result {
+		labels := make(map[string]string, len(stream.Stream))
+		level := zapcore.InfoLevel
+		for k, v := range stream.Stream {
+			if c.levelLabel != "" && k == c.levelLabel {
+				if l, err := zapcore.ParseLevel(v); err == nil {
+					level = l
+					continue
+				}
+			}
+			labels[k] = v
+		}
+
+		for _, value := range stream.Values {
+			entry, err := queryEntry(value)
+			if err != nil {
+				return nil, err
+			}
+			entry.Level = level
+			entry.Labels = labels
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp < entries[j].Timestamp
+	})
+	return entries, nil
+}
+
+// queryEntry 解析一条日志，格式为 [时间戳, 内容] 或 [时间戳, 内容, 结构化元数据]
+func queryEntry(value []json.RawMessage) (pkg.LogEntry, error) {
+	var entry pkg.LogEntry
+	if len(value) < 2 {
+		return entry, fmt.Errorf("invalid query result value: want at least 2 elements, got %d", len(value))
+	}
+
+	var ts string
+	if err := json.Unmarshal(value[0], &ts); err != nil {
+		return entry, fmt.Errorf("invalid query result timestamp: %w", err)
+	}
+	n, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return entry, fmt.Errorf("invalid query result timestamp: %w", err)
+	}
+	entry.Timestamp = n
+
+	if err := json.Unmarshal(value[1], &entry.Message); err != nil {
+		return entry, fmt.Errorf("invalid query result line: %w", err)
+	}
+	if len(value) > 2 {
+		if err := json.Unmarshal(value[2], &entry.Metadata); err != nil {
+			return entry, fmt.Errorf("invalid query result metadata: %w", err)
+		}
+	}
+	return entry, nil
+}
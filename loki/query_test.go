@@ -0,0 +1,161 @@
+package loki
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestQueryRangeRoundTrip(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL, Labels: map[string]string{"app": "demo"}})
+
+	now := time.Now()
+	_ = c.Push(pkg.LogEntry{Timestamp: now.UnixNano(), Message: "first", Level: zapcore.InfoLevel})
+	_ = c.Push(pkg.LogEntry{
+		Timestamp: now.Add(time.Millisecond).UnixNano(),
+		Message:   "second",
+		Level:     zapcore.ErrorLevel,
+		Metadata:  map[string]string{"trace_id": "abc"},
+	})
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	entries, err := c.QueryRange(context.Background(), `{app="demo"}`, now.Add(-time.Minute), now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(entries), entries)
+	}
+	first, second := entries[0], entries[1]
+	if first.Message != "first" || first.Level != zapcore.InfoLevel || first.Timestamp != now.UnixNano() {
+		t.Errorf("first entry = %+v", first)
+	}
+	if second.Message != "second" || second.Level != zapcore.ErrorLevel || second.Metadata["trace_id"] != "abc" {
+		t.Errorf("second entry = %+v", second)
+	}
+	if first.Labels["app"] != "demo" {
+		t.Errorf("labels = %v, want app=demo", first.Labels)
+	}
+	if _, ok := first.Labels[LevelLabel]; ok {
+		t.Errorf("labels = %v, want the level label removed", first.Labels)
+	}
+
+	entries, err = c.QueryRange(context.Background(), `{app="demo"}`, now.Add(-time.Minute), now.Add(time.Minute), 1)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("QueryRange(limit 1) = %d entries, %v, want 1", len(entries), err)
+	}
+}
+
+func TestQueryRangeRequest(t *testing.T) {
+	start := time.Unix(100, 0)
+	end := time.Unix(200, 0)
+	var got *http.Request
+	server := newFakeLoki(t, nil)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"level":"warn","job":"a"},"values":[["300","late"]]},
+			{"stream":{"job":"b"},"values":[["150","early"]]}
+		]}}`))
+	})
+	c, err := NewClient(ClientConfig{
+		URL:      server.URL + "/",
+		TenantID: "team-a",
+		Auth:     Auth{BearerToken: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	entries, err := c.QueryRange(context.Background(), `{job=~"a|b"}`, start, end, 0)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+
+	q := got.URL.Query()
+	if got.URL.Path != QueryRangePath || q.Get("query") != `{job=~"a|b"}` ||
+		q.Get("start") != "100000000000" || q.Get("end") != "200000000000" || q.Has("limit") {
+		t.Errorf("request = %s", got.URL)
+	}
+	if got.Header.Get("X-Scope-OrgID") != "team-a" || got.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("headers = %v, want tenant and auth", got.Header)
+	}
+
+	if len(entries) != 2 || entries[0].Message != "early" || entries[1].Message != "late" {
+		t.Fatalf("entries = %+v, want sorted by timestamp", entries)
+	}
+	if entries[0].Level != zapcore.InfoLevel || entries[1].Level != zapcore.WarnLevel {
+		t.Errorf("levels = %s, %s, want info, warn", entries[0].Level, entries[1].Level)
+	}
+}
+
+func TestQueryRangeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"bad status", http.StatusBadRequest, "parse error"},
+		{"metric query", http.StatusOK, `{"status":"success","data":{"resultType":"matrix","result":[]}}`},
+		{"invalid json", http.StatusOK, `not json`},
+		{"invalid timestamp", http.StatusOK, `{"status":"success","data":{"resultType":"streams","result":[{"stream":{},"values":[["x","line"]]}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeLoki(t, nil)
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+			c, err := NewClient(ClientConfig{URL: server.URL})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			_, err = c.QueryRange(context.Background(), `{}`, time.Now().Add(-time.Hour), time.Now(), 10)
+			if err == nil {
+				t.Fatal("QueryRange() error = nil, want an error")
+			}
+			var statusErr *StatusError
+			if tt.status != http.StatusOK && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.status) {
+				t.Errorf("QueryRange() error = %v, want StatusError %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestQueryRangeGzipResponse(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		_, _ = zw.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[{"stream":{"job":"a"},"values":[["1","zipped"]]}]}}`))
+		_ = zw.Close()
+	})
+	c, err := NewClient(ClientConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	entries, err := c.QueryRange(context.Background(), `{job="a"}`, time.Unix(0, 0), time.Now(), 10)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message != "zipped" {
+		t.Errorf("entries = %+v, want the decompressed entry", entries)
+	}
+}
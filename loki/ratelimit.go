@@ -0,0 +1,70 @@
+package loki
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+// rateLimitWarnInterval 是被限流时两次内部警告之间的最小间隔
+const rateLimitWarnInterval = time.Minute
+
+// recordRateLimit 记录一次 429 限流响应
+// 更新限流计数和最近一次的 Retry-After，并在限流期间每分钟最多输出一次警告
+func (c *Client) recordRateLimit(t *target, resp *http.Response) {
+	c.counters.rateLimited.Add(1)
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	c.lastRetryAfter.Store(int64(retryAfter))
+
+	now := time.Now().UnixNano()
+	last := c.lastRateLimitWarn.Load()
+	if now-last < int64(rateLimitWarnInterval) || !c.lastRateLimitWarn.CompareAndSwap(last, now) {
+		return
+	}
+	c.errorLog.Printf("Loki target %s is rate limiting pushes (429), retry after %s, rate limited %d times so far",
+		t.name, retryAfter, c.counters.rateLimited.Load())
+}
+
+// isRateLimited 判断发送错误是否是 429 限流响应
+// 配置了 ValidateResponse 时，只有它返回的 *StatusError 才能被识别
+func isRateLimited(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests
+}
+
+// requeueRateLimited 将因限流没有发送的日志放回缓冲区
+// 在 Retry-After 指定的时间之前不再自动刷新，到期后由工作协程重新发送。
+// 缓冲区已满时按 BufferDropPolicy 丢弃日志
+func (c *Client) requeueRateLimited(entries []pkg.LogEntry) {
+	c.counters.rateLimitedBatches.Add(1)
+	if retryAfter := c.lastRetryAfter.Load(); retryAfter > 0 {
+		c.rateLimitedUntil.Store(time.Now().UnixNano() + retryAfter)
+	}
+	for _, entry := range entries {
+		c.buffer.Add(entry)
+	}
+}
+
+// parseRetryAfter 解析 Retry-After 响应头
+// 支持秒数和 HTTP 日期两种格式，无法解析时返回 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
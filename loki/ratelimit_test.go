@@ -0,0 +1,172 @@
+package loki
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+// captureLog 在测试期间将标准库 log 的输出重定向到缓冲区
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+func TestRateLimitFeedback(t *testing.T) {
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	var failures int
+	c := newStartedClient(t, ClientConfig{
+		URL: server.URL,
+		// 设置回调以免发送失败本身也写入 log
+		OnSendError: func(entries []pkg.LogEntry, err error) { failures++ },
+	})
+	output := captureLog(t)
+
+	for i := 0; i < 5; i++ {
+		_ = c.Info("hello")
+		_ = c.Flush()
+	}
+
+	stats := c.Stats()
+	if stats.RateLimited != 5 {
+		t.Errorf("RateLimited = %d, want 5", stats.RateLimited)
+	}
+	if stats.LastRetryAfter != 7*time.Second {
+		t.Errorf("LastRetryAfter = %s, want 7s", stats.LastRetryAfter)
+	}
+	// 被限流的日志放回缓冲区，不算作丢弃
+	if failures != 0 {
+		t.Errorf("got %d OnSendError calls, want 0", failures)
+	}
+	if stats.RateLimitedBatches != 5 || stats.BufferLength != 5 || stats.Dropped != 0 {
+		t.Errorf("RateLimitedBatches = %d, BufferLength = %d, Dropped = %d, want 5, 5, 0",
+			stats.RateLimitedBatches, stats.BufferLength, stats.Dropped)
+	}
+	if got := strings.Count(output.String(), "rate limiting"); got != 1 {
+		t.Errorf("got %d rate limit warnings, want 1 per minute:\n%s", got, output)
+	}
+
+	// 距离上次警告超过一分钟后再次被限流，应该再输出一次
+	c.lastRateLimitWarn.Add(-int64(rateLimitWarnInterval))
+	_ = c.Info("hello")
+	_ = c.Flush()
+	if got := strings.Count(output.String(), "rate limiting"); got != 2 {
+		t.Errorf("got %d rate limit warnings after the interval, want 2", got)
+	}
+}
+
+func TestRateLimitRequeue(t *testing.T) {
+	var mu sync.Mutex
+	limited := true
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if limited {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{URL: server.URL, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	captureLog(t)
+
+	_ = c.Info("hello")
+	if err := c.Flush(); err == nil {
+		t.Fatal("Flush() error = nil, want the 429 error")
+	}
+	// 429 不在 sendWithRetry 中重试
+	if got := len(server.Pushes()); got != 1 {
+		t.Errorf("got %d requests, want 1 without retrying the 429", got)
+	}
+	stats := c.Stats()
+	if stats.BufferLength != 1 || stats.RateLimitedBatches != 1 || stats.Dropped != 0 || stats.Retries != 0 {
+		t.Errorf("stats = %+v, want the batch requeued", stats)
+	}
+	if got := stats.Targets[DefaultTargetName].Dropped; got != 0 {
+		t.Errorf("target Dropped = %d, want 0", got)
+	}
+
+	// Retry-After 到期之前不会自动刷新
+	c.flush()
+	if got := len(server.Pushes()); got != 1 {
+		t.Errorf("got %d requests during Retry-After, want 1", got)
+	}
+
+	mu.Lock()
+	limited = false
+	mu.Unlock()
+	c.rateLimitedUntil.Store(time.Now().UnixNano())
+	c.flush()
+	lines := server.Lines()
+	if len(lines) != 2 || lines[1].Line != "hello" {
+		t.Fatalf("lines = %+v, want the requeued entry resent", lines)
+	}
+	if c.BufferLen() != 0 {
+		t.Errorf("BufferLen() = %d, want 0", c.BufferLen())
+	}
+}
+
+func TestRateLimitPartialTargets(t *testing.T) {
+	healthy := newFakeLoki(t, nil)
+	limited := newFakeLoki(t, respondStatus(http.StatusTooManyRequests))
+	c := newStartedClient(t, ClientConfig{
+		URL:         healthy.URL,
+		Targets:     []Target{{Name: "central", URL: limited.URL}},
+		OnSendError: func(entries []pkg.LogEntry, err error) {},
+	})
+	captureLog(t)
+
+	_ = c.Info("hello")
+	_ = c.Flush()
+
+	// 其他目标已经收到的日志不放回缓冲区，被限流的目标计入丢弃
+	stats := c.Stats()
+	if stats.BufferLength != 0 || stats.RateLimitedBatches != 0 {
+		t.Errorf("BufferLength = %d, RateLimitedBatches = %d, want 0, 0", stats.BufferLength, stats.RateLimitedBatches)
+	}
+	if got := stats.Targets["central"].Dropped; got != 1 {
+		t.Errorf("central Dropped = %d, want 1", got)
+	}
+	if events := c.DroppedEvents(); len(events) != 1 || events[0].Target != "central" {
+		t.Errorf("DroppedEvents() = %+v, want one event for central", events)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
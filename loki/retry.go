@@ -0,0 +1,94 @@
+package loki
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// StatusError 表示Loki返回了非成功的状态码
+type StatusError struct {
+	// StatusCode 是响应的状态码
+	StatusCode int
+	// Body 是响应体内容
+	Body string
+}
+
+// Error 实现 error 接口
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// IsRetryable 是默认的错误分类器，判断发送失败后是否值得重试
+// 可以重试的错误：
+//   - 网络超时、连接被拒绝、连接被重置、连接意外断开
+//   - 临时性的 DNS 错误
+//   - 429 和 5xx 状态码
+//   - gRPC 的 DEADLINE_EXCEEDED、RESOURCE_EXHAUSTED、ABORTED、UNAVAILABLE 状态
+//
+// 不应重试的错误：
+//   - 证书校验失败等 TLS 错误，重试不会改变结果
+//   - 域名不存在
+//   - 其他 4xx 状态码，通常是请求本身有问题
+//   - context 被取消
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var grpcErr *GRPCStatusError
+	if errors.As(err, &grpcErr) {
+		return grpcErr.retryable()
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// TLS 证书错误重试没有意义
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &verifyErr) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound && (dnsErr.IsTimeout || dnsErr.IsTemporary)
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return false
+}
+
+// retryDelay 返回第 attempt 次重试前的等待时间，按指数增长
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	return base << uint(attempt)
+}
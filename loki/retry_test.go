@@ -0,0 +1,192 @@
+package loki
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+// timeoutError 是一个超时的 net.Error
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// urlError 模拟 http.Client 返回的错误
+func urlError(err error) error {
+	return &url.Error{Op: "Post", URL: "http://loki/loki/api/v1/push", Err: err}
+}
+
+// syscallError 模拟连接时的系统调用错误
+func syscallError(errno syscall.Errno) error {
+	return urlError(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)})
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"unknown error", errors.New("boom"), false},
+		{"status 429", &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"status 500", &StatusError{StatusCode: http.StatusInternalServerError}, true},
+		{"status 503 wrapped", fmt.Errorf("send: %w", &StatusError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"status 400", &StatusError{StatusCode: http.StatusBadRequest}, false},
+		{"status 401", &StatusError{StatusCode: http.StatusUnauthorized}, false},
+		{"connection refused", syscallError(syscall.ECONNREFUSED), true},
+		{"connection reset", syscallError(syscall.ECONNRESET), true},
+		{"unexpected EOF", urlError(io.ErrUnexpectedEOF), true},
+		{"EOF", urlError(io.EOF), true},
+		{"net timeout", urlError(timeoutError{}), true},
+		{"deadline exceeded", urlError(context.DeadlineExceeded), true},
+		{"canceled", urlError(context.Canceled), false},
+		{"dns not found", urlError(&net.DNSError{Err: "no such host", Name: "loki", IsNotFound: true}), false},
+		{"dns temporary", urlError(&net.DNSError{Err: "server misbehaving", Name: "loki", IsTemporary: true}), true},
+		{"dns timeout", urlError(&net.DNSError{Err: "timeout", Name: "loki", IsTimeout: true}), true},
+		{"unknown authority", urlError(x509.UnknownAuthorityError{}), false},
+		{"hostname mismatch", urlError(x509.HostnameError{Certificate: &x509.Certificate{}, Host: "loki"}), false},
+		{"invalid certificate", urlError(x509.CertificateInvalidError{Reason: x509.Expired}), false},
+		{"tls verification", urlError(&tls.CertificateVerificationError{Err: errors.New("bad cert")}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryUsesConfiguredClassifier(t *testing.T) {
+	tests := []struct {
+		name        string
+		isRetryable func(error) bool
+		want        int
+	}{
+		{"default retries 503", nil, 3},
+		{"custom never retries", func(error) bool { return false }, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeLoki(t, respondStatus(http.StatusServiceUnavailable))
+			c := newStartedClient(t, ClientConfig{
+				URL:          server.URL,
+				MaxRetries:   2,
+				RetryBackoff: time.Millisecond,
+				IsRetryable:  tt.isRetryable,
+			})
+			_ = c.Info("hello")
+			if err := c.Flush(); err == nil {
+				t.Fatal("Flush() error = nil, want send failure")
+			}
+			if got := len(server.Pushes()); got != tt.want {
+				t.Errorf("got %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetriesDoNotBlockPush(t *testing.T) {
+	server := newFakeLoki(t, respondStatus(http.StatusServiceUnavailable))
+	c := newStartedClient(t, ClientConfig{
+		URL:          server.URL,
+		BatchSize:    1,
+		MaxRetries:   3,
+		RetryBackoff: 50 * time.Millisecond,
+	})
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_ = c.Push(pkg.LogEntry{Message: "hello", Level: zapcore.InfoLevel})
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Push took %s with a failing server, want it to return without waiting for retries", elapsed)
+	}
+}
+
+func TestIdempotencyKeyStableAcrossRetries(t *testing.T) {
+	const header = "X-Idempotency-Key"
+
+	var attempts int
+	server := newFakeLoki(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newStartedClient(t, ClientConfig{
+		URL:               server.URL,
+		MaxRetries:        2,
+		RetryBackoff:      time.Millisecond,
+		IdempotencyHeader: header,
+	})
+
+	for _, msg := range []string{"first", "second"} {
+		_ = c.Push(pkg.LogEntry{Timestamp: 1, Message: msg, Level: zapcore.InfoLevel})
+		if err := c.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+
+	pushes := server.Pushes()
+	if len(pushes) != 6 {
+		t.Fatalf("got %d requests, want 2 batches of 3 attempts", len(pushes))
+	}
+	for batch := 0; batch < 2; batch++ {
+		key := pushes[batch*3].Header.Get(header)
+		if key == "" {
+			t.Fatalf("batch %d: missing %s header", batch, header)
+		}
+		for attempt := 1; attempt < 3; attempt++ {
+			if got := pushes[batch*3+attempt].Header.Get(header); got != key {
+				t.Errorf("batch %d attempt %d: key = %q, want %q", batch, attempt, got, key)
+			}
+		}
+	}
+	if pushes[0].Header.Get(header) == pushes[3].Header.Get(header) {
+		t.Error("different batches share an idempotency key")
+	}
+}
+
+func TestNoIdempotencyHeaderByDefault(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL})
+	_ = c.Info("hello")
+	_ = c.Flush()
+
+	for _, p := range server.Pushes() {
+		if got := p.Header.Get("X-Idempotency-Key"); got != "" {
+			t.Errorf("unexpected idempotency key %q", got)
+		}
+	}
+}
+
+func TestIdempotencyKeyDeterministic(t *testing.T) {
+	a := IdempotencyKey([]byte(`{"streams":[]}`))
+	if a != IdempotencyKey([]byte(`{"streams":[]}`)) {
+		t.Error("same body produced different keys")
+	}
+	if a == IdempotencyKey([]byte(`{"streams":[{}]}`)) {
+		t.Error("different bodies produced the same key")
+	}
+}
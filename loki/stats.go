@@ -0,0 +1,118 @@
+package loki
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// levelCount 是按级别统计时的级别个数，覆盖 Debug 到 Fatal
+const levelCount = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+
+// Stats 是客户端运行统计的快照
+type Stats struct {
+	// LogsPushed 是进入缓冲区或直接发送的日志总条数
+	LogsPushed uint64
+	// BatchesSent 是发送成功的请求总数
+	BatchesSent uint64
+	// EntriesSent 是发送成功的日志总条数
+	// 配置了多个推送目标时，每个目标收到的日志分别计数
+	EntriesSent uint64
+	// SendFailures 是发送失败的请求总数
+	SendFailures uint64
+	// Retries 是重试发送的总次数
+	Retries uint64
+	// RateLimited 是收到 429 限流响应的总次数
+	RateLimited uint64
+	// RateLimitedBatches 是因限流被放回缓冲区、稍后重新发送的批次数
+	RateLimitedBatches uint64
+	// LastRetryAfter 是最近一次限流响应中 Retry-After 指定的等待时间
+	LastRetryAfter time.Duration
+	// QueuedSends 是因达到 MaxConcurrentSends 而排队等待过的推送次数
+	QueuedSends uint64
+	// RejectedSends 是因排队达到 MaxQueuedSends 而放回缓冲区的批次数
+	RejectedSends uint64
+	// Dropped 是因发送失败、缓冲区溢出等原因被丢弃的日志总条数
+	Dropped uint64
+	// BufferLength 是缓冲区中等待发送的日志条数
+	BufferLength int
+	// LogsByLevel 是按级别统计的日志条数，键为级别名称
+	LogsByLevel map[string]uint64
+	// Uptime 是客户端自启动以来的运行时间
+	Uptime time.Duration
+	// Paused 表示是否处于暂停发送状态
+	Paused bool
+	// Targets 是各推送目标的统计，键为目标名称
+	Targets map[string]TargetStats
+	// SendLatency 是推送请求的耗时分布
+	SendLatency LatencyStats
+}
+
+// counters 保存客户端的运行计数器
+type counters struct {
+	logsPushed         atomic.Uint64
+	batchesSent        atomic.Uint64
+	entriesSent        atomic.Uint64
+	sendFailures       atomic.Uint64
+	dropped            atomic.Uint64
+	retries            atomic.Uint64
+	rateLimited        atomic.Uint64
+	rateLimitedBatches atomic.Uint64
+	queuedSends        atomic.Uint64
+	rejectedSends      atomic.Uint64
+	levels             [levelCount]atomic.Uint64
+}
+
+// addLevel 按级别累加日志条数
+func (c *counters) addLevel(level zapcore.Level, n uint64) {
+	if i := int(level - zapcore.DebugLevel); i >= 0 && i < levelCount {
+		c.levels[i].Add(n)
+	}
+}
+
+// Stats 返回客户端运行统计的快照
+// 该方法是线程安全的，可以被频繁调用
+func (c *Client) Stats() Stats {
+	byLevel := make(map[string]uint64)
+	for i := range c.counters.levels {
+		if n := c.counters.levels[i].Load(); n > 0 {
+			byLevel[(zapcore.DebugLevel + zapcore.Level(i)).String()] = n
+		}
+	}
+
+	var uptime time.Duration
+	if startedAt := c.startedAt.Load(); startedAt != 0 {
+		uptime = time.Since(time.Unix(0, startedAt))
+	}
+
+	targets := make(map[string]TargetStats, len(c.targets))
+	for _, t := range c.targets {
+		targets[t.name] = TargetStats{
+			BatchesSent:  t.batchesSent.Load(),
+			EntriesSent:  t.entriesSent.Load(),
+			SendFailures: t.sendFailures.Load(),
+			Dropped:      t.dropped.Load(),
+		}
+	}
+
+	return Stats{
+		LogsPushed:         c.counters.logsPushed.Load(),
+		BatchesSent:        c.counters.batchesSent.Load(),
+		EntriesSent:        c.counters.entriesSent.Load(),
+		SendFailures:       c.counters.sendFailures.Load(),
+		Retries:            c.counters.retries.Load(),
+		RateLimited:        c.counters.rateLimited.Load(),
+		RateLimitedBatches: c.counters.rateLimitedBatches.Load(),
+		LastRetryAfter:     time.Duration(c.lastRetryAfter.Load()),
+		QueuedSends:        c.counters.queuedSends.Load(),
+		RejectedSends:      c.counters.rejectedSends.Load(),
+		Dropped:            c.DroppedCount(),
+		BufferLength:       c.buffer.Len(),
+		LogsByLevel:        byLevel,
+		Uptime:             uptime,
+		Paused:             c.paused.Load(),
+		Targets:            targets,
+		SendLatency:        c.latency.snapshot(),
+	}
+}
@@ -0,0 +1,185 @@
+package loki
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultTargetName 是 ClientConfig.URL 对应的默认推送目标的名称
+const DefaultTargetName = "default"
+
+// DefaultPushPath 是Loki推送接口的默认路径
+const DefaultPushPath = "/loki/api/v1/push"
+
+// Target 定义一个额外的推送目标
+// 用于将同一份日志同时发送到多个Loki实例，例如本地短期保留的实例和中心长期保留的实例。
+// 这与故障转移不同，每个目标都会收到完整的日志。
+type Target struct {
+	// Name 是目标名称，用于统计和丢弃记录，不能为空且不能重复
+	Name string
+	// URL 是Loki服务器的地址
+	URL string
+	// PushPath 是推送接口的路径
+	// 如果为空，将使用 ClientConfig.PushPath
+	PushPath string
+	// Labels 是该目标额外的标签，会覆盖 ClientConfig.Labels 中的同名标签
+	Labels map[string]string
+	// HTTPClient 是用于发送请求的 HTTP 客户端
+	// 如果为 nil，将使用 ClientConfig.HTTPClient
+	HTTPClient *http.Client
+	// TenantID 是该目标的租户 ID
+	// 如果为空，将使用 ClientConfig.TenantID
+	TenantID string
+	// Auth 是该目标的认证方式
+	// 如果为 nil，将使用 ClientConfig.Auth
+	Auth *Auth
+}
+
+// TargetStats 是单个推送目标的运行统计
+type TargetStats struct {
+	// BatchesSent 是发送成功的请求总数
+	BatchesSent uint64
+	// EntriesSent 是发送成功的日志总条数
+	EntriesSent uint64
+	// SendFailures 是发送失败的请求总数
+	SendFailures uint64
+	// Dropped 是该目标没有收到的日志条数
+	// 其他目标收到的日志不计入 Stats.Dropped
+	Dropped uint64
+}
+
+// target 是推送目标的运行时状态
+type target struct {
+	// name 是目标名称
+	name string
+	// baseURL 是服务器地址，用于拼接推送以外的接口
+	baseURL string
+	// pushURL 是推送接口的完整地址
+	pushURL string
+	// labels 是合并后的完整默认标签
+	labels map[string]string
+	// httpClient 是用于发送请求的 HTTP 客户端
+	httpClient *http.Client
+	// tenantID 是 X-Scope-OrgID 请求头的值，为空时不发送
+	tenantID string
+	// auth 是认证方式
+	auth Auth
+	// batchesSent 是发送成功的请求总数
+	batchesSent atomic.Uint64
+	// entriesSent 是发送成功的日志总条数
+	entriesSent atomic.Uint64
+	// sendFailures 是发送失败的请求总数
+	sendFailures atomic.Uint64
+	// dropped 是该目标没有收到的日志条数
+	dropped atomic.Uint64
+}
+
+// newTargets 根据配置创建所有推送目标，第一个是 URL 指定的默认目标
+func newTargets(config ClientConfig) ([]*target, error) {
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+		if config.Transport == TransportGRPC {
+			var err error
+			if httpClient, err = newGRPCHTTPClient(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// gRPC 推送的路径是固定的方法名
+	if config.Transport == TransportGRPC {
+		config.PushPath = GRPCPushPath
+	}
+
+	defaultURL, err := pushURL(config.URL, config.PushPath)
+	if err != nil {
+		return nil, err
+	}
+	targets := []*target{{
+		name:       DefaultTargetName,
+		baseURL:    config.URL,
+		pushURL:    defaultURL,
+		labels:     config.Labels,
+		httpClient: httpClient,
+		tenantID:   config.TenantID,
+		auth:       config.Auth,
+	}}
+
+	names := map[string]bool{DefaultTargetName: true}
+	for _, t := range config.Targets {
+		if t.Name == "" || t.URL == "" {
+			return nil, fmt.Errorf("target name and URL are required")
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("duplicate target name %q", t.Name)
+		}
+		names[t.Name] = true
+		if err := validateLabelNames(t.Labels); err != nil {
+			return nil, fmt.Errorf("target %s: %w", t.Name, err)
+		}
+
+		labels := make(map[string]string, len(config.Labels)+len(t.Labels))
+		for k, v := range config.Labels {
+			labels[k] = v
+		}
+		for k, v := range t.Labels {
+			labels[k] = v
+		}
+
+		client := t.HTTPClient
+		if client == nil {
+			client = httpClient
+		}
+		tenantID := t.TenantID
+		if tenantID == "" {
+			tenantID = config.TenantID
+		}
+		auth := config.Auth
+		if t.Auth != nil {
+			auth = *t.Auth
+		}
+		path := t.PushPath
+		if path == "" || config.Transport == TransportGRPC {
+			path = config.PushPath
+		}
+		u, err := pushURL(t.URL, path)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: %w", t.Name, err)
+		}
+
+		targets = append(targets, &target{
+			name:       t.Name,
+			baseURL:    t.URL,
+			pushURL:    u,
+			labels:     labels,
+			httpClient: client,
+			tenantID:   tenantID,
+			auth:       auth,
+		})
+	}
+	return targets, nil
+}
+
+// pushURL 将服务器地址和推送路径拼接为推送接口的完整地址
+// path 为空时使用 DefaultPushPath，拼接后的地址必须包含协议和主机
+func pushURL(base, path string) (string, error) {
+	if path == "" {
+		path = DefaultPushPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	raw := strings.TrimSuffix(base, "/") + path
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid push URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid push URL %q: scheme and host are required", raw)
+	}
+	return raw, nil
+}
@@ -0,0 +1,209 @@
+package loki
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTargetsFanOut(t *testing.T) {
+	healthy := newFakeLoki(t, nil)
+	failing := newFakeLoki(t, respondStatus(http.StatusInternalServerError))
+
+	var mu sync.Mutex
+	var callbacks int
+	c := newStartedClient(t, ClientConfig{
+		URL:    healthy.URL,
+		Labels: map[string]string{"app": "svc"},
+		Targets: []Target{{
+			Name:   "central",
+			URL:    failing.URL,
+			Labels: map[string]string{"retention": "long"},
+		}},
+		OnSendError: func(entries []pkg.LogEntry, err error) {
+			mu.Lock()
+			callbacks++
+			mu.Unlock()
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		_ = c.Push(pkg.LogEntry{Message: "hello", Level: zapcore.InfoLevel})
+	}
+	if err := c.Flush(); err == nil {
+		t.Error("Flush() error = nil, want the failing target's error")
+	}
+
+	lines := healthy.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("healthy target got %d lines, want 3", len(lines))
+	}
+	if _, ok := lines[0].Labels["retention"]; ok {
+		t.Error("healthy target received the other target's labels")
+	}
+	failed := failing.Lines()
+	if len(failed) != 3 || failed[0].Labels["retention"] != "long" || failed[0].Labels["app"] != "svc" {
+		t.Errorf("failing target got %+v, want 3 lines with merged labels", failed)
+	}
+
+	stats := c.Stats()
+	if got := stats.Targets[DefaultTargetName]; got.BatchesSent != 1 || got.EntriesSent != 3 || got.SendFailures != 0 || got.Dropped != 0 {
+		t.Errorf("default target stats = %+v", got)
+	}
+	if got := stats.Targets["central"]; got.BatchesSent != 0 || got.EntriesSent != 0 || got.SendFailures != 1 || got.Dropped != 3 {
+		t.Errorf("central target stats = %+v", got)
+	}
+	if stats.Dropped != 0 || c.DroppedCount() != 0 {
+		t.Errorf("Dropped = %d, want 0 since the default target received the batch", stats.Dropped)
+	}
+	if callbacks != 0 {
+		t.Errorf("OnSendError called %d times, want 0", callbacks)
+	}
+}
+
+func TestTargetsAllFailing(t *testing.T) {
+	first := newFakeLoki(t, respondStatus(http.StatusInternalServerError))
+	second := newFakeLoki(t, respondStatus(http.StatusInternalServerError))
+
+	var mu sync.Mutex
+	var dropped []int
+	c := newStartedClient(t, ClientConfig{
+		URL:     first.URL,
+		Targets: []Target{{Name: "second", URL: second.URL}},
+		OnSendError: func(entries []pkg.LogEntry, err error) {
+			mu.Lock()
+			dropped = append(dropped, len(entries))
+			mu.Unlock()
+		},
+	})
+
+	_ = c.Info("a")
+	_ = c.Info("b")
+	_ = c.Flush()
+
+	if len(dropped) != 1 || dropped[0] != 2 {
+		t.Errorf("OnSendError calls = %v, want one call with 2 entries", dropped)
+	}
+	if got := c.Stats().Dropped; got != 2 {
+		t.Errorf("Dropped = %d, want 2", got)
+	}
+}
+
+func TestNewTargetsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []Target
+	}{
+		{"missing name", []Target{{URL: "http://b"}}},
+		{"missing url", []Target{{Name: "b"}}},
+		{"duplicate", []Target{{Name: "b", URL: "http://b"}, {Name: "b", URL: "http://c"}}},
+		{"reserved name", []Target{{Name: DefaultTargetName, URL: "http://b"}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewClient(ClientConfig{URL: "http://a", Targets: tt.targets}); err == nil {
+			t.Errorf("%s: NewClient() error = nil, want error", tt.name)
+		}
+	}
+}
+
+func TestTenantHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		tenantID    string
+		targetID    string
+		wantDefault string
+		wantTarget  string
+	}{
+		{"single tenant", "", "", "", ""},
+		{"tenant", "team-a", "", "team-a", "team-a"},
+		{"target override", "team-a", "team-b", "team-a", "team-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newFakeLoki(t, nil)
+			secondary := newFakeLoki(t, nil)
+			c := newStartedClient(t, ClientConfig{
+				URL:      primary.URL,
+				TenantID: tt.tenantID,
+				Targets:  []Target{{Name: "secondary", URL: secondary.URL, TenantID: tt.targetID}},
+			})
+			_ = c.Info("hello")
+			if err := c.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			for server, want := range map[*fakeLoki]string{primary: tt.wantDefault, secondary: tt.wantTarget} {
+				pushes := server.Pushes()
+				if len(pushes) != 1 {
+					t.Fatalf("got %d requests, want 1", len(pushes))
+				}
+				values, present := pushes[0].Header["X-Scope-Orgid"]
+				if want == "" && present {
+					t.Errorf("X-Scope-OrgID = %q, want header omitted", values)
+				}
+				if got := pushes[0].Header.Get("X-Scope-OrgID"); got != want {
+					t.Errorf("X-Scope-OrgID = %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPushPath(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{
+		URL:      server.URL + "/",
+		PushPath: "/proxy/loki/push",
+		Targets: []Target{
+			{Name: "inherit", URL: server.URL},
+			{Name: "override", URL: server.URL, PushPath: "api/v1/push"},
+		},
+	})
+
+	_ = c.Info("hello")
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var paths []string
+	for _, p := range server.Pushes() {
+		paths = append(paths, p.Path)
+	}
+	sort.Strings(paths)
+	want := []string{"/api/v1/push", "/proxy/loki/push", "/proxy/loki/push"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestDefaultPushPath(t *testing.T) {
+	server := newFakeLoki(t, nil)
+	c := newStartedClient(t, ClientConfig{URL: server.URL})
+
+	_ = c.Info("hello")
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if pushes := server.Pushes(); len(pushes) != 1 || pushes[0].Path != DefaultPushPath {
+		t.Errorf("pushes = %+v, want one request to %s", pushes, DefaultPushPath)
+	}
+}
+
+func TestInvalidPushURL(t *testing.T) {
+	tests := []ClientConfig{
+		{URL: "loki:3100"},
+		{URL: "http://loki", PushPath: "/push%zz"},
+		{URL: "http://loki", Targets: []Target{{Name: "central", URL: "central"}}},
+	}
+	for _, config := range tests {
+		if _, err := NewClient(config); err == nil {
+			t.Errorf("NewClient(%+v) error = nil, want invalid push URL", config)
+		}
+	}
+}
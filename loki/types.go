@@ -1,8 +1,12 @@
 package loki
 
 import (
+	"encoding/json"
 	"go.uber.org/zap/zapcore"
 	"net/http"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
 )
 
 // Stream 表示一个日志流
@@ -10,9 +14,27 @@ import (
 type Stream struct {
 	// Stream 存储标签键值对，如 {"app": "myapp", "env": "prod"}
 	Stream map[string]string `json:"stream"`
-	// Values 存储日志记录，每条记录是一个长度为2的数组
-	// [0]是时间戳字符串，[1]是日志消息
-	Values [][2]string `json:"values"`
+	// Values 存储日志记录，每条记录序列化为一个数组
+	// [0]是时间戳字符串，[1]是日志消息，[2]是可选的结构化元数据
+	Values []Value `json:"values"`
+}
+
+// Value 表示流中的一条日志记录
+type Value struct {
+	// Timestamp 是Unix纳秒时间戳字符串
+	Timestamp string
+	// Line 是日志消息
+	Line string
+	// Metadata 是结构化元数据（Loki 2.9+），为空时不发送
+	Metadata map[string]string
+}
+
+// MarshalJSON 将日志记录序列化为Loki要求的数组格式
+func (v Value) MarshalJSON() ([]byte, error) {
+	if len(v.Metadata) == 0 {
+		return json.Marshal([2]string{v.Timestamp, v.Line})
+	}
+	return json.Marshal([3]interface{}{v.Timestamp, v.Line, v.Metadata})
 }
 
 // PushRequest 表示向Loki发送的推送请求
@@ -22,21 +44,150 @@ type PushRequest struct {
 	Streams []Stream `json:"streams"`
 }
 
+// ErrorLogger 是记录客户端自身诊断信息的日志接口
+// *log.Logger 实现了该接口
+type ErrorLogger interface {
+	Printf(format string, args ...interface{})
+}
+
 // ClientConfig 定义Loki客户端的配置参数
 type ClientConfig struct {
 	// URL 是Loki服务器的地址
 	URL string
+	// PushPath 是推送接口的路径，拼接在 URL 之后，默认为 /loki/api/v1/push
+	// 用于经过改写路径的代理或使用不同前缀的托管服务，拼接后的地址必须是合法的 URL
+	PushPath string
 	// Labels 定义默认的标签集
+	// 标签名必须匹配 [a-zA-Z_][a-zA-Z0-9_]*，否则 NewClient 返回错误；
+	// 日志附加标签中的非法标签名会被跳过并记录警告
 	Labels map[string]string
+	// LevelLabel 定义级别标签名，为 nil 时使用 "level"
+	// 指向空字符串时不添加级别标签，所有级别的日志写入同一个流，可以避免与其他约定的标签冲突
+	LevelLabel *string
+	// LabelsFunc 设置后完全决定每条日志的流标签
+	// 返回的标签集直接作为日志所在流的标签，Labels、推送目标的 Labels、日志的附加标签和级别标签都不再添加，
+	// 返回 nil 或空标签集时按默认方式生成标签。返回的非法标签名会被跳过并记录警告。
+	// 每个不同的标签集都是一个独立的流，取值不受限制的标签（用户 ID、请求 ID 等）会使流的个数失控，
+	// 导致Loki的索引膨胀甚至拒绝推送，应只使用取值个数有限的标签。
+	// 该函数在发送时可能被多次调用，必须是并发安全、结果稳定且足够快的纯函数
+	LabelsFunc func(entry pkg.LogEntry) map[string]string
 	// BatchSize 定义批量发送的日志数量
 	BatchSize int
 	// MinWaitTime 定义两次发送之间的最小等待时间（秒）
+	// 缓冲区写满时，如果距离上次发送不足该时间，
+	// 发送会被推迟到该时间到期，期间的多次触发合并为一次发送。
+	// Stop、Resume 和日志超过 MaxEntryAge 时的刷新不受限制
 	MinWaitTime int64
-	// MaxWaitTime 定义强制发送的最大等待时间（秒）
+	// MaxWaitTime 定义日志在缓冲区中的最大等待时间（秒），默认为 10 秒
+	// 工作协程以该时间的四分之一为间隔检查最早一条日志，保证任何日志的等待时间都不超过该值。
+	// 与 MaxEntryAge 一样不受 MinWaitTime 限制
 	MaxWaitTime int64
+	// ShutdownTimeout 定义 Stop 等待最后一次刷新完成的最长时间，默认为 10 秒
+	ShutdownTimeout time.Duration
+	// MaxEntryAge 定义日志在缓冲区中的最长停留时间
+	// 作用与 MaxWaitTime 相同，但可以设置为小于 1 秒的时间，两者都设置时取较小的一个
+	MaxEntryAge time.Duration
 	// MinLevel 定义最低日志级别，低于此级别的日志将被忽略
+	// 运行时可以通过 Client.SetMinLevel 修改
 	MinLevel zapcore.Level
 	// HTTPClient 是用于发送请求的 HTTP 客户端
 	// 如果为 nil，将使用 http.DefaultClient
 	HTTPClient *http.Client
+	// TenantID 是多租户Loki的租户 ID，作为 X-Scope-OrgID 请求头发送
+	// 为空时不发送该请求头，适用于单租户部署
+	TenantID string
+	// LevelTenants 按日志级别指定租户，例如将错误日志发送到合规租户
+	// 发送时同一批日志按租户拆分为多个请求，分别带上对应的 X-Scope-OrgID；
+	// 未在其中指定的级别使用各推送目标自己的租户 ID。对所有推送目标生效
+	LevelTenants map[zapcore.Level]string
+	// Auth 定义访问Loki时的认证方式，支持 Bearer 令牌和 HTTP 基本认证
+	Auth Auth
+	// Targets 定义除 URL 之外的额外推送目标
+	// 每批日志会同时发送到 URL 和所有额外目标，各目标独立统计成功和失败
+	Targets []Target
+	// Compression 定义推送请求体的压缩方式
+	// 为空或 CompressionNone 时不压缩，CompressionGzip 时使用 gzip 压缩
+	// Protocol 为 ProtocolProtobuf 时不生效；Transport 为 TransportGRPC 时压缩 gRPC 消息
+	Compression Compression
+	// Protocol 定义推送请求的编码格式
+	// 为空或 ProtocolJSON 时使用 JSON，ProtocolProtobuf 时使用 snappy 压缩的 protobuf
+	Protocol Protocol
+	// Transport 定义推送请求的传输方式
+	// 为空或 TransportHTTP 时使用 HTTP 推送接口；TransportGRPC 时通过 gRPC 调用 logproto.Pusher/Push，
+	// URL 和各推送目标的 URL 是Loki的 gRPC 地址，例如 http://loki:9095，https 时使用 TLS。
+	// 使用 gRPC 时 PushPath、Protocol 和 ValidateResponse 不生效，Ping 和 QueryRange 不可用；
+	// HTTPClient 必须支持 HTTP/2，为 nil 时使用支持明文 HTTP/2 的客户端
+	Transport Transport
+	// ValidateResponse 用于判断推送请求是否成功
+	// 返回 nil 表示成功，返回错误表示失败
+	// 如果为 nil，只有状态码为 204 时视为成功
+	ValidateResponse func(resp *http.Response) error
+	// MaxRetries 定义发送失败后的最大重试次数，默认为 0，即不重试
+	MaxRetries int
+	// RetryBackoff 定义第一次重试前的等待时间，之后每次翻倍，默认为 500 毫秒
+	RetryBackoff time.Duration
+	// IsRetryable 判断发送失败的错误是否值得重试
+	// 如果为 nil，将使用 IsRetryable
+	IsRetryable func(err error) bool
+	// IdempotencyHeader 定义幂等键请求头的名称，例如 "X-Idempotency-Key"
+	// 设置后每个批次会携带由请求体计算出的幂等键，重试时保持不变，
+	// 便于网关或服务端识别超时后重试导致的重复推送。为空时不发送
+	IdempotencyHeader string
+	// AddEntryID 定义是否为每条日志附加 entry_id 结构化元数据
+	// entry_id 由时间戳、级别和消息计算得到，重试时保持不变，可用于下游去重
+	AddEntryID bool
+	// TimestampFormat 定义日志时间戳的格式，为空时使用Unix纳秒
+	// Loki原生推送接口只接受Unix纳秒，其他格式仅用于兼容的下游接收端
+	TimestampFormat TimestampFormat
+	// FlushWorkers 定义每次发送时并发发送的分片数
+	// 大于 1 时按流将日志分片并发发送，同一个流总是落在同一个分片中，保证流内顺序
+	// 默认为 1，即所有流在一个请求中发送
+	FlushWorkers int
+	// MaxConcurrentSends 定义同时进行的推送（一个批次发送到所有目标）的最大数量
+	// 网络较慢或批次较大时，避免并发的发送占用过多协程和连接。
+	// 超出的推送排队等待，经过排队的次数计入 Stats.QueuedSends。默认为 0，表示不限制
+	MaxConcurrentSends int
+	// MaxQueuedSends 定义等待推送名额的最大排队数量，只在设置了 MaxConcurrentSends 时生效
+	// 排队已满时本批日志不发送，放回缓冲区稍后重新发送，缓冲区已满时按 BufferDropPolicy 丢弃，
+	// 放回的次数计入 Stats.RejectedSends。关闭前的最后一次发送不受该限制。默认为 0，表示不限制
+	MaxQueuedSends int
+	// OnSendError 在日志最终发送失败（包括重试之后）时被调用
+	// entries 是该次发送失败而被丢弃的日志，可以重新入队、写入本地文件或增加监控计数。
+	// 配置了多个推送目标时，只有所有目标都没有收到的日志才会传给回调，每批最多调用一次。
+	// 回调在发送的 goroutine 中同步执行，不应长时间阻塞。
+	// 如果为 nil，将使用 ErrorLogger 记录错误
+	OnSendError func(entries []pkg.LogEntry, err error)
+	// ErrorLogger 用于记录客户端自身的诊断信息，包括发送失败、被限流、审计文件写入失败等
+	// 可以将Loki的投递错误接入自己的监控，而不是只输出到标准错误。
+	// 不要传入写入同一个客户端的日志器，否则发送失败时会产生递归。
+	// 如果为 nil，将使用标准库的log包
+	ErrorLogger ErrorLogger
+	// LogShutdownSummary 定义是否在 Stop 时发送一条关闭摘要日志
+	// 摘要包含各级别日志条数、发送批次、失败次数、丢弃条数和运行时间，
+	// 会随最后一批日志一起发送，且不受 MinLevel 限制
+	LogShutdownSummary bool
+	// LabelPolicy 定义标签规范，在 NewClient 中对 Labels 进行检查
+	// 如果为 nil，将使用 PermissiveLabelPolicy，接受任何标签
+	LabelPolicy LabelPolicy
+	// DropEventsSize 定义内存中保留的丢弃事件条数，默认为 100
+	DropEventsSize int
+	// DropAuditFile 是丢弃事件的审计文件路径
+	// 设置后每次丢弃都会以 JSON 行的形式追加写入，为空时只保留在内存中
+	DropAuditFile string
+	// MaxBufferEntries 定义缓冲区最多保存的日志条数
+	// Loki 长时间不可用或暂停发送时缓冲区会持续积压，超过上限后按 BufferDropPolicy 丢弃日志，
+	// 丢弃的条数计入 Stats.Dropped 并记录到丢弃事件中。默认为 0，表示不限制
+	MaxBufferEntries int
+	// BufferDropPolicy 定义缓冲区超过 MaxBufferEntries 时的丢弃策略，默认为 pkg.DropOldest
+	BufferDropPolicy pkg.DropPolicy
+	// MaxBatchBytes 定义每个请求中日志的最大估计字节数（参见 pkg.LogEntry.Size）
+	// 缓冲区中日志的字节数达到该值时立即触发发送，发送时按该值拆分请求，
+	// 避免消息较大时请求体超过Loki的大小限制被拒绝。单条日志超过该值时单独发送。
+	// 默认为 0，表示只按 BatchSize 分批
+	MaxBatchBytes int
+	// MaxStreamsPerFlush 定义每次刷新中带附加标签的流的最大个数
+	// 日志的附加标签来自字段等动态取值时，流的个数可能失控。超过上限后，
+	// 新出现的标签集的日志会去掉附加标签，并入只按级别划分的流中，同时记录一条警告。
+	// 默认为 0，表示不限制
+	MaxStreamsPerFlush int
 }
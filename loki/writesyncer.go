@@ -0,0 +1,71 @@
+package loki
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap/zapcore"
+)
+
+// writeSyncer 将 zap 编码器输出的字节作为日志内容写入客户端缓冲区
+type writeSyncer struct {
+	client *Client
+}
+
+// WriteSyncer 返回将 zap 编码后的日志直接写入客户端缓冲区的 zapcore.WriteSyncer
+// 配合 zapcore.NewCore 使用，可以用任意编码器和级别构建写入Loki的 core，
+// 保证控制台、文件和Loki收到的内容逐字节一致，例如
+//
+//	core := zapcore.NewCore(zapcore.NewJSONEncoder(encCfg), loki.WriteSyncer(client), zapcore.InfoLevel)
+//
+// zap 的 core 每条日志调用一次 Write，写入的内容去掉结尾的换行后作为一条日志推送。
+// 写入的字节中没有单独的级别，级别从内容中识别：JSON 取顶层的 level 字段，
+// 其他格式取制表符分隔的前几列中第一个能解析为级别的值（带颜色的级别无法识别），
+// 无法识别时视为 Info。Sync 同步发送缓冲区中的日志
+func WriteSyncer(client *Client) zapcore.WriteSyncer {
+	return &writeSyncer{client: client}
+}
+
+// Write 实现 io.Writer，每次写入作为一条日志
+func (w *writeSyncer) Write(p []byte) (int, error) {
+	line := strings.TrimSuffix(string(p), "\n")
+	if err := w.client.Push(pkg.LogEntry{Message: line, Level: detectLevel(line)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync 实现 zapcore.WriteSyncer，同步发送缓冲区中的日志
+func (w *writeSyncer) Sync() error {
+	return w.client.Flush()
+}
+
+// detectLevelColumns 是非 JSON 格式中查找级别的最大列数
+// zap 控制台格式的级别在时间之后，即第二列
+const detectLevelColumns = 3
+
+// detectLevel 从编码后的日志中识别级别，无法识别时返回 Info
+func detectLevel(line string) zapcore.Level {
+	if strings.HasPrefix(line, "{") {
+		var fields struct {
+			Level string `json:"level"`
+		}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			if level, err := zapcore.ParseLevel(fields.Level); err == nil {
+				return level
+			}
+		}
+		return zapcore.InfoLevel
+	}
+
+	for i, column := range strings.SplitN(line, "\t", detectLevelColumns+1) {
+		if i == detectLevelColumns {
+			break
+		}
+		if level, err := zapcore.ParseLevel(strings.TrimSpace(column)); err == nil {
+			return level
+		}
+	}
+	return zapcore.InfoLevel
+}
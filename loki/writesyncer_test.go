@@ -0,0 +1,70 @@
+package loki
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWriteSyncer(t *testing.T) {
+	encoders := map[string]func(zapcore.EncoderConfig) zapcore.Encoder{
+		"json":    zapcore.NewJSONEncoder,
+		"console": zapcore.NewConsoleEncoder,
+	}
+	for name, newEncoder := range encoders {
+		t.Run(name, func(t *testing.T) {
+			server := newFakeLoki(t, nil)
+			c := newStartedClient(t, ClientConfig{URL: server.URL})
+
+			encCfg := zap.NewProductionEncoderConfig()
+			var local bytes.Buffer
+			logger := zap.New(zapcore.NewTee(
+				zapcore.NewCore(newEncoder(encCfg), zapcore.AddSync(&local), zapcore.DebugLevel),
+				zapcore.NewCore(newEncoder(encCfg), WriteSyncer(c), zapcore.DebugLevel),
+			))
+			logger.Info("hello", zap.String("user", "alice"))
+			logger.Warn("careful", zap.Int("attempt", 2))
+			if err := logger.Sync(); err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			want := strings.Split(strings.TrimSuffix(local.String(), "\n"), "\n")
+			lines := server.Lines()
+			if len(lines) != len(want) {
+				t.Fatalf("got %d lines, want %d", len(lines), len(want))
+			}
+			levels := []string{"info", "warn"}
+			for i, line := range lines {
+				if line.Line != want[i] {
+					t.Errorf("line %d = %q, want byte-identical %q", i, line.Line, want[i])
+				}
+				if got := line.Labels["level"]; got != levels[i] {
+					t.Errorf("line %d level = %q, want %q", i, got, levels[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDetectLevel(t *testing.T) {
+	tests := []struct {
+		line string
+		want zapcore.Level
+	}{
+		{`{"level":"error","msg":"boom"}`, zapcore.ErrorLevel},
+		{`{"msg":"no level"}`, zapcore.InfoLevel},
+		{`{"msg":"nested","obj":{"level":"error"}}`, zapcore.InfoLevel},
+		{"2024-01-01T00:00:00.000Z\tWARN\tmain.go:10\tslow", zapcore.WarnLevel},
+		{"2024-01-01T00:00:00.000Z\tdebug\tdetails", zapcore.DebugLevel},
+		{"plain text", zapcore.InfoLevel},
+		{"a\tb\tc\terror", zapcore.InfoLevel},
+	}
+	for _, tt := range tests {
+		if got := detectLevel(tt.line); got != tt.want {
+			t.Errorf("detectLevel(%q) = %s, want %s", tt.line, got, tt.want)
+		}
+	}
+}
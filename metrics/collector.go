@@ -0,0 +1,89 @@
+// Package metrics 提供 btlog 的 Prometheus 采集器
+// 该包是独立的模块 github.com/bt-smart/btlog/metrics，Prometheus 依赖只在这个模块的 go.mod 中，
+// 只依赖 github.com/bt-smart/btlog 的项目不会引入 Prometheus 客户端库
+package metrics
+
+import (
+	"github.com/bt-smart/btlog/loki"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector 是采集 Loki 客户端运行统计的 prometheus.Collector
+// 每次抓取时读取 Client.Stats 的快照，不会在客户端中额外维护指标，例如
+//
+//	prometheus.MustRegister(metrics.NewCollector(client))
+type Collector struct {
+	client *loki.Client
+
+	bufferLength   *prometheus.Desc
+	bufferCapacity *prometheus.Desc
+	logsPushed     *prometheus.Desc
+	batchesSent    *prometheus.Desc
+	entriesSent    *prometheus.Desc
+	sendFailures   *prometheus.Desc
+	retries        *prometheus.Desc
+	rateLimited    *prometheus.Desc
+	queuedSends    *prometheus.Desc
+	dropped        *prometheus.Desc
+	sendDuration   *prometheus.Desc
+}
+
+// NewCollector 创建采集指定客户端的采集器
+// constLabels 会附加到所有指标上，用于区分同一进程中的多个客户端，可以为 nil
+func NewCollector(client *loki.Client, constLabels prometheus.Labels) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("btlog_"+name, help, nil, constLabels)
+	}
+	return &Collector{
+		client:         client,
+		bufferLength:   desc("buffer_length", "Number of log entries waiting in the buffer."),
+		bufferCapacity: desc("buffer_capacity", "Capacity of the log buffer."),
+		logsPushed:     desc("logs_pushed_total", "Total number of log entries pushed to the Loki client."),
+		batchesSent:    desc("batches_sent_total", "Total number of batches successfully sent to Loki."),
+		entriesSent:    desc("entries_sent_total", "Total number of log entries successfully sent to Loki."),
+		sendFailures:   desc("send_failures_total", "Total number of failed Loki push requests."),
+		retries:        desc("retries_total", "Total number of retried Loki push requests."),
+		rateLimited:    desc("rate_limited_batches_total", "Total number of batches rejected with 429 and requeued for a later attempt."),
+		queuedSends:    desc("queued_sends_total", "Total number of pushes that waited for a free send slot."),
+		dropped:        desc("dropped_total", "Total number of log entries dropped."),
+		sendDuration:   desc("send_duration_seconds", "Duration of individual Loki push requests, each retry observed separately."),
+	}
+}
+
+// Describe 实现 prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.bufferLength
+	ch <- c.bufferCapacity
+	ch <- c.logsPushed
+	ch <- c.batchesSent
+	ch <- c.entriesSent
+	ch <- c.sendFailures
+	ch <- c.retries
+	ch <- c.rateLimited
+	ch <- c.queuedSends
+	ch <- c.dropped
+	ch <- c.sendDuration
+}
+
+// Collect 实现 prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.bufferLength, prometheus.GaugeValue, float64(stats.BufferLength))
+	ch <- prometheus.MustNewConstMetric(c.bufferCapacity, prometheus.GaugeValue, float64(c.client.BufferCap()))
+	ch <- prometheus.MustNewConstMetric(c.logsPushed, prometheus.CounterValue, float64(stats.LogsPushed))
+	ch <- prometheus.MustNewConstMetric(c.batchesSent, prometheus.CounterValue, float64(stats.BatchesSent))
+	ch <- prometheus.MustNewConstMetric(c.entriesSent, prometheus.CounterValue, float64(stats.EntriesSent))
+	ch <- prometheus.MustNewConstMetric(c.sendFailures, prometheus.CounterValue, float64(stats.SendFailures))
+	ch <- prometheus.MustNewConstMetric(c.retries, prometheus.CounterValue, float64(stats.Retries))
+	ch <- prometheus.MustNewConstMetric(c.rateLimited, prometheus.CounterValue, float64(stats.RateLimitedBatches))
+	ch <- prometheus.MustNewConstMetric(c.queuedSends, prometheus.CounterValue, float64(stats.QueuedSends))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(stats.Dropped))
+
+	latency := stats.SendLatency
+	buckets := make(map[float64]uint64, len(latency.Buckets))
+	for i, upper := range latency.Buckets {
+		buckets[upper.Seconds()] = latency.Counts[i]
+	}
+	ch <- prometheus.MustNewConstHistogram(c.sendDuration, latency.Count, latency.Sum.Seconds(), buckets)
+}
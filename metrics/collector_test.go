@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bt-smart/btlog/loki"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := loki.NewClient(loki.ClientConfig{URL: server.URL, BatchSize: 10})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.Start()
+	defer client.Stop(context.Background())
+
+	_ = client.Info("one")
+	_ = client.Info("two")
+	if err := client.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	_ = client.Info("buffered")
+
+	collector := NewCollector(client, prometheus.Labels{"app": "svc"})
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP btlog_buffer_capacity Capacity of the log buffer.
+# TYPE btlog_buffer_capacity gauge
+btlog_buffer_capacity{app="svc"} 10
+# HELP btlog_buffer_length Number of log entries waiting in the buffer.
+# TYPE btlog_buffer_length gauge
+btlog_buffer_length{app="svc"} 1
+# HELP btlog_entries_sent_total Total number of log entries successfully sent to Loki.
+# TYPE btlog_entries_sent_total counter
+btlog_entries_sent_total{app="svc"} 2
+# HELP btlog_dropped_total Total number of log entries dropped.
+# TYPE btlog_dropped_total counter
+btlog_dropped_total{app="svc"} 0
+# HELP btlog_rate_limited_batches_total Total number of batches rejected with 429 and requeued for a later attempt.
+# TYPE btlog_rate_limited_batches_total counter
+btlog_rate_limited_batches_total{app="svc"} 0
+# HELP btlog_queued_sends_total Total number of pushes that waited for a free send slot.
+# TYPE btlog_queued_sends_total counter
+btlog_queued_sends_total{app="svc"} 0
+# HELP btlog_send_failures_total Total number of failed Loki push requests.
+# TYPE btlog_send_failures_total counter
+btlog_send_failures_total{app="svc"} 0
+`), "btlog_buffer_capacity", "btlog_buffer_length", "btlog_entries_sent_total", "btlog_dropped_total", "btlog_rate_limited_batches_total", "btlog_queued_sends_total", "btlog_send_failures_total"); err != nil {
+		t.Error(err)
+	}
+
+	if n := testutil.CollectAndCount(collector, "btlog_send_duration_seconds"); n != 1 {
+		t.Errorf("got %d send duration histograms, want 1", n)
+	}
+	problems, err := testutil.CollectAndLint(collector)
+	if err != nil {
+		t.Fatalf("CollectAndLint() error = %v", err)
+	}
+	for _, p := range problems {
+		t.Errorf("lint %s: %s", p.Metric, p.Text)
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+}
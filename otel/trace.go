@@ -0,0 +1,42 @@
+// Package otel 提供 btlog 与 OpenTelemetry 的集成
+// OpenTelemetry 依赖只存在于这个包中，不使用该包的项目不会引入 OpenTelemetry 库
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// TraceIDKey 是 trace ID 的字段名
+	TraceIDKey = "trace_id"
+	// SpanIDKey 是 span ID 的字段名
+	SpanIDKey = "span_id"
+)
+
+// TraceFields 从 context 中提取当前 span 的 trace ID 和 span ID，没有有效的 span 时返回 nil
+// 可以直接作为 ContextExtractor 使用，通过 ...Ctx 方法记录的日志会自动带上这两个字段，例如
+//
+//	logger, err := btzap.NewLogger(&btzap.Config{
+//		ContextExtractors: []btzap.ContextExtractor{otel.TraceFields},
+//		LokiConfig: btzap.LokiConfig{
+//			// trace_id 作为结构化元数据发送，并按哈希值分桶作为 trace_bucket 标签
+//			TraceIDField:           otel.TraceIDKey,
+//			TraceBuckets:           16,
+//			StructuredMetadataKeys: []string{otel.SpanIDKey},
+//		},
+//	})
+//
+// trace ID 和 span ID 的取值个数没有上限，不应直接作为 Loki 的流标签
+func TraceFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String(TraceIDKey, sc.TraceID().String()),
+		zap.String(SpanIDKey, sc.SpanID().String()),
+	}
+}
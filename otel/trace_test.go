@@ -0,0 +1,32 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTraceFields(t *testing.T) {
+	if fields := TraceFields(context.Background()); fields != nil {
+		t.Errorf("TraceFields() without span = %v, want nil", fields)
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range TraceFields(ctx) {
+		f.AddTo(enc)
+	}
+	if got := enc.Fields[TraceIDKey]; got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("%s = %v", TraceIDKey, got)
+	}
+	if got := enc.Fields[SpanIDKey]; got != "00f067aa0ba902b7" {
+		t.Errorf("%s = %v", SpanIDKey, got)
+	}
+}
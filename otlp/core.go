@@ -0,0 +1,60 @@
+package otlp
+
+import (
+	"context"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Core 是将日志写入 Exporter 的 zap core
+// 可以与控制台、文件等 core 组合在 zapcore.NewTee 中使用
+type Core struct {
+	zapcore.LevelEnabler
+	exporter *Exporter
+	// fields 是 With 附加的字段
+	fields []zapcore.Field
+}
+
+// NewCore 创建写入指定导出器的 core，enab 决定输出的最低级别
+func NewCore(exporter *Exporter, enab zapcore.LevelEnabler) *Core {
+	return &Core{LevelEnabler: enab, exporter: exporter}
+}
+
+// With 返回附加了字段的 core，与原 core 共享同一个导出器
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return &clone
+}
+
+// Check 实现 zapcore.Core
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 将日志加入导出器的缓冲区
+// 与 zap 的 ioCore 一致，高于 Error 的日志写入后立即同步发送，避免程序随后退出时丢失
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	all := fields
+	if len(c.fields) > 0 {
+		all = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+		all = append(all, c.fields...)
+		all = append(all, fields...)
+	}
+	c.exporter.export(newRecord(ent, all))
+
+	if ent.Level > zapcore.ErrorLevel {
+		return c.Sync()
+	}
+	return nil
+}
+
+// Sync 同步发送导出器缓冲区中的日志
+func (c *Core) Sync() error {
+	return c.exporter.Flush(context.Background())
+}
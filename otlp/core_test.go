@@ -0,0 +1,200 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeCollector 是记录导出请求的 OTLP 测试接收端
+type fakeCollector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []exportRequest
+	headers  []http.Header
+	paths    []string
+}
+
+func newFakeCollector(t *testing.T) *fakeCollector {
+	t.Helper()
+
+	f := &fakeCollector{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req exportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.headers = append(f.headers, r.Header.Clone())
+		f.paths = append(f.paths, r.URL.Path)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// records 返回收到的所有日志
+func (f *fakeCollector) records() []logRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var records []logRecord
+	for _, req := range f.requests {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+func newTestCore(t *testing.T, config Config) (*zap.Logger, *Exporter) {
+	t.Helper()
+
+	exporter, err := NewExporter(config)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = exporter.Shutdown(context.Background()) })
+	return zap.New(NewCore(exporter, zapcore.InfoLevel)), exporter
+}
+
+func TestCoreExport(t *testing.T) {
+	collector := newFakeCollector(t)
+	logger, _ := newTestCore(t, Config{
+		Endpoint:           collector.URL,
+		Headers:            map[string]string{"Authorization": "Bearer t0ken"},
+		ResourceAttributes: map[string]string{"service.name": "billing"},
+	})
+
+	logger.Debug("filtered")
+	logger.Named("db").With(zap.String("tenant", "a")).Warn("slow query",
+		zap.Int("rows", 3),
+		zap.Bool("cached", false),
+		zap.Float64("ratio", 0.5),
+		zap.String(TraceIDKey, "4bf92f3577b34da6a3ce929d0e0e4736"),
+		zap.String(SpanIDKey, "00f067aa0ba902b7"),
+	)
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	records := collector.records()
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	r := records[0]
+	if r.SeverityNumber != 13 || r.SeverityText != "WARN" || *r.Body.StringValue != "slow query" {
+		t.Errorf("record = %+v", r)
+	}
+	if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.SpanID != "00f067aa0ba902b7" {
+		t.Errorf("traceId = %q, spanId = %q", r.TraceID, r.SpanID)
+	}
+	ratio, cached := 0.5, false
+	want := []keyValue{
+		{Key: "cached", Value: anyValue{BoolValue: &cached}},
+		{Key: "logger", Value: stringValue("db")},
+		{Key: "ratio", Value: anyValue{DoubleValue: &ratio}},
+		{Key: "rows", Value: intValue(3)},
+		{Key: "tenant", Value: stringValue("a")},
+	}
+	if !reflect.DeepEqual(r.Attributes, want) {
+		t.Errorf("attributes = %+v, want %+v", r.Attributes, want)
+	}
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if got := collector.paths[0]; got != DefaultLogsPath {
+		t.Errorf("path = %q, want %q", got, DefaultLogsPath)
+	}
+	if got := collector.headers[0].Get("Authorization"); got != "Bearer t0ken" {
+		t.Errorf("Authorization = %q", got)
+	}
+	wantResource := []keyValue{{Key: "service.name", Value: stringValue("billing")}}
+	if got := collector.requests[0].ResourceLogs[0].Resource.Attributes; !reflect.DeepEqual(got, wantResource) {
+		t.Errorf("resource attributes = %+v, want %+v", got, wantResource)
+	}
+}
+
+func TestExporterBatchAndShutdown(t *testing.T) {
+	collector := newFakeCollector(t)
+	logger, exporter := newTestCore(t, Config{Endpoint: collector.URL, BatchSize: 2, FlushInterval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		logger.Info("hello")
+	}
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := len(collector.records()); got != 5 {
+		t.Errorf("got %d records after Shutdown, want 5", got)
+	}
+	collector.mu.Lock()
+	requests := len(collector.requests)
+	collector.mu.Unlock()
+	if got := requests; got < 3 {
+		t.Errorf("got %d requests, want batches of at most 2", got)
+	}
+
+	// 关闭之后的日志被丢弃
+	logger.Info("late")
+	if exporter.DroppedCount() != 1 {
+		t.Errorf("DroppedCount() = %d, want 1", exporter.DroppedCount())
+	}
+}
+
+func TestFatalLevelSyncsImmediately(t *testing.T) {
+	collector := newFakeCollector(t)
+	exporter, err := NewExporter(Config{Endpoint: collector.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+	core := NewCore(exporter, zapcore.InfoLevel)
+
+	if err := core.Write(zapcore.Entry{Level: zapcore.FatalLevel, Message: "boom", Time: time.Now()}, nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if records := collector.records(); len(records) != 1 || records[0].SeverityNumber != 22 {
+		t.Errorf("records = %+v, want the fatal record sent before Write returns", records)
+	}
+}
+
+func TestNewExporterValidation(t *testing.T) {
+	for _, endpoint := range []string{"", "collector:4318", "://bad"} {
+		if _, err := NewExporter(Config{Endpoint: endpoint}); err == nil {
+			t.Errorf("NewExporter(%q) error = nil, want error", endpoint)
+		}
+	}
+}
+
+func TestToAnyValue(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want anyValue
+	}{
+		{"s", stringValue("s")},
+		{int32(-4), intValue(-4)},
+		{uint64(1 << 63), stringValue("9223372036854775808")},
+		{1500 * time.Millisecond, stringValue("1.5s")},
+		{[]interface{}{"a", 1}, stringValue(`["a",1]`)},
+	}
+	for _, tt := range tests {
+		if got := toAnyValue(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("toAnyValue(%#v) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// TraceIDKey 是 trace ID 的字段名，与 otel.TraceIDKey 一致
+	// 取值为 32 位十六进制时作为日志的 traceId 发送，而不是普通属性
+	TraceIDKey = "trace_id"
+	// SpanIDKey 是 span ID 的字段名，与 otel.SpanIDKey 一致
+	// 取值为 16 位十六进制时作为日志的 spanId 发送，而不是普通属性
+	SpanIDKey = "span_id"
+)
+
+// severity 将 zap 级别映射为 OTLP 的 SeverityNumber
+// DPanic 视为 ERROR2，Panic 和 Fatal 分别视为 FATAL 和 FATAL2
+func severity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel:
+		return 21
+	case zapcore.FatalLevel:
+		return 22
+	default:
+		return 0
+	}
+}
+
+// newRecord 将一条 zap 日志转换为 OTLP 日志
+// 日志器名称、调用方和堆栈分别作为 logger、caller 和 stacktrace 属性
+func newRecord(ent zapcore.Entry, fields []zapcore.Field) logRecord {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	if ent.LoggerName != "" {
+		enc.Fields["logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		enc.Fields["caller"] = ent.Caller.TrimmedPath()
+	}
+	if ent.Stack != "" {
+		enc.Fields["stacktrace"] = ent.Stack
+	}
+
+	record := logRecord{
+		TimeUnixNano:         strconv.FormatInt(ent.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       severity(ent.Level),
+		SeverityText:         ent.Level.CapitalString(),
+		Body:                 stringValue(ent.Message),
+	}
+	if id, ok := enc.Fields[TraceIDKey].(string); ok && isHexID(id, 16) {
+		record.TraceID = id
+		delete(enc.Fields, TraceIDKey)
+	}
+	if id, ok := enc.Fields[SpanIDKey].(string); ok && isHexID(id, 8) {
+		record.SpanID = id
+		delete(enc.Fields, SpanIDKey)
+	}
+	record.Attributes = attributes(enc.Fields)
+	return record
+}
+
+// attributes 将字段转换为按键排序的属性
+func attributes(fields map[string]interface{}) []keyValue {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]keyValue, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, keyValue{Key: k, Value: toAnyValue(fields[k])})
+	}
+	return attrs
+}
+
+// stringAttributes 将字符串映射转换为按键排序的属性
+func stringAttributes(values map[string]string) []keyValue {
+	fields := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		fields[k] = v
+	}
+	return attributes(fields)
+}
+
+// toAnyValue 将 zap 编码后的字段值转换为属性值
+// 对象和数组没有对应的简单类型，编码为 JSON 字符串
+func toAnyValue(v interface{}) anyValue {
+	switch v := v.(type) {
+	case string:
+		return stringValue(v)
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int8:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint:
+		return uintValue(uint64(v))
+	case uint8:
+		return uintValue(uint64(v))
+	case uint16:
+		return uintValue(uint64(v))
+	case uint32:
+		return uintValue(uint64(v))
+	case uint64:
+		return uintValue(v)
+	case uintptr:
+		return uintValue(uint64(v))
+	case float32:
+		f := float64(v)
+		return anyValue{DoubleValue: &f}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case time.Duration:
+		return stringValue(v.String())
+	case time.Time:
+		return stringValue(v.Format(time.RFC3339Nano))
+	case fmt.Stringer:
+		return stringValue(v.String())
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return stringValue(fmt.Sprint(v))
+	}
+	return stringValue(string(data))
+}
+
+// stringValue 返回字符串属性值
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+// intValue 返回整数属性值
+func intValue(n int64) anyValue {
+	s := strconv.FormatInt(n, 10)
+	return anyValue{IntValue: &s}
+}
+
+// uintValue 返回无符号整数属性值，超过 int64 范围时编码为字符串
+func uintValue(n uint64) anyValue {
+	if n > 1<<63-1 {
+		return stringValue(strconv.FormatUint(n, 10))
+	}
+	return intValue(int64(n))
+}
+
+// isHexID 判断 s 是否是 n 字节、且不全为零的十六进制 ID
+func isHexID(s string, n int) bool {
+	if len(s) != 2*n {
+		return false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return false
+	}
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
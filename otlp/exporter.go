@@ -0,0 +1,226 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// scopeName 是 OTLP 日志中的埋点库名称
+const scopeName = "github.com/bt-smart/btlog"
+
+// Exporter 在后台批量发送 OTLP 日志
+// 日志先进入内存缓冲区，达到 BatchSize 或每隔 FlushInterval 发送一次
+type Exporter struct {
+	config Config
+	// url 是日志接口的完整地址
+	url string
+	// resource 是所有请求共有的资源属性
+	resource []keyValue
+
+	mu      sync.Mutex
+	records []logRecord
+	// sendMu 保证同一时间只有一次发送，使日志按写入顺序到达
+	sendMu sync.Mutex
+
+	// flushReq 用于请求工作协程发送，容量为 1，多次请求会被合并
+	flushReq chan struct{}
+	// done 在 Shutdown 时关闭，通知工作协程退出
+	done chan struct{}
+	// workerDone 在工作协程完成最后一次发送并退出后关闭
+	workerDone chan struct{}
+	// closed 表示是否已关闭
+	closed atomic.Bool
+	// dropped 是缓冲区溢出或发送失败而丢弃的日志条数
+	dropped atomic.Uint64
+}
+
+// NewExporter 创建导出器并启动后台工作协程
+func NewExporter(config Config) (*Exporter, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+	if config.LogsPath == "" {
+		config.LogsPath = DefaultLogsPath
+	}
+	if !strings.HasPrefix(config.LogsPath, "/") {
+		config.LogsPath = "/" + config.LogsPath
+	}
+	raw := strings.TrimSuffix(config.Endpoint, "/") + config.LogsPath
+	if u, err := url.Parse(raw); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q: scheme and host are required", raw)
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxBufferEntries <= 0 {
+		config.MaxBufferEntries = config.BatchSize * 100
+	}
+
+	e := &Exporter{
+		config:     config,
+		url:        raw,
+		resource:   stringAttributes(config.ResourceAttributes),
+		flushReq:   make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		workerDone: make(chan struct{}),
+	}
+	go e.worker()
+	return e, nil
+}
+
+// export 将一条日志加入缓冲区，缓冲区超过上限时丢弃最早的日志
+func (e *Exporter) export(record logRecord) {
+	if e.closed.Load() {
+		e.dropped.Add(1)
+		return
+	}
+
+	e.mu.Lock()
+	if len(e.records) >= e.config.MaxBufferEntries {
+		e.records = e.records[1:]
+		e.dropped.Add(1)
+	}
+	e.records = append(e.records, record)
+	full := len(e.records) >= e.config.BatchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.flushReq <- struct{}{}:
+		default:
+			// 已有未处理的请求，本次请求合并到其中
+		}
+	}
+}
+
+// worker 是后台工作协程的主循环
+func (e *Exporter) worker() {
+	defer close(e.workerDone)
+
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			e.logError(e.Flush(context.Background()))
+			return
+		case <-e.flushReq:
+			e.logError(e.Flush(context.Background()))
+		case <-ticker.C:
+			e.logError(e.Flush(context.Background()))
+		}
+	}
+}
+
+// logError 记录后台发送的错误
+// 为了避免递归，这里使用标准库的log包记录错误
+func (e *Exporter) logError(err error) {
+	if err != nil {
+		log.Printf("Failed to export logs to OTLP endpoint: %v", err)
+	}
+}
+
+// Flush 立即同步发送缓冲区中的所有日志，并返回发送错误
+// ctx 结束时取消进行中的请求，发送失败的日志被丢弃
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.sendMu.Lock()
+	defer e.sendMu.Unlock()
+
+	e.mu.Lock()
+	records := e.records
+	e.records = nil
+	e.mu.Unlock()
+
+	var errs []error
+	for len(records) > 0 {
+		n := min(len(records), e.config.BatchSize)
+		if err := e.send(ctx, records[:n]); err != nil {
+			e.dropped.Add(uint64(n))
+			errs = append(errs, err)
+		}
+		records = records[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown 停止工作协程，并在退出前发送缓冲区中的所有日志
+// ctx 结束时不再等待并返回错误。该方法可以被多次调用
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	if e.closed.Swap(true) {
+		return nil
+	}
+	close(e.done)
+
+	select {
+	case <-e.workerDone:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("shutdown cancelled before final export: %w", ctx.Err())
+	}
+}
+
+// DroppedCount 返回因缓冲区溢出或发送失败而丢弃的日志条数
+func (e *Exporter) DroppedCount() uint64 {
+	return e.dropped.Load()
+}
+
+// send 将一批日志作为一个请求发送
+func (e *Exporter) send(ctx context.Context, records []logRecord) error {
+	data, err := json.Marshal(exportRequest{
+		ResourceLogs: []resourceLogs{{
+			Resource: resource{Attributes: e.resource},
+			ScopeLogs: []scopeLogs{{
+				Scope:      scope{Name: scopeName},
+				LogRecords: records,
+			}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal request failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Package otlp 实现将日志通过 OTLP/HTTP 导出到 OpenTelemetry 接收端的 zap core
+// 直接编码 OTLP 的 JSON 格式，不依赖 OpenTelemetry SDK
+package otlp
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultLogsPath 是 OTLP/HTTP 日志接口的默认路径
+const DefaultLogsPath = "/v1/logs"
+
+// Config 定义 OTLP 日志导出器的配置
+type Config struct {
+	// Endpoint 是 OTLP/HTTP 接收端的地址，例如 http://otel-collector:4318
+	Endpoint string
+	// LogsPath 是日志接口的路径，拼接在 Endpoint 之后，默认为 /v1/logs
+	LogsPath string
+	// Headers 是每个请求附加的请求头，例如认证令牌
+	Headers map[string]string
+	// HTTPClient 是用于发送请求的 HTTP 客户端
+	// 如果为 nil，将使用 http.DefaultClient
+	HTTPClient *http.Client
+	// ResourceAttributes 是所有日志共有的资源属性，例如 service.name
+	ResourceAttributes map[string]string
+	// BatchSize 是每个请求最多包含的日志条数，默认为 100
+	BatchSize int
+	// FlushInterval 是定期发送的间隔，默认为 5 秒
+	FlushInterval time.Duration
+	// Timeout 是单个请求的超时时间，默认为 10 秒
+	Timeout time.Duration
+	// MaxBufferEntries 是缓冲区最多保存的日志条数，超过后丢弃最早的日志，默认为 BatchSize 的 100 倍
+	MaxBufferEntries int
+}
+
+// exportRequest 是 OTLP 的 ExportLogsServiceRequest
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+// resourceLogs 是同一个资源产生的日志
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+// resource 描述产生日志的资源
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+// scopeLogs 是同一个埋点库产生的日志
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+// scope 描述产生日志的埋点库
+type scope struct {
+	Name string `json:"name"`
+}
+
+// logRecord 是一条 OTLP 日志
+// 按 OTLP 的 JSON 编码规则，64 位整数编码为字符串，trace ID 和 span ID 编码为十六进制
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+}
+
+// keyValue 是一个属性
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue 是属性值，只有一个字段不为 nil
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
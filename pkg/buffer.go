@@ -2,8 +2,12 @@
 package pkg
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"go.uber.org/zap/zapcore"
+	"strconv"
 	"sync"
+	"time"
 )
 
 // LogEntry 表示一条日志记录
@@ -19,71 +23,273 @@ type LogEntry struct {
 
 	// Level 日志级别
 	Level zapcore.Level
+
+	// Labels 是该条日志附加的流标签，会与客户端的默认标签合并
+	// 为空时只使用默认标签
+	Labels map[string]string
+
+	// Metadata 是该条日志的结构化元数据（Loki 2.9+）
+	// 不参与流的划分，适合存放 trace_id 等高基数的信息
+	Metadata map[string]string
 }
 
-// Buffer 实现了一个线程安全的日志缓冲区
-// 用于批量收集日志条目，当达到指定大小时触发发送
-type Buffer struct {
-	// entries 存储日志条目
-	entries []LogEntry
+// ID 返回日志条目的确定性标识
+// 由时间戳、级别和消息计算得到，相同的日志总是得到相同的标识，
+// 可用于在重试导致重复发送时进行去重
+func (e LogEntry) ID() string {
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatInt(e.Timestamp, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Level.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Message))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// Size 返回日志条目序列化后的估计字节数
+// 包括消息、附加标签和结构化元数据的长度，以及时间戳的长度，
+// 不包括 JSON 或 protobuf 编码的额外开销
+func (e LogEntry) Size() int {
+	// 纳秒时间戳最多 19 位
+	n := 19 + len(e.Message)
+	for k, v := range e.Labels {
+		n += len(k) + len(v)
+	}
+	for k, v := range e.Metadata {
+		n += len(k) + len(v)
+	}
+	return n
+}
+
+// DropPolicy 定义缓冲区达到容量上限时的丢弃策略
+type DropPolicy int
+
+const (
+	// DropOldest 丢弃缓冲区中最早的日志，为新日志腾出空间
+	DropOldest DropPolicy = iota
+	// DropNewest 丢弃新加入的日志，保留缓冲区中已有的日志
+	DropNewest
+)
+
+// TypedBuffer 是线程安全的通用批量缓冲区，T 是条目的类型
+// 用于批量收集条目，达到指定条数或字节数时提示调用方发送，
+// 除日志之外也可以用于指标数据点等其他需要批量发送的数据
+type TypedBuffer[T any] struct {
+	// entries 存储条目
+	entries []T
 	// size 是触发发送的目标大小
 	size int
+	// maxEntries 是缓冲区最多保存的条目数，0 表示不限制
+	maxEntries int
+	// policy 是超过 maxEntries 时的丢弃策略
+	policy DropPolicy
+	// dropped 是因超过容量上限被丢弃的条目总数
+	dropped uint64
+	// added 是每个条目加入缓冲区的时间，与 entries 一一对应
+	added []time.Time
+	// sizeOf 返回条目的估计字节数，为 nil 时不统计字节数
+	sizeOf func(T) int
+	// maxBytes 是触发发送的目标字节数，0 表示只按条数触发
+	maxBytes int
+	// bytes 是缓冲区中所有条目的估计字节数之和，在加入和移除条目时累加
+	bytes int
 	// mu 用于保护并发访问
 	mu sync.Mutex
 }
 
-// NewBuffer 创建一个新的缓冲区实例
+// Buffer 实现了一个线程安全的日志缓冲区
+// 用于批量收集日志条目，当达到指定大小时触发发送
+type Buffer = TypedBuffer[LogEntry]
+
+// NewTypedBuffer 创建一个新的通用缓冲区实例
 // 参数：
 //   - size: 触发发送的目标大小
 //
 // 返回：
-//   - *Buffer: 初始化好的缓冲区实例
-func NewBuffer(size int) *Buffer {
+//   - *TypedBuffer[T]: 初始化好的缓冲区实例
+func NewTypedBuffer[T any](size int) *TypedBuffer[T] {
 	if size <= 0 {
 		size = 100 // 设置一个合理的默认值
 	}
-	return &Buffer{
-		entries: make([]LogEntry, 0, size), // 预分配容量以提高性能
+	return &TypedBuffer[T]{
+		entries: make([]T, 0, size), // 预分配容量以提高性能
+		added:   make([]time.Time, 0, size),
 		size:    size,
 	}
 }
 
-// Add 向缓冲区添加一条日志
+// NewBoundedTypedBuffer 创建一个有容量上限的通用缓冲区实例
+// 参数与 NewBoundedBuffer 相同
+func NewBoundedTypedBuffer[T any](size, maxEntries int, policy DropPolicy) *TypedBuffer[T] {
+	b := NewTypedBuffer[T](size)
+	if maxEntries > 0 {
+		b.maxEntries = max(maxEntries, b.size)
+	}
+	b.policy = policy
+	return b
+}
+
+// NewBuffer 创建一个新的缓冲区实例
+// 参数：
+//   - size: 触发发送的目标大小
+//
+// 返回：
+//   - *Buffer: 初始化好的缓冲区实例
+func NewBuffer(size int) *Buffer {
+	b := NewTypedBuffer[LogEntry](size)
+	b.sizeOf = LogEntry.Size
+	return b
+}
+
+// NewBoundedBuffer 创建一个有容量上限的缓冲区实例
+// 在 Loki 长时间不可用等情况下，缓冲区最多保存 maxEntries 条日志，
+// 超过上限时按 policy 丢弃日志，避免内存无限增长
+// 参数：
+//   - size: 触发发送的目标大小
+//   - maxEntries: 最多保存的日志条数，小于等于 0 表示不限制，小于 size 时按 size 处理
+//   - policy: 超过上限时的丢弃策略
+//
+// 返回：
+//   - *Buffer: 初始化好的缓冲区实例
+func NewBoundedBuffer(size, maxEntries int, policy DropPolicy) *Buffer {
+	b := NewBoundedTypedBuffer[LogEntry](size, maxEntries, policy)
+	b.sizeOf = LogEntry.Size
+	return b
+}
+
+// Add 向缓冲区添加一个条目
 // 该方法是线程安全的，可以被多个goroutine同时调用
 // 参数：
-//   - entry: 要添加的日志条目
+//   - entry: 要添加的条目
 //
 // 返回：
-//   - bool: 如果缓冲区达到目标大小返回true，表示应该触发发送操作
-func (b *Buffer) Add(entry LogEntry) bool {
+//   - bool: 如果条目被加入且缓冲区达到目标大小返回true，表示应该触发发送操作；
+//     按 DropNewest 策略丢弃该条目时返回false
+func (b *TypedBuffer[T]) Add(entry T) bool {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// 添加日志条目到切片
+	// 达到容量上限时按策略丢弃
+	if b.maxEntries > 0 && len(b.entries) >= b.maxEntries {
+		b.dropped++
+		if b.policy == DropNewest {
+			return false
+		}
+		b.bytes -= b.entrySize(b.entries[0])
+		b.entries = b.entries[1:]
+		b.added = b.added[1:]
+	}
+
+	// 添加条目到切片
 	b.entries = append(b.entries, entry)
+	b.added = append(b.added, time.Now())
+	b.bytes += b.entrySize(entry)
 
-	// 检查是否达到目标大小
-	return len(b.entries) >= b.size
+	// 检查是否达到目标大小或目标字节数
+	return len(b.entries) >= b.size || (b.maxBytes > 0 && b.bytes >= b.maxBytes)
+}
+
+// entrySize 返回条目的估计字节数，没有设置 sizeOf 时返回 0
+func (b *TypedBuffer[T]) entrySize(entry T) int {
+	if b.sizeOf == nil {
+		return 0
+	}
+	return b.sizeOf(entry)
+}
+
+// SetSizeFunc 设置计算条目估计字节数的函数，配合 SetMaxBytes 按字节数触发发送
+// Buffer 默认使用 LogEntry.Size，其他类型默认不统计字节数。应在开始使用缓冲区之前调用
+func (b *TypedBuffer[T]) SetSizeFunc(sizeOf func(T) int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sizeOf = sizeOf
+}
+
+// SetMaxBytes 设置触发发送的目标字节数
+// 缓冲区中条目的估计字节数之和（参见 LogEntry.Size 和 SetSizeFunc）达到 maxBytes 时，Add 返回 true。
+// 小于等于 0 表示只按条数触发。应在开始使用缓冲区之前调用
+func (b *TypedBuffer[T]) SetMaxBytes(maxBytes int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.maxBytes = max(maxBytes, 0)
+}
+
+// Bytes 返回缓冲区中所有条目的估计字节数之和
+// 该方法是线程安全的
+func (b *TypedBuffer[T]) Bytes() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.bytes
+}
+
+// Len 返回缓冲区中当前的条目数
+// 该方法是线程安全的
+func (b *TypedBuffer[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.entries)
+}
+
+// Cap 返回缓冲区的容量
+// 设置了容量上限时返回上限，否则返回触发发送的目标大小，
+// 可以与 Len 一起用于计算缓冲区的填充程度
+// 该方法是线程安全的
+func (b *TypedBuffer[T]) Cap() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxEntries > 0 {
+		return b.maxEntries
+	}
+	return b.size
+}
+
+// DroppedCount 返回因超过容量上限被丢弃的条目总数
+// 该方法是线程安全的
+func (b *TypedBuffer[T]) DroppedCount() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dropped
+}
+
+// Oldest 返回缓冲区中最早一个条目加入的时间
+// 缓冲区为空时返回零值
+// 该方法是线程安全的
+func (b *TypedBuffer[T]) Oldest() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.added) == 0 {
+		return time.Time{}
+	}
+	return b.added[0]
 }
 
-// Flush 清空并返回缓冲区中的所有日志条目
+// Flush 清空并返回缓冲区中的所有条目
 // 该方法是线程安全的
 // 返回：
-//   - []LogEntry: 缓冲区中的所有日志条目
-func (b *Buffer) Flush() []LogEntry {
+//   - []T: 缓冲区中的所有条目
+func (b *TypedBuffer[T]) Flush() []T {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// 如果没有日志，返回nil
+	// 如果没有条目，返回nil
 	if len(b.entries) == 0 {
 		return nil
 	}
 
-	// 获取当前所有日志
+	// 获取当前所有条目
 	entries := b.entries
 
 	// 创建新的切片，保持预分配的容量
-	b.entries = make([]LogEntry, 0, b.size)
+	b.entries = make([]T, 0, b.size)
+	b.added = make([]time.Time, 0, b.size)
+	b.bytes = 0
 
 	return entries
 }
@@ -0,0 +1,277 @@
+package pkg
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogEntryID(t *testing.T) {
+	base := LogEntry{Timestamp: 1700000000000000000, Message: "payment accepted", Level: zapcore.InfoLevel}
+
+	same := base
+	same.Labels = map[string]string{"ignored": "labels do not affect the id"}
+	if base.ID() != same.ID() {
+		t.Errorf("identical entries got different ids: %s, %s", base.ID(), same.ID())
+	}
+	if len(base.ID()) != 32 {
+		t.Errorf("id %q has %d characters, want 32", base.ID(), len(base.ID()))
+	}
+
+	variants := map[string]LogEntry{
+		"timestamp": {Timestamp: base.Timestamp + 1, Message: base.Message, Level: base.Level},
+		"message":   {Timestamp: base.Timestamp, Message: "payment rejected", Level: base.Level},
+		"level":     {Timestamp: base.Timestamp, Message: base.Message, Level: zapcore.ErrorLevel},
+	}
+	for name, v := range variants {
+		if v.ID() == base.ID() {
+			t.Errorf("entries differing in %s share id %s", name, base.ID())
+		}
+	}
+}
+
+// messages 返回日志条目的消息列表
+func messages(entries []LogEntry) []string {
+	result := make([]string, len(entries))
+	for i, e := range entries {
+		result[i] = e.Message
+	}
+	return result
+}
+
+func TestBoundedBufferDropPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      DropPolicy
+		want        []string
+		wantTrigger []bool
+	}{
+		{"drop oldest", DropOldest, []string{"2", "3", "4"}, []bool{false, false, true, true, true}},
+		{"drop newest", DropNewest, []string{"0", "1", "2"}, []bool{false, false, true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBoundedBuffer(3, 3, tt.policy)
+			for i := 0; i < 5; i++ {
+				if got := b.Add(LogEntry{Message: strconv.Itoa(i)}); got != tt.wantTrigger[i] {
+					t.Errorf("Add(%d) = %v, want %v", i, got, tt.wantTrigger[i])
+				}
+			}
+			if b.DroppedCount() != 2 {
+				t.Errorf("DroppedCount() = %d, want 2", b.DroppedCount())
+			}
+			got := messages(b.Flush())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoundedBufferMinimumCap(t *testing.T) {
+	if got := NewBoundedBuffer(10, 3, DropOldest).Cap(); got != 10 {
+		t.Errorf("Cap() = %d, want maxEntries clamped to size 10", got)
+	}
+	if got := NewBoundedBuffer(10, 0, DropOldest).Cap(); got != 10 {
+		t.Errorf("Cap() = %d without limit, want size 10", got)
+	}
+	b := NewBoundedBuffer(2, 0, DropOldest)
+	for i := 0; i < 100; i++ {
+		b.Add(LogEntry{})
+	}
+	if b.Len() != 100 || b.DroppedCount() != 0 {
+		t.Errorf("unbounded buffer Len() = %d, DroppedCount() = %d", b.Len(), b.DroppedCount())
+	}
+}
+
+func TestBufferOldestAfterEviction(t *testing.T) {
+	b := NewBoundedBuffer(2, 2, DropOldest)
+	if !b.Oldest().IsZero() {
+		t.Error("empty buffer Oldest() is not zero")
+	}
+
+	b.Add(LogEntry{Message: "0"})
+	first := b.Oldest()
+	time.Sleep(5 * time.Millisecond)
+	b.Add(LogEntry{Message: "1"})
+	time.Sleep(5 * time.Millisecond)
+	b.Add(LogEntry{Message: "2"})
+
+	// 最早的日志被丢弃后，Oldest 应该变为第二条日志的加入时间
+	if oldest := b.Oldest(); !oldest.After(first) {
+		t.Errorf("Oldest() = %v after evicting the first entry, want later than %v", oldest, first)
+	}
+
+	b.Flush()
+	if !b.Oldest().IsZero() {
+		t.Error("Oldest() is not zero after Flush")
+	}
+
+	// DropNewest 不改变最早的日志
+	n := NewBoundedBuffer(1, 1, DropNewest)
+	n.Add(LogEntry{})
+	kept := n.Oldest()
+	time.Sleep(5 * time.Millisecond)
+	n.Add(LogEntry{})
+	if !n.Oldest().Equal(kept) {
+		t.Errorf("Oldest() changed after DropNewest: %v, want %v", n.Oldest(), kept)
+	}
+}
+
+func TestBufferLenCap(t *testing.T) {
+	b := NewBoundedBuffer(4, 8, DropOldest)
+	if b.Len() != 0 || b.Cap() != 8 {
+		t.Fatalf("new buffer Len() = %d, Cap() = %d, want 0 and 8", b.Len(), b.Cap())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				b.Add(LogEntry{})
+				_ = b.Len()
+				_ = b.Cap()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if b.Len() != 8 {
+		t.Errorf("Len() = %d, want capped at 8", b.Len())
+	}
+	b.Flush()
+	if b.Len() != 0 || b.Cap() != 8 {
+		t.Errorf("after Flush Len() = %d, Cap() = %d, want 0 and 8", b.Len(), b.Cap())
+	}
+	if NewBuffer(0).Cap() != 100 {
+		t.Errorf("NewBuffer(0).Cap() = %d, want default 100", NewBuffer(0).Cap())
+	}
+}
+
+func TestLogEntrySize(t *testing.T) {
+	e := LogEntry{
+		Message:  "hello",
+		Labels:   map[string]string{"app": "svc"},
+		Metadata: map[string]string{"trace_id": "abc"},
+	}
+	if got, want := e.Size(), 19+5+6+11; got != want {
+		t.Errorf("Size() = %d, want %d", got, want)
+	}
+}
+
+func TestBufferMaxBytes(t *testing.T) {
+	entry := LogEntry{Message: strings.Repeat("x", 81)} // Size() = 100
+	b := NewBuffer(100)
+	b.SetMaxBytes(250)
+
+	if b.Add(entry) || b.Add(entry) {
+		t.Fatal("Add() = true before reaching MaxBytes")
+	}
+	if !b.Add(entry) {
+		t.Error("Add() = false after crossing MaxBytes, want flush signal")
+	}
+	if got := b.Bytes(); got != 300 {
+		t.Errorf("Bytes() = %d, want 300", got)
+	}
+
+	b.Flush()
+	if got := b.Bytes(); got != 0 {
+		t.Errorf("Bytes() after Flush = %d, want 0", got)
+	}
+
+	// 超过条数上限时被淘汰的日志不再计入字节数
+	bounded := NewBoundedBuffer(3, 3, DropOldest)
+	for i := 0; i < 3; i++ {
+		bounded.Add(entry)
+	}
+	bounded.Add(LogEntry{Message: "y"})
+	if got := bounded.Bytes(); got != 220 {
+		t.Errorf("Bytes() after eviction = %d, want 220", got)
+	}
+}
+
+// metricPoint 是测试通用缓冲区使用的非日志类型
+type metricPoint struct {
+	Name  string
+	Value float64
+}
+
+func TestTypedBufferConcurrent(t *testing.T) {
+	const (
+		writers   = 8
+		perWriter = 1000
+	)
+	b := NewTypedBuffer[metricPoint](50)
+
+	var mu sync.Mutex
+	var flushed []metricPoint
+	collect := func() {
+		points := b.Flush()
+		mu.Lock()
+		flushed = append(flushed, points...)
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if b.Add(metricPoint{Name: "w" + strconv.Itoa(w), Value: float64(i)}) {
+					collect()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	collect()
+
+	if len(flushed) != writers*perWriter {
+		t.Fatalf("flushed %d points, want %d", len(flushed), writers*perWriter)
+	}
+	// 每个写入方的数据点都完整且按写入顺序出现
+	next := make(map[string]float64)
+	for _, p := range flushed {
+		if p.Value != next[p.Name] {
+			t.Fatalf("point %+v out of order, want value %v", p, next[p.Name])
+		}
+		next[p.Name]++
+	}
+	if b.Len() != 0 || b.Bytes() != 0 {
+		t.Errorf("Len() = %d, Bytes() = %d after final Flush, want 0", b.Len(), b.Bytes())
+	}
+}
+
+func TestTypedBufferSizeFunc(t *testing.T) {
+	b := NewBoundedTypedBuffer[metricPoint](3, 3, DropNewest)
+	b.SetMaxBytes(15)
+	if b.Add(metricPoint{Name: "cpu"}) || b.Add(metricPoint{Name: "mem"}) {
+		t.Error("Add() = true without a size func, want only the count to trigger")
+	}
+	b.Add(metricPoint{Name: "disk"})
+	if b.Add(metricPoint{Name: "net"}) || b.DroppedCount() != 1 || b.Len() != 3 {
+		t.Errorf("Add() over the cap: dropped = %d, Len() = %d, want the new point dropped", b.DroppedCount(), b.Len())
+	}
+
+	sized := NewTypedBuffer[metricPoint](10)
+	sized.SetSizeFunc(func(p metricPoint) int { return len(p.Name) + 8 })
+	sized.SetMaxBytes(20)
+	if sized.Add(metricPoint{Name: "cpu"}) {
+		t.Error("Add() = true below MaxBytes")
+	}
+	if !sized.Add(metricPoint{Name: "mem"}) || sized.Bytes() != 22 {
+		t.Errorf("Add() did not trigger at MaxBytes, Bytes() = %d", sized.Bytes())
+	}
+	if got := sized.Flush(); len(got) != 2 || got[0].Name != "cpu" {
+		t.Errorf("Flush() = %+v", got)
+	}
+}
@@ -0,0 +1,53 @@
+package zap
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// encodeBinaryFields 将字节类型的字段转换为字符串字段
+// zap.Binary 按配置编码为 base64（默认）或十六进制，
+// zap.ByteString 按 UTF-8 解释，非法字节序列替换为 U+FFFD。
+// 转换在写入之前完成，保证文件和 Loki 中看到的内容一致。
+func (l *Logger) encodeBinaryFields(fields []zap.Field) []zap.Field {
+	var converted []zap.Field
+	for i, field := range fields {
+		var replacement zap.Field
+		switch field.Type {
+		case zapcore.BinaryType:
+			replacement = zap.String(field.Key, l.encodeBinary(field.Interface.([]byte)))
+		case zapcore.ByteStringType:
+			b := field.Interface.([]byte)
+			if utf8.Valid(b) {
+				continue
+			}
+			replacement = zap.String(field.Key, strings.ToValidUTF8(string(b), string(utf8.RuneError)))
+		default:
+			continue
+		}
+
+		// 第一次需要转换时才复制，避免修改调用方的切片
+		if converted == nil {
+			converted = append([]zap.Field(nil), fields...)
+		}
+		converted[i] = replacement
+	}
+
+	if converted == nil {
+		return fields
+	}
+	return converted
+}
+
+// encodeBinary 按配置将二进制数据编码为字符串
+func (l *Logger) encodeBinary(b []byte) string {
+	if l.config.BinaryAsHex {
+		return hex.EncodeToString(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,63 @@
+package zap
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestBinaryFields(t *testing.T) {
+	payload := []byte{0x00, 0xff, 0x10, 0x80}
+	invalid := []byte("ok\xffend")
+
+	tests := []struct {
+		name  string
+		hex   bool
+		field zap.Field
+		want  string
+	}{
+		{"binary as base64", false, zap.Binary("payload", payload), "AP8QgA=="},
+		{"binary as hex", true, zap.Binary("payload", payload), "00ff1080"},
+		{"valid byte string", false, zap.ByteString("payload", []byte("héllo")), "héllo"},
+		{"invalid byte string", false, zap.ByteString("payload", invalid), "ok�end"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileLogger, path := newFileLogger(t, Config{BinaryAsHex: tt.hex})
+			fileLogger.Info("bytes", tt.field)
+			records := readFileLines(t, fileLogger, path)
+			if len(records) != 1 || records[0]["payload"] != tt.want {
+				t.Errorf("file records = %v, want payload %q", records, tt.want)
+			}
+
+			lokiLogger, server := newLokiLogger(t, Config{BinaryAsHex: tt.hex})
+			lokiLogger.Info("bytes", tt.field)
+			lines := closeAndCollect(t, lokiLogger, server)
+			if len(lines) != 1 {
+				t.Fatalf("got %d Loki lines, want 1", len(lines))
+			}
+			if got := lineFields(t, lines[0].Line)["payload"]; got != tt.want {
+				t.Errorf("Loki payload = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeBinaryFieldsDoesNotModifyInput(t *testing.T) {
+	l := &Logger{}
+	fields := []zap.Field{zap.String("a", "b"), zap.Binary("payload", []byte{1})}
+
+	got := l.encodeBinaryFields(fields)
+	if fields[1].Key != "payload" || fields[1].String != "" || fields[1].Interface == nil {
+		t.Errorf("caller's field modified: %+v", fields[1])
+	}
+	if got[1].String != "AQ==" {
+		t.Errorf("converted field = %+v, want base64 string", got[1])
+	}
+
+	plain := []zap.Field{zap.String("a", "b")}
+	if got := l.encodeBinaryFields(plain); &got[0] != &plain[0] {
+		t.Error("fields without bytes should be returned unchanged")
+	}
+}
@@ -0,0 +1,43 @@
+package zap
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 控制台输出使用的标准输出和标准错误，测试时可以替换
+var (
+	consoleStdout zapcore.WriteSyncer = os.Stdout
+	consoleStderr zapcore.WriteSyncer = os.Stderr
+)
+
+// consoleCores 创建控制台输出的 core
+// 开启 ErrorToStderr 时按级别拆分为两个 core，两者的级别范围互不重叠，
+// 每条日志只会写入其中一个。
+// encoderConfig 按值传入，开启 ConsoleColor 时的修改不影响文件输出。
+// JSON 格式不着色，避免颜色控制字符破坏 JSON 的取值
+func consoleCores(cfg *Config, encoderConfig zapcore.EncoderConfig, level zap.AtomicLevel) ([]zapcore.Core, error) {
+	if cfg.ConsoleColor && cfg.ConsoleEncoding != EncodingJSON {
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	encoder, err := newEncoder(cfg.ConsoleEncoding, EncodingConsole, encoderConfig)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.ErrorToStderr {
+		return []zapcore.Core{zapcore.NewCore(encoder, consoleStdout, level)}, nil
+	}
+
+	low := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return level.Enabled(l) && l < zapcore.ErrorLevel
+	})
+	high := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return level.Enabled(l) && l >= zapcore.ErrorLevel
+	})
+	return []zapcore.Core{
+		zapcore.NewCore(encoder, consoleStdout, low),
+		zapcore.NewCore(encoder.Clone(), consoleStderr, high),
+	}, nil
+}
@@ -0,0 +1,145 @@
+package zap
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// captureConsole 将控制台输出重定向到缓冲区，返回标准输出和标准错误的内容
+func captureConsole(t *testing.T) (stdout, stderr *bytes.Buffer) {
+	t.Helper()
+
+	stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	oldOut, oldErr := consoleStdout, consoleStderr
+	consoleStdout, consoleStderr = zapcore.AddSync(stdout), zapcore.AddSync(stderr)
+	t.Cleanup(func() { consoleStdout, consoleStderr = oldOut, oldErr })
+	return stdout, stderr
+}
+
+func TestErrorToStderr(t *testing.T) {
+	tests := []struct {
+		name       string
+		toStderr   bool
+		wantStdout []string
+		wantStderr []string
+	}{
+		{"disabled", false, []string{"debug-msg", "info-msg", "warn-msg", "error-msg", "dpanic-msg"}, nil},
+		{"enabled", true, []string{"info-msg", "warn-msg"}, []string{"error-msg", "dpanic-msg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := captureConsole(t)
+			logger, err := NewLogger(&Config{
+				EnableConsole: true,
+				ConsoleLevel:  zapcore.InfoLevel,
+				ErrorToStderr: tt.toStderr,
+			})
+			if err != nil {
+				t.Fatalf("NewLogger() error = %v", err)
+			}
+			if !tt.toStderr {
+				logger.SetConsoleLevel(zapcore.DebugLevel)
+			}
+
+			logger.Debug("debug-msg")
+			logger.Info("info-msg")
+			logger.Warn("warn-msg")
+			logger.Error("error-msg")
+			logger.DPanic("dpanic-msg")
+			_ = logger.Close()
+
+			check := func(name, out string, want []string) {
+				if got := strings.Count(out, "\n"); got != len(want) {
+					t.Errorf("%s has %d lines, want %d:\n%s", name, got, len(want), out)
+				}
+				for _, msg := range want {
+					if strings.Count(out, msg) != 1 {
+						t.Errorf("%s does not contain %q exactly once:\n%s", name, msg, out)
+					}
+				}
+			}
+			check("stdout", stdout.String(), tt.wantStdout)
+			check("stderr", stderr.String(), tt.wantStderr)
+		})
+	}
+}
+
+func TestConsoleColor(t *testing.T) {
+	const colored = "\x1b[31mERROR\x1b[0m"
+
+	for _, color := range []bool{false, true} {
+		stdout, _ := captureConsole(t)
+		path := filepath.Join(t.TempDir(), "app.log")
+		logger, server := newLokiLogger(t, Config{
+			EnableConsole: true,
+			ConsoleColor:  color,
+			EnableFile:    true,
+			FilePath:      path,
+		})
+		logger.Error("boom")
+
+		lines := closeAndCollect(t, logger, server)
+		records := readFileLines(t, logger, path)
+
+		if got := strings.Contains(stdout.String(), colored); got != color {
+			t.Errorf("ConsoleColor=%v: colored level in console = %v, output %q", color, got, stdout.String())
+		}
+		if len(records) != 1 || records[0]["level"] != "error" {
+			t.Errorf("ConsoleColor=%v: file records = %v, want plain level", color, records)
+		}
+		if len(lines) != 1 || strings.Contains(lines[0].Line, "\x1b[") {
+			t.Errorf("ConsoleColor=%v: Loki lines = %+v, want no color codes", color, lines)
+		}
+	}
+}
+
+func TestSinkEncoding(t *testing.T) {
+	stdout, _ := captureConsole(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := NewLogger(&Config{
+		EnableConsole:   true,
+		ConsoleEncoding: EncodingJSON,
+		ConsoleColor:    true,
+		EnableFile:      true,
+		FileEncoding:    EncodingConsole,
+		FilePath:        path,
+	})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	logger.Info("hello", zap.String("k", "v"))
+	_ = logger.Close()
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &record); err != nil {
+		t.Fatalf("console output %q is not JSON: %v", stdout.String(), err)
+	}
+	if record["msg"] != "hello" || record["level"] != "info" || record["k"] != "v" {
+		t.Errorf("console record = %v", record)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	if line := string(data); !strings.Contains(line, "\tinfo\thello\t{\"k\": \"v\"}") {
+		t.Errorf("file output = %q, want console encoding", line)
+	}
+
+	for _, cfg := range []Config{
+		{EnableConsole: true, ConsoleEncoding: "text"},
+		{EnableFile: true, FilePath: path, FileEncoding: "logfmt"},
+	} {
+		if _, err := NewLogger(&cfg); err == nil {
+			t.Errorf("NewLogger(%+v) error = nil, want unsupported encoding", cfg)
+		}
+	}
+}
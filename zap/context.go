@@ -0,0 +1,95 @@
+package zap
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ContextExtractor 从 context 中提取需要附加到日志上的字段
+// 例如 user_id、tenant 等关联信息
+type ContextExtractor func(ctx context.Context) []zap.Field
+
+// contextFields 依次执行所有提取器，并将提取到的字段放在调用方字段之前
+// 同时附加 context 的状态：
+//   - ctx_err: context 已取消或超时时的错误
+//   - ctx_deadline_remaining: context 设置了截止时间时的剩余时间
+func (l *Logger) contextFields(ctx context.Context, fields []zap.Field) []zap.Field {
+	if ctx == nil {
+		return fields
+	}
+
+	var extracted []zap.Field
+	for _, extractor := range l.extractors {
+		extracted = append(extracted, extractor(ctx)...)
+	}
+	if err := ctx.Err(); err != nil {
+		extracted = append(extracted, zap.String("ctx_err", err.Error()))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		extracted = append(extracted, zap.Duration("ctx_deadline_remaining", time.Until(deadline)))
+	}
+	if len(extracted) == 0 {
+		return fields
+	}
+	return append(extracted, fields...)
+}
+
+// DebugCtx 记录调试级别的日志，并附加从 context 中提取的字段
+func (l *Logger) DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = l.contextFields(ctx, fields)
+	fields = l.prepareFields(zapcore.DebugLevel, fields)
+	l.Logger.Debug(msg, fields...)
+	l.forward(ctx, zapcore.DebugLevel, msg, fields)
+}
+
+// InfoCtx 记录信息级别的日志，并附加从 context 中提取的字段
+func (l *Logger) InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = l.contextFields(ctx, fields)
+	fields = l.prepareFields(zapcore.InfoLevel, fields)
+	l.Logger.Info(msg, fields...)
+	l.forward(ctx, zapcore.InfoLevel, msg, fields)
+}
+
+// WarnCtx 记录警告级别的日志，并附加从 context 中提取的字段
+func (l *Logger) WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = l.contextFields(ctx, fields)
+	fields = l.prepareFields(zapcore.WarnLevel, fields)
+	l.Logger.Warn(msg, fields...)
+	l.forward(ctx, zapcore.WarnLevel, msg, fields)
+}
+
+// ErrorCtx 记录错误级别的日志，并附加从 context 中提取的字段
+func (l *Logger) ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = l.contextFields(ctx, fields)
+	fields = l.prepareFields(zapcore.ErrorLevel, fields)
+	l.Logger.Error(msg, fields...)
+	l.forward(ctx, zapcore.ErrorLevel, msg, fields)
+}
+
+// DPanicCtx 记录 DPanic 级别的日志，并附加从 context 中提取的字段
+func (l *Logger) DPanicCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = l.contextFields(ctx, fields)
+	fields = l.prepareFields(zapcore.DPanicLevel, fields)
+	l.forward(ctx, zapcore.DPanicLevel, msg, fields)
+	l.Logger.DPanic(msg, fields...) // 开发模式下 DPanic 会 panic，所以先发送到 Loki
+}
+
+// PanicCtx 记录 Panic 级别的日志，并附加从 context 中提取的字段
+func (l *Logger) PanicCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = l.contextFields(ctx, fields)
+	fields = l.prepareFields(zapcore.PanicLevel, fields)
+	l.forward(ctx, zapcore.PanicLevel, msg, fields)
+	l.Logger.Panic(msg, fields...) // Panic 之后不会返回，所以先发送到 Loki
+}
+
+// FatalCtx 记录 Fatal 级别的日志，并附加从 context 中提取的字段
+func (l *Logger) FatalCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = l.contextFields(ctx, fields)
+	fields = l.prepareFields(zapcore.FatalLevel, fields)
+	l.forward(ctx, zapcore.FatalLevel, msg, fields)
+	l.flushBeforeExit()
+	l.Logger.Fatal(msg, fields...) // Fatal 会导致程序退出，所以先发送到 Loki
+}
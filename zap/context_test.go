@@ -0,0 +1,137 @@
+package zap
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey string
+
+func TestContextExtractors(t *testing.T) {
+	userExtractor := func(ctx context.Context) []zap.Field {
+		if v, ok := ctx.Value(ctxKey("user_id")).(string); ok {
+			return []zap.Field{zap.String("user_id", v)}
+		}
+		return nil
+	}
+	tenantExtractor := func(ctx context.Context) []zap.Field {
+		if v, ok := ctx.Value(ctxKey("tenant")).(string); ok {
+			return []zap.Field{zap.String("tenant", v)}
+		}
+		return nil
+	}
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, server := newLokiLogger(t, Config{
+		EnableFile:        true,
+		FilePath:          path,
+		ContextExtractors: []ContextExtractor{userExtractor, tenantExtractor},
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey("user_id"), "u-1")
+	ctx = context.WithValue(ctx, ctxKey("tenant"), "acme")
+	logger.InfoCtx(ctx, "with context", zap.String("op", "pay"))
+	logger.InfoCtx(context.Background(), "without context")
+
+	lines := closeAndCollect(t, logger, server)
+	records := readFileLines(t, logger, path)
+
+	if len(records) != 2 {
+		t.Fatalf("got %d file records, want 2", len(records))
+	}
+	want := map[string]string{"user_id": "u-1", "tenant": "acme", "op": "pay"}
+	for k, v := range want {
+		if records[0][k] != v {
+			t.Errorf("file record %s = %v, want %q", k, records[0][k], v)
+		}
+	}
+	if _, ok := records[1]["user_id"]; ok {
+		t.Error("file record without context has user_id")
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("got %d Loki lines, want 2", len(lines))
+	}
+	fields := lineFields(t, lines[0].Line)
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("Loki field %s = %v, want %q", k, fields[k], v)
+		}
+	}
+	if lineFields(t, lines[1].Line) != nil {
+		t.Errorf("Loki line without context has fields: %q", lines[1].Line)
+	}
+}
+
+func TestContextExtractorOrder(t *testing.T) {
+	first := func(context.Context) []zap.Field { return []zap.Field{zap.String("a", "1")} }
+	second := func(context.Context) []zap.Field { return []zap.Field{zap.String("b", "2")} }
+	logger := &Logger{extractors: []ContextExtractor{first, second}}
+
+	fields := logger.contextFields(context.Background(), []zap.Field{zap.String("c", "3")})
+	var keys []string
+	for _, f := range fields {
+		keys = append(keys, f.Key)
+	}
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("field order = %v, want [a b c]", keys)
+	}
+}
+
+func TestContextStateFields(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	pending, cancelPending := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelPending()
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		wantErr      string
+		wantDeadline bool
+		wantPositive bool
+	}{
+		{"background", context.Background(), "", false, false},
+		{"canceled", canceled, context.Canceled.Error(), false, false},
+		{"deadline exceeded", expired, context.DeadlineExceeded.Error(), true, false},
+		{"pending deadline", pending, "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			logger, server := newLokiLogger(t, Config{EnableFile: true, FilePath: path})
+			logger.WarnCtx(tt.ctx, "request finished")
+
+			lines := closeAndCollect(t, logger, server)
+			records := readFileLines(t, logger, path)
+			if len(records) != 1 || len(lines) != 1 {
+				t.Fatalf("got %d file records and %d Loki lines, want 1 each", len(records), len(lines))
+			}
+			outputs := map[string]map[string]interface{}{
+				"file": records[0],
+				"loki": lineFields(t, lines[0].Line),
+			}
+
+			for output, fields := range outputs {
+				if got, _ := fields["ctx_err"].(string); got != tt.wantErr {
+					t.Errorf("%s ctx_err = %q, want %q", output, got, tt.wantErr)
+				}
+				remaining, ok := fields["ctx_deadline_remaining"]
+				if ok != tt.wantDeadline {
+					t.Errorf("%s ctx_deadline_remaining present = %v, want %v", output, ok, tt.wantDeadline)
+					continue
+				}
+				if n, isNum := remaining.(float64); ok && isNum && (n > 0) != tt.wantPositive {
+					t.Errorf("%s ctx_deadline_remaining = %v, want positive = %v", output, n, tt.wantPositive)
+				}
+			}
+		})
+	}
+}
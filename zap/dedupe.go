@@ -0,0 +1,152 @@
+package zap
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RepeatedKey 是去重汇总日志中重复条数的字段名
+const RepeatedKey = "repeated"
+
+// dedupeKey 是去重的键，级别和消息都相同的日志视为重复
+type dedupeKey struct {
+	level zapcore.Level
+	msg   string
+}
+
+// dedupeWindow 是一条消息的去重窗口
+type dedupeWindow struct {
+	// n 是窗口内被合并的重复条数，不包括窗口开始时输出的第一条
+	n uint64
+	// repeat 在窗口结束、且有重复时输出汇总日志，取最近一条重复日志的上下文
+	repeat func(n uint64)
+	// timer 在窗口结束时触发
+	timer *time.Timer
+}
+
+// deduper 合并去重窗口内级别和消息都相同的日志
+// 每个窗口的第一条日志立即输出，之后的重复日志只计数，
+// 窗口结束时如果有重复，输出一条带 repeated 字段的汇总日志。
+// 高于 Error 的日志之后程序会 panic 或退出，不参与去重
+type deduper struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[dedupeKey]*dedupeWindow
+}
+
+// newDeduper 创建去重器，window 小于等于 0 时返回 nil，表示不去重
+func newDeduper(window time.Duration) *deduper {
+	if window <= 0 {
+		return nil
+	}
+	return &deduper{window: window, windows: make(map[dedupeKey]*dedupeWindow)}
+}
+
+// allow 判断一条日志是否输出
+// 日志被合并时返回 false，repeat 会在窗口结束时以合并的条数被调用
+func (d *deduper) allow(level zapcore.Level, msg string, repeat func(n uint64)) bool {
+	if d == nil || level > zapcore.ErrorLevel {
+		return true
+	}
+
+	key := dedupeKey{level: level, msg: msg}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if w, ok := d.windows[key]; ok {
+		w.n++
+		w.repeat = repeat
+		return false
+	}
+	w := &dedupeWindow{}
+	w.timer = time.AfterFunc(d.window, func() { d.close(key, w) })
+	d.windows[key] = w
+	return true
+}
+
+// close 结束一个去重窗口，有重复时输出汇总日志
+func (d *deduper) close(key dedupeKey, w *dedupeWindow) {
+	d.mu.Lock()
+	if d.windows[key] != w {
+		// 已被 flush 提前结束
+		d.mu.Unlock()
+		return
+	}
+	delete(d.windows, key)
+	n, repeat := w.n, w.repeat
+	d.mu.Unlock()
+
+	if n > 0 {
+		repeat(n)
+	}
+}
+
+// flush 立即结束所有去重窗口并输出汇总日志，用于关闭日志器之前
+func (d *deduper) flush() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	windows := d.windows
+	d.windows = make(map[dedupeKey]*dedupeWindow)
+	d.mu.Unlock()
+
+	for _, w := range windows {
+		w.timer.Stop()
+		if w.n > 0 {
+			w.repeat(w.n)
+		}
+	}
+}
+
+// dedupeCore 用 deduper 合并重复日志的 core
+type dedupeCore struct {
+	zapcore.Core
+	deduper *deduper
+}
+
+// With 附加字段，返回的 core 与原 core 共享同一个去重器
+func (c *dedupeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupeCore{Core: c.Core.With(fields), deduper: c.deduper}
+}
+
+// Check 合并重复日志，未启用的级别不计数
+// 汇总日志直接写入被包装的 core，不再参与去重
+func (c *dedupeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	inner := c.Core
+	keep := c.deduper.allow(ent.Level, ent.Message, func(n uint64) {
+		summary := ent
+		summary.Time = time.Now()
+		if checked := inner.Check(summary, nil); checked != nil {
+			checked.Write(zap.Uint64(RepeatedKey, n))
+		}
+	})
+	if !keep {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// dedupeLoki 对一条 Loki 日志去重，未配置 DedupeWindow 时总是保留
+// Loki 的日志不经过 zap 的 core，因此使用单独的去重器，汇总日志直接推送
+func (l *Logger) dedupeLoki(level zapcore.Level, msg string, fields []zap.Field) bool {
+	if l.lokiDeduper == nil {
+		return true
+	}
+	return l.lokiDeduper.allow(level, msg, func(n uint64) {
+		summary := make([]zap.Field, 0, len(fields)+1)
+		summary = append(summary, fields...)
+		summary = append(summary, zap.Uint64(RepeatedKey, n))
+		l.pushLoki(context.Background(), level, msg, summary)
+	})
+}
@@ -0,0 +1,110 @@
+package zap
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDeduperWindowBoundary(t *testing.T) {
+	d := newDeduper(50 * time.Millisecond)
+
+	var mu sync.Mutex
+	var repeats []uint64
+	repeat := func(n uint64) {
+		mu.Lock()
+		repeats = append(repeats, n)
+		mu.Unlock()
+	}
+
+	kept := 0
+	for i := 0; i < 5; i++ {
+		if d.allow(zapcore.ErrorLevel, "boom", repeat) {
+			kept++
+		}
+	}
+	// 第一个窗口结束，之后的日志开启新窗口
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if d.allow(zapcore.ErrorLevel, "boom", repeat) {
+			kept++
+		}
+	}
+	// 不同级别或消息不算重复，高于 Error 的日志不参与去重
+	for _, allowed := range []bool{
+		d.allow(zapcore.WarnLevel, "boom", repeat),
+		d.allow(zapcore.ErrorLevel, "other", repeat),
+		d.allow(zapcore.DPanicLevel, "boom", repeat),
+		d.allow(zapcore.DPanicLevel, "boom", repeat),
+	} {
+		if !allowed {
+			t.Error("allow() = false for a non-repeated or bypassed entry")
+		}
+	}
+	d.flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if kept != 2 {
+		t.Errorf("kept %d entries, want the first of each window", kept)
+	}
+	if len(repeats) != 2 || repeats[0] != 4 || repeats[1] != 2 {
+		t.Errorf("repeats = %v, want [4 2]", repeats)
+	}
+}
+
+func TestDedupeWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, server := newLokiLogger(t, Config{EnableFile: true, FilePath: path, DedupeWindow: 100 * time.Millisecond})
+
+	for i := 0; i < 5; i++ {
+		logger.Error("flood", zap.Int("i", i))
+	}
+	time.Sleep(200 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		logger.Error("flood", zap.Int("i", i))
+	}
+	logger.Info("other")
+
+	lines := closeAndCollect(t, logger, server)
+	records := readFileLines(t, logger, path)
+
+	// 每个窗口输出第一条和一条汇总日志，最后一个窗口在关闭时汇总
+	want := []float64{0, 4, 0, 2}
+	var fileRepeats, lokiRepeats []float64
+	for _, r := range records {
+		if r["msg"] != "flood" {
+			continue
+		}
+		n, _ := r[RepeatedKey].(float64)
+		fileRepeats = append(fileRepeats, n)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line.Line, "flood") {
+			continue
+		}
+		n, _ := lineFields(t, line.Line)[RepeatedKey].(float64)
+		lokiRepeats = append(lokiRepeats, n)
+	}
+
+	for name, got := range map[string][]float64{"file": fileRepeats, "loki": lokiRepeats} {
+		if len(got) != len(want) {
+			t.Errorf("%s repeated counts = %v, want %v", name, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s repeated counts = %v, want %v", name, got, want)
+				break
+			}
+		}
+	}
+	if len(records) != 5 || len(lines) != 5 {
+		t.Errorf("got %d file records and %d Loki lines, want 5 each", len(records), len(lines))
+	}
+}
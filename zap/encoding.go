@@ -0,0 +1,29 @@
+package zap
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// EncodingConsole 是便于阅读的控制台格式，控制台输出的默认值
+	EncodingConsole = "console"
+	// EncodingJSON 是每行一个 JSON 对象的格式，文件输出的默认值
+	EncodingJSON = "json"
+)
+
+// newEncoder 按编码格式创建编码器，encoding 为空时使用 defaultEncoding
+func newEncoder(encoding, defaultEncoding string, encoderConfig zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	if encoding == "" {
+		encoding = defaultEncoding
+	}
+	switch encoding {
+	case EncodingConsole:
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	case EncodingJSON:
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	default:
+		return nil, fmt.Errorf("不支持的编码格式 %q，可选值为 %q 或 %q", encoding, EncodingConsole, EncodingJSON)
+	}
+}
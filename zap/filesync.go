@@ -0,0 +1,95 @@
+package zap
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// FileSyncBuffered 只在 Sync 和 Close 时同步到磁盘，默认值
+	// 每条日志写入后已经在操作系统的页缓存中，进程崩溃不会丢失，但机器断电或内核崩溃时可能丢失
+	FileSyncBuffered = "buffered"
+	// FileSyncEveryN 每写入 FileSyncEvery 条日志同步一次
+	FileSyncEveryN = "everyN"
+	// FileSyncAlways 每条日志写入后立即同步
+	FileSyncAlways = "always"
+)
+
+// defaultFileSyncEvery 是 FileSyncEveryN 模式下默认的同步间隔条数
+const defaultFileSyncEvery = 100
+
+// fileSyncer 是按 FileSyncMode 同步文件的 WriteSyncer
+// 每次 Write 对应一条完整的日志，写入和计数在同一把锁中完成
+type fileSyncer struct {
+	mu sync.Mutex
+	w  io.Writer
+	// sync 将已写入的内容同步到磁盘
+	sync func() error
+	// every 是每写入多少条同步一次，为 0 时只在 Sync 时同步
+	every int
+	// pending 是上次同步以来写入的条数
+	pending int
+}
+
+// newFileSyncer 按同步方式创建写入 w 的 WriteSyncer
+func newFileSyncer(w io.Writer, sync func() error, mode string, every int) *fileSyncer {
+	s := &fileSyncer{w: w, sync: sync}
+	switch mode {
+	case FileSyncAlways:
+		s.every = 1
+	case FileSyncEveryN:
+		s.every = every
+		if s.every <= 0 {
+			s.every = defaultFileSyncEvery
+		}
+	}
+	return s
+}
+
+// Write 写入一条日志，达到同步间隔时同步到磁盘
+func (s *fileSyncer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.w.Write(p)
+	if err != nil || s.every == 0 {
+		return n, err
+	}
+	s.pending++
+	if s.pending < s.every {
+		return n, nil
+	}
+	s.pending = 0
+	return n, s.sync()
+}
+
+// Sync 立即同步到磁盘
+func (s *fileSyncer) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = 0
+	return s.sync()
+}
+
+// syncPath 同步指定路径的文件
+// lumberjack 不暴露当前打开的文件，这里重新打开同一个文件同步，效果与同步原文件相同；
+// 文件还不存在说明没有写入过日志，无需同步
+func syncPath(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// validFileSyncMode 判断同步方式是否受支持，空字符串表示使用默认值
+func validFileSyncMode(mode string) bool {
+	return mode == "" || mode == FileSyncBuffered || mode == FileSyncEveryN || mode == FileSyncAlways
+}
@@ -0,0 +1,67 @@
+package zap
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSyncer(t *testing.T) {
+	tests := []struct {
+		mode  string
+		every int
+		want  int
+	}{
+		{"", 0, 0},
+		{FileSyncBuffered, 0, 0},
+		{FileSyncEveryN, 3, 3},
+		{FileSyncEveryN, 0, 0}, // 默认每 100 条同步一次
+		{FileSyncAlways, 0, 10},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		syncs := 0
+		s := newFileSyncer(&buf, func() error { syncs++; return nil }, tt.mode, tt.every)
+		for i := 0; i < 10; i++ {
+			_, _ = s.Write([]byte("line\n"))
+		}
+		if syncs != tt.want {
+			t.Errorf("mode %q every %d: %d syncs after 10 writes, want %d", tt.mode, tt.every, syncs, tt.want)
+		}
+		if buf.Len() != 50 {
+			t.Errorf("mode %q: wrote %d bytes, want 50", tt.mode, buf.Len())
+		}
+
+		_ = s.Sync()
+		if syncs != tt.want+1 {
+			t.Errorf("mode %q: Sync() did not sync", tt.mode)
+		}
+	}
+}
+
+func TestFileSyncModeOutput(t *testing.T) {
+	for _, mode := range []string{FileSyncBuffered, FileSyncEveryN, FileSyncAlways} {
+		path := filepath.Join(t.TempDir(), "app.log")
+		logger, err := NewLogger(&Config{EnableFile: true, FilePath: path, FileSyncMode: mode, FileSyncEvery: 2})
+		if err != nil {
+			t.Fatalf("NewLogger(%s) error = %v", mode, err)
+		}
+		for i := 0; i < 3; i++ {
+			logger.Info("hello")
+		}
+		if records := readFileLines(t, logger, path); len(records) != 3 {
+			t.Errorf("mode %s: %d records, want 3", mode, len(records))
+		}
+	}
+
+	if err := (&Config{EnableFile: true, FilePath: "x.log", FileSyncMode: "sometimes"}).Validate(); err == nil {
+		t.Error("Validate() error = nil, want unsupported FileSyncMode")
+	}
+}
+
+func TestSyncPathMissingFile(t *testing.T) {
+	if err := syncPath(filepath.Join(t.TempDir(), "missing.log")); err != nil {
+		t.Errorf("syncPath() error = %v, want nil for a file that was never written", err)
+	}
+}
@@ -0,0 +1,209 @@
+package zap
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TraceBucketLabel 是 trace ID 分桶在 Loki 中的标签名
+const TraceBucketLabel = "trace_bucket"
+
+// forward 将一条日志转发到 Loki
+// 依次采样、去重、提取 trace ID、执行转换器、格式化消息，并在存在日志作用域时暂存到作用域中
+func (l *Logger) forward(ctx context.Context, level zapcore.Level, msg string, fields []zap.Field) {
+	if l.lokiClient == nil || level < l.lokiClient.MinLevel() {
+		return
+	}
+	keep, sampled := l.sampleLoki(level, msg)
+	if !keep {
+		return
+	}
+	if len(sampled) > 0 {
+		fields = append(fields[:len(fields):len(fields)], sampled...)
+	}
+	if !l.dedupeLoki(level, msg, fields) {
+		return
+	}
+	l.pushLoki(ctx, level, msg, fields)
+}
+
+// pushLoki 将一条已经通过采样和去重的日志推送到 Loki
+func (l *Logger) pushLoki(ctx context.Context, level zapcore.Level, msg string, fields []zap.Field) {
+	// 复制字段，避免转换器修改调用方的切片
+	entryFields := make([]zap.Field, 0, len(l.fields)+len(fields))
+	entryFields = append(entryFields, l.fields...)
+	entryFields = append(entryFields, fields...)
+	// 最先脱敏，敏感字段不会被提取到标签或结构化元数据中
+	entryFields = l.redactor.redact(entryFields)
+
+	e := &Entry{
+		Level:   level,
+		Time:    time.Now(),
+		Message: msg,
+		Fields:  entryFields,
+	}
+	l.extractTraceID(e)
+	l.extractMetadata(e)
+	l.extractErrors(e)
+	l.extractLabels(e)
+	l.loggerField(e)
+	e.Labels = l.loggerLabels(e.Labels)
+	for _, transform := range l.config.Transformers {
+		if e = transform(e); e == nil {
+			return
+		}
+	}
+
+	entry := pkg.LogEntry{
+		Timestamp: e.Time.UnixNano(),
+		Message:   formatMessage(e.Message, e.Fields),
+		Level:     e.Level,
+		Labels:    e.Labels,
+		Metadata:  e.Metadata,
+	}
+
+	// Fatal 之后程序退出，日志作用域不会再结束，因此不暂存到作用域中
+	if scope := scopeFromContext(ctx); scope != nil && level < zapcore.FatalLevel && scope.add(entry) {
+		return
+	}
+	// 只有日志器关闭之后才会出现缓冲错误，发送错误由 OnSendError 或 ErrorLogger 报告
+	if l.config.LokiConfig.SyncDelivery {
+		_ = l.lokiClient.PushBatch([]pkg.LogEntry{entry})
+		return
+	}
+	_ = l.lokiClient.Push(entry)
+}
+
+// extractTraceID 将 trace ID 字段移到结构化元数据中，并按配置添加 trace_bucket 标签
+func (l *Logger) extractTraceID(e *Entry) {
+	key := l.config.LokiConfig.TraceIDField
+	if key == "" {
+		return
+	}
+
+	for i, field := range e.Fields {
+		if field.Key != key {
+			continue
+		}
+
+		traceID := fieldString(field)
+		if traceID == "" {
+			return
+		}
+
+		e.setMetadata(key, traceID)
+		if buckets := l.config.LokiConfig.TraceBuckets; buckets > 0 {
+			e.setLabel(TraceBucketLabel, traceBucket(traceID, buckets))
+		}
+		e.Fields = append(e.Fields[:i:i], e.Fields[i+1:]...)
+		return
+	}
+}
+
+// extractMetadata 将 StructuredMetadataKeys 中的字段移到结构化元数据中
+func (l *Logger) extractMetadata(e *Entry) {
+	keys := l.config.LokiConfig.StructuredMetadataKeys
+	if len(keys) == 0 {
+		return
+	}
+
+	fields := e.Fields[:0:0]
+	for _, field := range e.Fields {
+		if !containsString(keys, field.Key) {
+			fields = append(fields, field)
+			continue
+		}
+		e.setMetadata(field.Key, fieldString(field))
+	}
+	e.Fields = fields
+}
+
+// extractErrors 在开启 ErrorMetadata 时将错误字段移到结构化元数据中，取值包含堆栈等详细信息
+func (l *Logger) extractErrors(e *Entry) {
+	if !l.config.LokiConfig.ErrorMetadata {
+		return
+	}
+
+	fields := e.Fields[:0:0]
+	for _, field := range e.Fields {
+		err, ok := field.Interface.(error)
+		if field.Type != zapcore.ErrorType || !ok {
+			fields = append(fields, field)
+			continue
+		}
+		e.setMetadata(field.Key, fmt.Sprintf("%+v", err))
+	}
+	e.Fields = fields
+}
+
+// extractLabels 将 LabelKeys 中的字段移到流标签中
+func (l *Logger) extractLabels(e *Entry) {
+	keys := l.config.LokiConfig.LabelKeys
+	if len(keys) == 0 {
+		return
+	}
+
+	fields := e.Fields[:0:0]
+	for _, field := range e.Fields {
+		if !containsString(keys, field.Key) {
+			fields = append(fields, field)
+			continue
+		}
+		e.setLabel(field.Key, fieldString(field))
+	}
+	e.Fields = fields
+}
+
+// setLabel 设置一个附加的流标签
+func (e *Entry) setLabel(name, value string) {
+	if e.Labels == nil {
+		e.Labels = make(map[string]string)
+	}
+	e.Labels[name] = value
+}
+
+// setMetadata 设置一项结构化元数据
+func (e *Entry) setMetadata(key, value string) {
+	if e.Metadata == nil {
+		e.Metadata = make(map[string]string)
+	}
+	e.Metadata[key] = value
+}
+
+// containsString 判断切片中是否包含指定的字符串
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldString 返回字段值的字符串形式
+func fieldString(field zap.Field) string {
+	switch field.Type {
+	case zapcore.StringType:
+		return field.String
+	case zapcore.StringerType:
+		return field.Interface.(fmt.Stringer).String()
+	default:
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+		return fmt.Sprint(enc.Fields[field.Key])
+	}
+}
+
+// traceBucket 计算 trace ID 所属的桶，相同的 trace ID 总是落在同一个桶中
+func traceBucket(traceID string, buckets int) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(traceID))
+	return strconv.FormatUint(uint64(h.Sum32()%uint32(buckets)), 10)
+}
@@ -0,0 +1,267 @@
+package zap
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap"
+)
+
+func TestTraceBucketStableAndBounded(t *testing.T) {
+	const buckets = 16
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		bucket := traceBucket(id, buckets)
+		if again := traceBucket(id, buckets); again != bucket {
+			t.Fatalf("traceBucket(%q) not stable: %s then %s", id, bucket, again)
+		}
+		n, err := strconv.Atoi(bucket)
+		if err != nil || n < 0 || n >= buckets {
+			t.Fatalf("traceBucket(%q) = %q, want 0..%d", id, bucket, buckets-1)
+		}
+		seen[bucket] = true
+	}
+	if len(seen) > buckets {
+		t.Errorf("got %d distinct buckets, want at most %d", len(seen), buckets)
+	}
+}
+
+func TestTraceIDSentAsMetadata(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{
+		LokiConfig: LokiConfig{TraceIDField: "trace_id", TraceBuckets: 8},
+	})
+
+	logger.Info("first", zap.String("trace_id", "abc123"), zap.String("op", "pay"))
+	logger.Info("second", zap.String("trace_id", "abc123"))
+	logger.Info("untraced")
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+
+	bucket := traceBucket("abc123", 8)
+	for _, line := range lines[:2] {
+		if line.Metadata["trace_id"] != "abc123" {
+			t.Errorf("line %q metadata = %v, want trace_id", line.Line, line.Metadata)
+		}
+		if line.Labels[TraceBucketLabel] != bucket {
+			t.Errorf("line %q trace_bucket = %q, want %q", line.Line, line.Labels[TraceBucketLabel], bucket)
+		}
+		if _, ok := lineFields(t, line.Line)["trace_id"]; ok {
+			t.Errorf("line %q still contains the trace_id field", line.Line)
+		}
+	}
+	if lines[0].Line != `first {"op":"pay"}` {
+		t.Errorf("line = %q, want other fields kept", lines[0].Line)
+	}
+
+	if _, ok := lines[2].Labels[TraceBucketLabel]; ok || lines[2].Metadata != nil {
+		t.Errorf("untraced line has trace data: %+v", lines[2])
+	}
+}
+
+func TestStructuredMetadataKeys(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{
+		LokiConfig: LokiConfig{
+			TraceIDField:           "trace_id",
+			StructuredMetadataKeys: []string{"user_id", "status"},
+		},
+	})
+
+	logger.Info("request",
+		zap.String("trace_id", "abc123"),
+		zap.Int("user_id", 42),
+		zap.Int("status", 200),
+		zap.String("path", "/pay"),
+	)
+	logger.Info("plain", zap.String("path", "/health"))
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+
+	want := map[string]string{"trace_id": "abc123", "user_id": "42", "status": "200"}
+	for k, v := range want {
+		if lines[0].Metadata[k] != v {
+			t.Errorf("metadata[%q] = %q, want %q (metadata = %v)", k, lines[0].Metadata[k], v, lines[0].Metadata)
+		}
+	}
+	if lines[0].Line != `request {"path":"/pay"}` {
+		t.Errorf("line = %q, want metadata fields removed", lines[0].Line)
+	}
+	if lines[1].Metadata != nil {
+		t.Errorf("plain line metadata = %v, want none", lines[1].Metadata)
+	}
+}
+
+func TestLabelKeys(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{
+		LokiConfig: LokiConfig{
+			Labels:       map[string]string{"app": "svc"},
+			LabelKeys:    []string{"tenant"},
+			TraceIDField: "trace_id",
+			TraceBuckets: 4,
+		},
+	})
+
+	logger.Info("a", zap.String("tenant", "acme"), zap.String("op", "pay"))
+	logger.Info("b", zap.String("tenant", "globex"), zap.String("trace_id", "abc123"))
+	logger.Info("c")
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+
+	byLine := make(map[string]lokiLine)
+	for _, line := range lines {
+		byLine[line.Line] = line
+	}
+	a := byLine[`a {"op":"pay"}`]
+	if a.Labels["tenant"] != "acme" || a.Labels["app"] != "svc" {
+		t.Errorf("a labels = %v, want tenant=acme merged with app=svc", a.Labels)
+	}
+	b := byLine["b"]
+	if b.Labels["tenant"] != "globex" || b.Labels[TraceBucketLabel] != traceBucket("abc123", 4) {
+		t.Errorf("b labels = %v, want tenant and trace_bucket", b.Labels)
+	}
+	if _, ok := byLine["c"].Labels["tenant"]; ok {
+		t.Errorf("c labels = %v, want no tenant", byLine["c"].Labels)
+	}
+}
+
+func TestSeverityPreserved(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{})
+	hook := &fatalHook{server: server}
+	logger.Logger = logger.Logger.WithOptions(zap.WithFatalHook(hook))
+
+	logger.Error("error")
+	logger.DPanic("dpanic")
+	func() {
+		defer func() { _ = recover() }()
+		logger.Panic("panic")
+	}()
+	logger.Fatal("fatal")
+
+	lines := closeAndCollect(t, logger, server)
+	want := []string{"error", "dpanic", "panic", "fatal"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(lines), len(want), lines)
+	}
+	for i, line := range lines {
+		if line.Line != want[i] || line.Labels["level"] != want[i] {
+			t.Errorf("line %d = %q with level %q, want %q", i, line.Line, line.Labels["level"], want[i])
+		}
+	}
+}
+
+// stackError 是在 %+v 时输出堆栈的错误，与 github.com/pkg/errors 的错误类似
+type stackError struct{ msg string }
+
+func (e stackError) Error() string { return e.msg }
+
+func (e stackError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\nmain.handler\n\t/app/main.go:42", e.msg)
+		return
+	}
+	fmt.Fprint(s, e.msg)
+}
+
+func TestErrorFields(t *testing.T) {
+	const verbose = "boom\nmain.handler\n\t/app/main.go:42"
+
+	t.Run("in message", func(t *testing.T) {
+		logger, server := newLokiLogger(t, Config{})
+		logger.Error("failed", zap.Error(stackError{"boom"}))
+
+		lines := closeAndCollect(t, logger, server)
+		if len(lines) != 1 {
+			t.Fatalf("got %d lines, want 1", len(lines))
+		}
+		fields := lineFields(t, lines[0].Line)
+		if fields["error"] != "boom" || fields["errorVerbose"] != verbose {
+			t.Errorf("line = %q, want error and errorVerbose with the stack", lines[0].Line)
+		}
+		if lines[0].Metadata != nil {
+			t.Errorf("metadata = %v, want none", lines[0].Metadata)
+		}
+	})
+
+	t.Run("as metadata", func(t *testing.T) {
+		logger, server := newLokiLogger(t, Config{LokiConfig: LokiConfig{ErrorMetadata: true}})
+		logger.Error("failed",
+			zap.Error(stackError{"boom"}),
+			zap.NamedError("cause", fmt.Errorf("timeout")),
+			zap.String("op", "pay"),
+		)
+
+		lines := closeAndCollect(t, logger, server)
+		if len(lines) != 1 {
+			t.Fatalf("got %d lines, want 1", len(lines))
+		}
+		if lines[0].Line != `failed {"op":"pay"}` {
+			t.Errorf("line = %q, want error fields removed", lines[0].Line)
+		}
+		want := map[string]string{"error": verbose, "cause": "timeout"}
+		for k, v := range want {
+			if lines[0].Metadata[k] != v {
+				t.Errorf("metadata[%q] = %q, want %q", k, lines[0].Metadata[k], v)
+			}
+		}
+		if _, ok := lines[0].Labels["error"]; ok {
+			t.Errorf("labels = %v, want error not sent as a label", lines[0].Labels)
+		}
+	})
+}
+
+func TestSyncDelivery(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{LokiConfig: LokiConfig{SyncDelivery: true, BatchSize: 100}})
+	defer logger.Close()
+
+	logger.Info("delivered")
+	// 不等待刷新，Info 返回时日志已经送达
+	if lines := server.Lines(); len(lines) != 1 || lines[0].Line != "delivered" {
+		t.Errorf("lines = %+v, want the entry delivered synchronously", lines)
+	}
+	if err := logger.LokiLastError(); err != nil {
+		t.Errorf("LokiLastError() = %v, want nil", err)
+	}
+}
+
+func TestSyncDeliveryFailure(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	var failed []pkg.LogEntry
+	logger, err := NewLogger(&Config{EnableLoki: true, SuppressWarnings: true, LokiConfig: LokiConfig{
+		URL:          failing.URL,
+		SyncDelivery: true,
+		OnSendError:  func(entries []pkg.LogEntry, err error) { failed = append(failed, entries...) },
+	}})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	if err := logger.LokiLastError(); err != nil {
+		t.Errorf("LokiLastError() before logging = %v, want nil", err)
+	}
+	logger.Error("lost")
+	if err := logger.LokiLastError(); err == nil {
+		t.Error("LokiLastError() = nil, want the send error")
+	}
+	if len(failed) != 1 || failed[0].Message != "lost" {
+		t.Errorf("OnSendError entries = %+v, want the failed entry", failed)
+	}
+}
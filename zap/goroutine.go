@@ -0,0 +1,26 @@
+package zap
+
+import (
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// goroutineInfo 为达到 GoroutineInfoLevel 的日志附加 goroutine 信息
+// 总是附加 goroutine_count，配置了 GoroutineStackSize 时还会附加截断后的当前 goroutine 堆栈
+// 这些字段在格式化之前加入，因此会同时出现在控制台、文件和 Loki 中
+func (l *Logger) goroutineInfo(level zapcore.Level, fields []zap.Field) []zap.Field {
+	if !l.config.EnableGoroutineInfo || level < l.config.GoroutineInfoLevel {
+		return fields
+	}
+
+	// 限制容量，避免修改调用方传入的切片
+	fields = append(fields[:len(fields):len(fields)], zap.Int("goroutine_count", runtime.NumGoroutine()))
+	if size := l.config.GoroutineStackSize; size > 0 {
+		buf := make([]byte, size)
+		n := runtime.Stack(buf, false)
+		fields = append(fields, zap.ByteString("goroutine_stack", buf[:n]))
+	}
+	return fields
+}
@@ -0,0 +1,63 @@
+package zap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGoroutineInfoLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, server := newLokiLogger(t, Config{
+		EnableFile:          true,
+		FilePath:            path,
+		EnableGoroutineInfo: true,
+		GoroutineInfoLevel:  zapcore.ErrorLevel,
+		GoroutineStackSize:  256,
+	})
+
+	logger.Info("below level")
+	logger.Warn("below level")
+	logger.Error("at level")
+
+	lines := closeAndCollect(t, logger, server)
+	records := readFileLines(t, logger, path)
+	if len(records) != 3 || len(lines) != 3 {
+		t.Fatalf("got %d file records and %d Loki lines, want 3 each", len(records), len(lines))
+	}
+
+	for i, record := range records {
+		_, hasCount := record["goroutine_count"]
+		_, hasStack := record["goroutine_stack"]
+		wantInfo := record["level"] == "error"
+		if hasCount != wantInfo || hasStack != wantInfo {
+			t.Errorf("file record %d (%v): goroutine_count %v, goroutine_stack %v, want %v",
+				i, record["level"], hasCount, hasStack, wantInfo)
+		}
+		if stack, ok := record["goroutine_stack"].(string); ok && len(stack) > 256 {
+			t.Errorf("goroutine_stack has %d bytes, want at most 256", len(stack))
+		}
+		if n, ok := record["goroutine_count"].(float64); ok && n < 1 {
+			t.Errorf("goroutine_count = %v, want >= 1", n)
+		}
+	}
+
+	for _, line := range lines {
+		fields := lineFields(t, line.Line)
+		_, hasCount := fields["goroutine_count"]
+		if want := line.Labels["level"] == "error"; hasCount != want {
+			t.Errorf("Loki line %q: goroutine_count present %v, want %v", line.Line, hasCount, want)
+		}
+	}
+}
+
+func TestGoroutineInfoDisabled(t *testing.T) {
+	logger, path := newFileLogger(t, Config{GoroutineInfoLevel: zapcore.DebugLevel})
+	logger.Error("disabled")
+
+	records := readFileLines(t, logger, path)
+	if _, ok := records[0]["goroutine_count"]; ok {
+		t.Error("goroutine_count present although EnableGoroutineInfo is false")
+	}
+}
@@ -0,0 +1,167 @@
+package zap
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// defaultLokiStaleAfter 是未配置 LokiStaleAfter 时 Loki 发送停滞的判定时间
+const defaultLokiStaleAfter = time.Minute
+
+// HealthStatus 表示健康状态
+type HealthStatus string
+
+const (
+	// HealthOK 表示组件工作正常
+	HealthOK HealthStatus = "ok"
+	// HealthDegraded 表示组件可用但存在异常，例如最近一次发送失败
+	HealthDegraded HealthStatus = "degraded"
+	// HealthDown 表示组件不可用
+	HealthDown HealthStatus = "down"
+)
+
+// ComponentHealth 描述单个组件的健康状态
+type ComponentHealth struct {
+	// Status 组件状态
+	Status HealthStatus `json:"status"`
+	// Detail 状态说明
+	Detail string `json:"detail,omitempty"`
+}
+
+// HealthReport 汇总日志器各个子系统的健康状态
+type HealthReport struct {
+	// Status 总体状态，取所有组件中最差的状态
+	Status HealthStatus `json:"status"`
+	// Components 各组件的状态，未启用的组件不会出现
+	Components map[string]ComponentHealth `json:"components"`
+	// CheckedAt 检查时间
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Health 返回日志器的健康状态汇总
+// 只读取已缓存的状态，不会发起网络请求，适合在 /healthz 中频繁调用
+// 包含以下组件：
+//   - file: 日志文件是否可写
+//   - loki: 最近一次发送是否成功，以及有积压时最近一次成功是否足够近
+//   - buffer: 缓冲区积压是否在容量范围内
+//   - worker: 后台工作协程是否在运行
+func (l *Logger) Health() HealthReport {
+	report := HealthReport{
+		Status:     HealthOK,
+		Components: make(map[string]ComponentHealth),
+		CheckedAt:  time.Now(),
+	}
+
+	if l.fileLogger != nil {
+		report.add("file", l.fileHealth())
+	}
+
+	if l.lokiClient != nil {
+		report.add("loki", l.lokiHealth())
+		report.add("buffer", l.bufferHealth())
+		report.add("worker", l.workerHealth())
+	}
+
+	return report
+}
+
+// add 记录组件状态，并在组件状态更差时降低总体状态
+func (r *HealthReport) add(name string, h ComponentHealth) {
+	r.Components[name] = h
+	if healthRank(h.Status) > healthRank(r.Status) {
+		r.Status = h.Status
+	}
+}
+
+// healthRank 返回状态的严重程度，数值越大越严重
+func healthRank(s HealthStatus) int {
+	switch s {
+	case HealthDegraded:
+		return 1
+	case HealthDown:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// fileHealth 检查日志文件是否可以以追加方式打开
+func (l *Logger) fileHealth() ComponentHealth {
+	filename := l.fileLogger.filename()
+	if filename == "" {
+		return ComponentHealth{Status: HealthOK, Detail: "使用 lumberjack 默认路径"}
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return ComponentHealth{Status: HealthDown, Detail: fmt.Sprintf("日志文件不可写: %v", err)}
+	}
+	_ = f.Close()
+	return ComponentHealth{Status: HealthOK}
+}
+
+// lokiHealth 根据最近一次发送结果判断 Loki 是否可用
+// 最近一次发送失败时视为异常；
+// 缓冲区中有待发送的日志、但距最近一次发送成功（从未成功时为客户端启动）已超过 LokiStaleAfter 时，
+// 说明日志长时间没有送达，同样视为异常。空闲时没有发送不影响健康状态
+func (l *Logger) lokiHealth() ComponentHealth {
+	lastSuccess := l.lokiClient.LastSuccess()
+	lastFailure := l.lokiClient.LastFailure()
+
+	if !lastFailure.IsZero() && lastFailure.After(lastSuccess) {
+		return ComponentHealth{
+			Status: HealthDegraded,
+			Detail: fmt.Sprintf("最近一次发送失败于 %s", lastFailure.Format(time.RFC3339)),
+		}
+	}
+
+	staleAfter := l.config.LokiStaleAfter
+	if staleAfter <= 0 {
+		staleAfter = defaultLokiStaleAfter
+	}
+	since := lastSuccess
+	if since.IsZero() {
+		since = time.Now().Add(-l.lokiClient.Stats().Uptime)
+	}
+	if l.lokiClient.BufferLen() > 0 && time.Since(since) > staleAfter {
+		return ComponentHealth{
+			Status: HealthDegraded,
+			Detail: fmt.Sprintf("有待发送的日志，但超过 %s 没有发送成功", staleAfter),
+		}
+	}
+
+	if lastSuccess.IsZero() {
+		return ComponentHealth{Status: HealthOK, Detail: "尚未发送过日志"}
+	}
+	return ComponentHealth{
+		Status: HealthOK,
+		Detail: fmt.Sprintf("最近一次发送成功于 %s", lastSuccess.Format(time.RFC3339)),
+	}
+}
+
+// bufferHealth 检查缓冲区积压是否达到容量
+// 设置了 MaxBufferEntries 时容量为该上限，达到上限后日志会被丢弃；
+// 没有设置时缓冲区不限制大小，不会因积压丢弃日志，总是视为正常。
+// 长时间没有发送成功由 loki 组件的 LokiStaleAfter 检查
+func (l *Logger) bufferHealth() ComponentHealth {
+	length := l.lokiClient.BufferLen()
+	if l.config.LokiConfig.MaxBufferEntries <= 0 {
+		return ComponentHealth{Status: HealthOK, Detail: fmt.Sprintf("%d/不限制", length)}
+	}
+	capacity := l.lokiClient.BufferCap()
+
+	detail := fmt.Sprintf("%d/%d", length, capacity)
+	if length >= capacity {
+		return ComponentHealth{Status: HealthDegraded, Detail: detail}
+	}
+	return ComponentHealth{Status: HealthOK, Detail: detail}
+}
+
+// workerHealth 检查 Loki 客户端的后台工作协程是否在运行
+func (l *Logger) workerHealth() ComponentHealth {
+	if !l.lokiClient.IsRunning() {
+		return ComponentHealth{Status: HealthDown, Detail: "工作协程未运行"}
+	}
+	return ComponentHealth{Status: HealthOK}
+}
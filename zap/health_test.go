@@ -0,0 +1,151 @@
+package zap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+func TestHealthOK(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, _ := newLokiLogger(t, Config{EnableFile: true, FilePath: path})
+	defer logger.Close()
+
+	logger.Info("hello")
+	if err := logger.lokiClient.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	report := logger.Health()
+	if report.Status != HealthOK {
+		t.Errorf("Status = %s, want ok; components: %+v", report.Status, report.Components)
+	}
+	for _, name := range []string{"file", "loki", "buffer", "worker"} {
+		if h, ok := report.Components[name]; !ok || h.Status != HealthOK {
+			t.Errorf("component %s = %+v, want ok", name, h)
+		}
+	}
+}
+
+func TestHealthDegraded(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		setup     func(l *Logger)
+		component string
+		want      HealthStatus
+	}{
+		{
+			name: "last send failed",
+			cfg:  Config{LokiConfig: LokiConfig{URL: failing.URL, OnSendError: func([]pkg.LogEntry, error) {}}},
+			setup: func(l *Logger) {
+				l.Info("hello")
+				_ = l.lokiClient.Flush()
+			},
+			component: "loki",
+			want:      HealthDegraded,
+		},
+		{
+			name: "buffer at capacity",
+			cfg:  Config{LokiConfig: LokiConfig{BatchSize: 2, MaxBufferEntries: 3}},
+			setup: func(l *Logger) {
+				l.lokiClient.Pause()
+				for i := 0; i < 5; i++ {
+					l.Info("hello")
+				}
+			},
+			component: "buffer",
+			want:      HealthDegraded,
+		},
+		{
+			name: "no success within stale threshold",
+			cfg:  Config{LokiStaleAfter: 10 * time.Millisecond},
+			setup: func(l *Logger) {
+				l.lokiClient.Pause()
+				l.Info("hello")
+				time.Sleep(20 * time.Millisecond)
+			},
+			component: "loki",
+			want:      HealthDegraded,
+		},
+		{
+			name: "worker stopped",
+			setup: func(l *Logger) {
+				_ = l.lokiClient.Stop(context.Background())
+			},
+			component: "worker",
+			want:      HealthDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logger *Logger
+			if tt.cfg.LokiConfig.URL != "" {
+				cfg := tt.cfg
+				cfg.EnableLoki = true
+				cfg.SuppressWarnings = true
+				var err error
+				if logger, err = NewLogger(&cfg); err != nil {
+					t.Fatalf("NewLogger() error = %v", err)
+				}
+			} else {
+				logger, _ = newLokiLogger(t, tt.cfg)
+			}
+			defer logger.Close()
+
+			tt.setup(logger)
+			report := logger.Health()
+			if got := report.Components[tt.component].Status; got != tt.want {
+				t.Errorf("component %s = %s, want %s", tt.component, got, tt.want)
+			}
+			if report.Status != tt.want {
+				t.Errorf("Status = %s, want %s", report.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthFileNotWritable(t *testing.T) {
+	dir := t.TempDir()
+	notDir := filepath.Join(dir, "file")
+	if err := os.WriteFile(notDir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := NewLogger(&Config{EnableFile: true, FilePath: filepath.Join(notDir, "app.log")})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	report := logger.Health()
+	if report.Components["file"].Status != HealthDown || report.Status != HealthDown {
+		t.Errorf("report = %+v, want file down", report)
+	}
+}
+
+func TestHealthUnboundedBuffer(t *testing.T) {
+	logger, _ := newLokiLogger(t, Config{LokiConfig: LokiConfig{BatchSize: 2}})
+	defer logger.Close()
+
+	// 积压超过批量大小，但没有设置 MaxBufferEntries，不会丢弃日志
+	logger.lokiClient.Pause()
+	for i := 0; i < 5; i++ {
+		logger.Info("hello")
+	}
+	if h := logger.Health().Components["buffer"]; h.Status != HealthOK {
+		t.Errorf("buffer health = %+v, want ok for an unbounded buffer", h)
+	}
+}
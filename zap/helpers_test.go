@@ -0,0 +1,126 @@
+package zap
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lokiLine 是 fakeLoki 收到的一条日志
+type lokiLine struct {
+	// Labels 是日志所在流的标签
+	Labels map[string]string
+	// Line 是日志内容
+	Line string
+	// Metadata 是结构化元数据
+	Metadata map[string]string
+}
+
+// fakeLoki 是记录推送内容的 Loki 测试服务器
+type fakeLoki struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	lines []lokiLine
+}
+
+// newFakeLoki 启动一个总是返回 204 的 Loki 测试服务器
+func newFakeLoki(t *testing.T) *fakeLoki {
+	t.Helper()
+
+	f := &fakeLoki{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeLoki) handle(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+
+	var req struct {
+		Streams []struct {
+			Stream map[string]string   `json:"stream"`
+			Values [][]json.RawMessage `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	for _, s := range req.Streams {
+		for _, v := range s.Values {
+			line := lokiLine{Labels: s.Stream}
+			_ = json.Unmarshal(v[1], &line.Line)
+			if len(v) > 2 {
+				_ = json.Unmarshal(v[2], &line.Metadata)
+			}
+			f.lines = append(f.lines, line)
+		}
+	}
+	f.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Lines 返回收到的所有日志
+func (f *fakeLoki) Lines() []lokiLine {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]lokiLine(nil), f.lines...)
+}
+
+// newLokiLogger 创建只输出到 fakeLoki 的日志器
+func newLokiLogger(t *testing.T, cfg Config) (*Logger, *fakeLoki) {
+	t.Helper()
+
+	server := newFakeLoki(t)
+	cfg.EnableLoki = true
+	cfg.LokiConfig.URL = server.URL
+	cfg.SuppressWarnings = true
+	logger, err := NewLogger(&cfg)
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	return logger, server
+}
+
+// closeAndCollect 关闭日志器以发送缓冲区中的日志，返回 Loki 收到的日志
+func closeAndCollect(t *testing.T, logger *Logger, server *fakeLoki) []lokiLine {
+	t.Helper()
+
+	if err := logger.Close(); err != nil {
+		t.Logf("Close() error = %v", err)
+	}
+	return server.Lines()
+}
+
+// lineFields 解析 Loki 日志中追加在消息后面的 JSON 字段
+func lineFields(t *testing.T, line string) map[string]interface{} {
+	t.Helper()
+
+	i := strings.Index(line, " {")
+	if i < 0 {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line[i+1:]), &fields); err != nil {
+		t.Fatalf("unmarshal fields of %q: %v", line, err)
+	}
+	return fields
+}
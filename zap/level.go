@@ -0,0 +1,126 @@
+package zap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levels 是各输出可以在运行时修改的日志级别
+// 使用 zap.AtomicLevel，通过 Named、With 创建的子日志器与原日志器共享同一组级别
+type levels struct {
+	// console 是控制台输出的级别
+	console zap.AtomicLevel
+	// file 是文件输出的级别
+	file zap.AtomicLevel
+}
+
+// newLevels 根据配置创建各输出的级别
+func newLevels(cfg *Config) levels {
+	return levels{
+		console: zap.NewAtomicLevelAt(cfg.ConsoleLevel),
+		file:    zap.NewAtomicLevelAt(cfg.FileLevel),
+	}
+}
+
+// ConsoleLevel 返回控制台输出当前的最小日志级别
+func (l *Logger) ConsoleLevel() zapcore.Level {
+	return l.levels.console.Level()
+}
+
+// SetConsoleLevel 在运行时修改控制台输出的最小日志级别
+func (l *Logger) SetConsoleLevel(level zapcore.Level) {
+	l.levels.console.SetLevel(level)
+}
+
+// FileLevel 返回文件输出当前的最小日志级别
+func (l *Logger) FileLevel() zapcore.Level {
+	return l.levels.file.Level()
+}
+
+// SetFileLevel 在运行时修改文件输出的最小日志级别
+func (l *Logger) SetFileLevel(level zapcore.Level) {
+	l.levels.file.SetLevel(level)
+}
+
+// LokiLevel 返回 Loki 输出当前的最小日志级别
+// 未启用 Loki 时返回配置中的 LokiLevel
+func (l *Logger) LokiLevel() zapcore.Level {
+	if l.lokiClient == nil {
+		return l.config.LokiLevel
+	}
+	return l.lokiClient.MinLevel()
+}
+
+// SetLokiLevel 在运行时修改 Loki 输出的最小日志级别，未启用 Loki 时不做任何事
+func (l *Logger) SetLokiLevel(level zapcore.Level) {
+	if l.lokiClient != nil {
+		l.lokiClient.SetMinLevel(level)
+	}
+}
+
+// levelPayload 是 LevelHandler 读写的 JSON 格式
+// 修改时只需要给出要修改的输出，Level 同时修改所有输出
+type levelPayload struct {
+	Level   *zapcore.Level `json:"level,omitempty"`
+	Console *zapcore.Level `json:"console,omitempty"`
+	File    *zapcore.Level `json:"file,omitempty"`
+	Loki    *zapcore.Level `json:"loki,omitempty"`
+}
+
+// LevelHandler 返回查看和修改日志级别的 HTTP 处理器，用法与 zap.AtomicLevel.ServeHTTP 类似
+//
+//	GET 返回各输出当前的级别，例如 {"console":"info","file":"info","loki":"warn"}
+//	PUT 修改级别，例如 {"loki":"debug"} 或 {"level":"debug"}，返回修改后的级别
+//
+// 该处理器可以修改日志级别，只应挂载在内部管理端口上
+func (l *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req levelPayload
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelError(w, http.StatusBadRequest, fmt.Sprintf("请求格式错误: %v", err))
+				return
+			}
+			l.applyLevels(req)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeLevelError(w, http.StatusMethodNotAllowed, "只支持 GET 和 PUT")
+			return
+		}
+
+		console, file, lokiLevel := l.ConsoleLevel(), l.FileLevel(), l.LokiLevel()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levelPayload{Console: &console, File: &file, Loki: &lokiLevel})
+	})
+}
+
+// applyLevels 按请求修改各输出的级别
+func (l *Logger) applyLevels(req levelPayload) {
+	if req.Level != nil {
+		l.SetConsoleLevel(*req.Level)
+		l.SetFileLevel(*req.Level)
+		l.SetLokiLevel(*req.Level)
+	}
+	if req.Console != nil {
+		l.SetConsoleLevel(*req.Console)
+	}
+	if req.File != nil {
+		l.SetFileLevel(*req.File)
+	}
+	if req.Loki != nil {
+		l.SetLokiLevel(*req.Loki)
+	}
+}
+
+// writeLevelError 以 JSON 格式写入错误信息
+func writeLevelError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,81 @@
+package zap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSetLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, server := newLokiLogger(t, Config{
+		EnableFile: true,
+		FilePath:   path,
+		FileLevel:  zapcore.InfoLevel,
+		LokiLevel:  zapcore.InfoLevel,
+	})
+	child := logger.Named("worker")
+
+	logger.Debug("before")
+	child.SetFileLevel(zapcore.DebugLevel)
+	child.SetLokiLevel(zapcore.DebugLevel)
+	logger.Debug("after")
+	logger.SetLokiLevel(zapcore.ErrorLevel)
+	logger.Warn("filtered from loki")
+
+	if logger.FileLevel() != zapcore.DebugLevel || logger.LokiLevel() != zapcore.ErrorLevel {
+		t.Errorf("levels = file %s, loki %s, want debug and error", logger.FileLevel(), logger.LokiLevel())
+	}
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 1 || lines[0].Line != "after" {
+		t.Errorf("Loki lines = %+v, want only the debug entry logged after SetLokiLevel", lines)
+	}
+
+	records := readFileLines(t, logger, path)
+	var msgs []string
+	for _, r := range records {
+		msgs = append(msgs, r["msg"].(string))
+	}
+	if got := strings.Join(msgs, ","); got != "after,filtered from loki" {
+		t.Errorf("file messages = %q, want debug entries only after SetFileLevel", got)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger, _ := newLokiLogger(t, Config{ConsoleLevel: zapcore.InfoLevel, LokiLevel: zapcore.WarnLevel})
+	defer logger.Close()
+	handler := logger.LevelHandler()
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/log/level", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "")
+	if want := `{"console":"info","file":"info","loki":"warn"}`; strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("GET body = %s, want %s", rec.Body.String(), want)
+	}
+
+	rec = serve(http.MethodPut, `{"loki":"debug"}`)
+	if rec.Code != http.StatusOK || logger.LokiLevel() != zapcore.DebugLevel || logger.ConsoleLevel() != zapcore.InfoLevel {
+		t.Errorf("PUT loki: code %d, levels console %s loki %s", rec.Code, logger.ConsoleLevel(), logger.LokiLevel())
+	}
+
+	rec = serve(http.MethodPut, `{"level":"error"}`)
+	if want := `{"console":"error","file":"error","loki":"error"}`; strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("PUT level body = %s, want %s", rec.Body.String(), want)
+	}
+
+	if rec = serve(http.MethodPut, `{"level":"loud"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT invalid level code = %d, want 400", rec.Code)
+	}
+	if rec = serve(http.MethodPost, `{}`); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST code = %d, want 405", rec.Code)
+	}
+}
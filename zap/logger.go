@@ -1,16 +1,25 @@
 package zap
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-
+	"io"
+	"math"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bt-smart/btlog/loki"
+	"github.com/bt-smart/btlog/otlp"
+	"github.com/bt-smart/btlog/pkg"
 	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Config 定义了日志配置
@@ -21,15 +30,54 @@ type Config struct {
 	EnableFile bool
 	// 是否启用Loki输出
 	EnableLoki bool
+	// 是否启用 OTLP 输出，将日志通过 OTLP/HTTP 发送到 OpenTelemetry 接收端
+	EnableOTLP bool
+	// 是否启用 syslog 输出，Windows 和 Plan 9 上不支持
+	EnableSyslog bool
 	// 控制台输出的最小日志级别
 	ConsoleLevel zapcore.Level
+	// 是否将 Error 及以上级别的控制台日志输出到标准错误，其余级别仍输出到标准输出
+	ErrorToStderr bool
+	// 是否为控制台输出的日志级别着色，只影响控制台，不影响文件和 Loki
+	// 默认关闭，避免重定向到文件或管道时输出中混入颜色控制字符。ConsoleEncoding 为 json 时不生效
+	ConsoleColor bool
+	// 控制台输出的编码格式，可以是 EncodingConsole 或 EncodingJSON，默认为 EncodingConsole
+	// 容器中由日志采集器读取标准输出时可以使用 EncodingJSON
+	ConsoleEncoding string
+	// 文件输出的编码格式，可以是 EncodingConsole 或 EncodingJSON，默认为 EncodingJSON
+	FileEncoding string
 	// 文件输出的最小日志级别
 	FileLevel zapcore.Level
 	// loki输出的最小日志级别
 	LokiLevel zapcore.Level
+	// OTLP 输出的最小日志级别
+	OTLPLevel zapcore.Level
+	// syslog 输出的最小日志级别
+	SyslogLevel zapcore.Level
+	// syslog 的网络类型，例如 "udp"、"tcp"，为空时连接本机的 syslog 守护进程
+	SyslogNetwork string
+	// syslog 服务器地址，例如 "logs.example.com:514"，SyslogNetwork 为空时忽略
+	SyslogAddr string
+	// syslog 消息的标签，为空时使用程序名
+	SyslogTag string
+	// 内存中保留的最近日志条数，大于 0 时启用，可以通过 Logger.RecentLogs 读取
+	RecentLogsSize int
+	// 保留到内存中的最小日志级别
+	RecentLogsLevel zapcore.Level
 	// 是否记录调用方信息
 	EnableCaller bool
+	// 控制台与文件输出的时间格式（Go 时间布局，如 "2006-01-02 15:04:05.000"）
+	// 为空时使用默认的 RFC3339 格式。设置了 TimeFormat 时忽略该选项
+	TimeLayout string
+	// 控制台与文件输出的时间编码方式，可以是 TimeFormatRFC3339 等预设格式或 Go 时间布局
+	// 为空时使用 TimeLayout
+	TimeFormat string
+	// 控制台与文件输出的编码器配置，不为 nil 时原样使用，忽略 TimeFormat 和 TimeLayout
+	// 可用于统一字段命名，例如将时间和级别字段命名为 @timestamp 和 severity。
+	// ConsoleColor 仍然作用于控制台输出
+	EncoderConfig *zapcore.EncoderConfig
 	// 日志文件路径
+	// 指向 /dev/stdout、/dev/stderr 或其他字符设备时直接写入，不做切割
 	FilePath string
 	// 日志文件最大大小(MB)
 	MaxSize int
@@ -39,198 +87,813 @@ type Config struct {
 	MaxAge int
 	// 是否压缩旧文件
 	Compress bool
+	// 按时间切割日志文件的周期，可以是 RotationDaily 或 RotationHourly，为空时只按大小切割
+	// 设置后每个周期写入一个文件名带有日期的文件，例如 app-2006-01-02.log，周期内仍按 MaxSize 切割
+	RotationInterval string
+	// 日志文件同步到磁盘的方式，可以是 FileSyncBuffered、FileSyncEveryN 或 FileSyncAlways，默认为 FileSyncBuffered
+	// 同步越频繁，机器崩溃时丢失的日志越少，但吞吐量越低：FileSyncAlways 每条日志一次 fsync，
+	// 在普通磁盘上每秒通常只能写入几百到几千条。FilePath 指向设备时不生效
+	FileSyncMode string
+	// FileSyncEveryN 模式下每写入多少条日志同步一次，默认为 100
+	FileSyncEvery int
 	// Loki配置
 	LokiConfig LokiConfig
+	// OTLP配置
+	OTLPConfig OTLPConfig
+	// 上下文字段提取器，按顺序作用于所有 ...Ctx 方法
+	ContextExtractors []ContextExtractor
+	// 转发到 Loki 之前按顺序执行的转换器，任一转换器返回 nil 时丢弃该条日志
+	Transformers []EntryTransformer
+	// 是否为高级别日志附加 goroutine 信息
+	EnableGoroutineInfo bool
+	// 附加 goroutine 信息的最小日志级别
+	GoroutineInfoLevel zapcore.Level
+	// 附加的 goroutine 堆栈最大字节数，为 0 时不附加堆栈
+	GoroutineStackSize int
+	// 是否将 zap.Binary 字段编码为十六进制，默认为 base64
+	BinaryAsHex bool
+	// 是否将 Named 设置的日志器名称作为 logger 标签发送到 Loki
+	// 名称通常是少量固定的组件名，不会导致流数量爆炸
+	AddLoggerLabel bool
+	// 是否关闭创建日志器时对可疑配置的警告，参见 Config.Warnings
+	SuppressWarnings bool
+	// 健康检查中 Loki 发送停滞的判定时间，默认为 1 分钟
+	// 缓冲区中有待发送的日志、且距最近一次发送成功超过该时间时，Loki 视为异常
+	LokiStaleAfter time.Duration
+	// 日志采样配置，同时作用于控制台、文件和 Loki 输出，为 nil 时不采样
+	Sampling *SamplingConfig
+	// 重复日志的去重窗口，同时作用于所有输出，为 0 时不去重
+	// 窗口内级别和消息都相同的日志只输出第一条，窗口结束时如果有重复，
+	// 再输出一条带 repeated 字段的汇总日志，值为被合并的条数。高于 Error 的日志不参与去重
+	DedupeWindow time.Duration
+	// 每条日志写入后按顺序调用的钩子，例如统计错误日志的个数或触发告警
+	// 钩子在记录日志的协程中同步执行，耗时的操作应放到其他协程中，避免拖慢日志调用。
+	// 只有写入控制台、文件、syslog 或 OTLP 中至少一个输出的日志才会触发钩子，只发送到 Loki 的日志不会触发。
+	// 钩子返回的错误写入 zap 的 ErrorOutput（默认为标准错误）
+	Hooks []func(zapcore.Entry) error
 }
 
 // LokiConfig 定义了Loki相关配置
 type LokiConfig struct {
 	// Loki服务器地址
 	URL string
+	// 推送接口的路径，默认为 /loki/api/v1/push
+	PushPath string
 	// 批量发送大小
 	BatchSize int
 	// 日志标签
 	Labels map[string]string
-	// 发送超时时间（秒）
+	// 级别标签名，为 nil 时使用 "level"，指向空字符串时不按级别拆分流
+	LevelLabel *string
+	// 完全决定每条日志流标签的函数，为 nil 时使用默认标签，参见 loki.ClientConfig.LabelsFunc
+	// 只应返回取值个数有限的标签，否则流的个数会失控
+	LabelsFunc func(entry pkg.LogEntry) map[string]string
+	// 发送超时时间（秒），作为 HTTP 客户端的 Timeout，0 表示不设置
+	// HTTPClient 自身设置了超时时以 HTTPClient 为准；Transport 为 gRPC 时只对自定义的 HTTPClient 生效
 	Timeout int
 	// HTTPClient 是用于发送请求的 HTTP 客户端
-	// 如果为 nil，将使用 http.DefaultClient
+	// 如果为 nil，将使用 http.DefaultClient，设置了 Timeout 时使用带该超时的客户端
+	HTTPClient *http.Client
+	// 多租户Loki的租户 ID，作为 X-Scope-OrgID 请求头发送，为空时不发送
+	TenantID string
+	// 按日志级别指定的租户 ID，未指定的级别使用 TenantID
+	LevelTenants map[zapcore.Level]string
+	// 访问Loki时的认证方式，支持 Bearer 令牌和 HTTP 基本认证
+	Auth loki.Auth
+	// 除 URL 之外的额外推送目标，每批日志会同时发送到所有目标
+	Targets []loki.Target
+	// 推送请求体的压缩方式，为空时不压缩
+	Compression loki.Compression
+	// 推送请求的编码格式，为空时使用 JSON
+	Protocol loki.Protocol
+	// 推送请求的传输方式，为空时使用 HTTP，TransportGRPC 时 URL 是Loki的 gRPC 地址
+	Transport loki.Transport
+	// ValidateResponse 用于判断推送请求是否成功
+	// 如果为 nil，只有状态码为 204 时视为成功
+	ValidateResponse func(resp *http.Response) error
+	// 日志在缓冲区中的最长停留时间，为 0 时只按 MaxWaitTime 定期发送
+	MaxEntryAge time.Duration
+	// 关闭时等待最后一次发送完成的最长时间，默认为 10 秒
+	ShutdownTimeout time.Duration
+	// 发送失败后的最大重试次数，默认为 0，即不重试
+	MaxRetries int
+	// 第一次重试前的等待时间，之后每次翻倍，默认为 500 毫秒
+	RetryBackoff time.Duration
+	// 判断发送失败的错误是否值得重试，为 nil 时使用 loki.IsRetryable
+	IsRetryable func(err error) bool
+	// 幂等键请求头的名称，例如 "X-Idempotency-Key"，为空时不发送
+	IdempotencyHeader string
+	// 是否为每条日志附加 entry_id 结构化元数据
+	AddEntryID bool
+	// 日志时间戳格式，为空时使用Loki要求的Unix纳秒
+	TimestampFormat loki.TimestampFormat
+	// 并发发送的分片数，默认为 1
+	FlushWorkers int
+	// 同时进行的推送的最大数量，超出的排队等待，0 表示不限制
+	MaxConcurrentSends int
+	// 等待推送名额的最大排队数量，排队已满时日志放回缓冲区，0 表示不限制
+	MaxQueuedSends int
+	// 日志最终发送失败时的回调，为 nil 时使用 ErrorLogger 记录错误
+	OnSendError func(entries []pkg.LogEntry, err error)
+	// 记录Loki客户端自身诊断信息的日志接口，为 nil 时使用标准库的log包
+	// 不要传入写入Loki的日志器，否则发送失败时会产生递归
+	ErrorLogger loki.ErrorLogger
+	// 是否在关闭时发送一条包含运行统计的摘要日志
+	LogShutdownSummary bool
+	// 是否同步发送，为 true 时每条日志在写日志的方法返回之前发送到 Loki，
+	// 发送失败时与异步发送一样交给 OnSendError 或 ErrorLogger，并可以通过 Logger.LokiLastError 查询。
+	// 每条日志单独发送一个请求，会显著增加写日志的耗时，只适合必须确认送达的低频日志
+	SyncDelivery bool
+	// 标签规范，创建时对 Labels 进行检查，为 nil 时接受任何标签
+	LabelPolicy loki.LabelPolicy
+	// 内存中保留的丢弃事件条数，默认为 100
+	DropEventsSize int
+	// 丢弃事件的审计文件路径，为空时只保留在内存中
+	DropAuditFile string
+	// 缓冲区最多保存的日志条数，超过后按 BufferDropPolicy 丢弃，0 表示不限制
+	MaxBufferEntries int
+	// 缓冲区超过上限时的丢弃策略，默认丢弃最早的日志
+	BufferDropPolicy pkg.DropPolicy
+	// TraceIDField 是 trace ID 所在的字段名，为空时不做处理
+	// 该字段会从消息中移出，作为结构化元数据发送（需要 Loki 2.9+）
+	TraceIDField string
+	// TraceBuckets 是 trace_bucket 标签的取值个数
+	// 大于 0 时按 trace ID 的哈希值分桶，作为 trace_bucket 标签，
+	// 在不引起流数量爆炸的前提下提供一定的局部性
+	TraceBuckets int
+	// StructuredMetadataKeys 是作为结构化元数据发送的字段名（需要 Loki 2.9+）
+	// 这些字段会从消息中移出，可以在 LogQL 中直接按字段过滤，且不影响流的划分
+	StructuredMetadataKeys []string
+	// ErrorMetadata 定义是否将错误字段（zap.Error、zap.NamedError）作为结构化元数据发送（需要 Loki 2.9+）
+	// 开启后错误字段从消息中移出，以字段名为键，取值为 fmt.Sprintf("%+v", err)，
+	// 包含 github.com/pkg/errors 等库记录的堆栈。错误内容取值不受限制，因此不会作为流标签发送。
+	// 关闭时错误仍按 zap 的方式编码在消息中：error 为错误信息，实现了 fmt.Formatter 的错误附加 errorVerbose
+	ErrorMetadata bool
+	// LabelKeys 是作为流标签发送的字段名，不同取值的日志会落在不同的流中
+	// 这些字段会从消息中移出。只应使用取值个数有限的字段，并配合 MaxStreamsPerFlush 限制流的个数
+	LabelKeys []string
+	// 每次刷新中带附加标签的流的最大个数，超过后多出的日志去掉附加标签，0 表示不限制
+	MaxStreamsPerFlush int
+	// RedactKeys 是不允许发送到 Loki 的敏感字段名，例如 password、ssn、authorization，不区分大小写
+	// 匹配的字段在离开进程之前、提取标签和结构化元数据之前被脱敏，控制台和文件输出不受影响。
+	// 只检查顶层字段，对象和数组内部的字段不做处理
+	RedactKeys []string
+	// RedactKeyPattern 是匹配敏感字段名的正则表达式，例如 (?i)(token|secret)$，为空时只按 RedactKeys 匹配
+	RedactKeyPattern string
+	// RedactDrop 为 true 时直接去掉敏感字段，否则将取值替换为 [REDACTED]
+	RedactDrop bool
+	// 每个请求中日志的最大估计字节数，达到后立即发送并按该值拆分请求，0 表示只按条数分批
+	MaxBatchBytes int
+}
+
+// OTLPConfig 定义了 OTLP 相关配置
+type OTLPConfig struct {
+	// OTLP/HTTP 接收端地址，例如 http://otel-collector:4318
+	Endpoint string
+	// 日志接口的路径，默认为 /v1/logs
+	LogsPath string
+	// 每个请求附加的请求头，例如认证令牌
+	Headers map[string]string
+	// 用于发送请求的 HTTP 客户端，为 nil 时使用 http.DefaultClient
 	HTTPClient *http.Client
+	// 资源属性，例如 service.name
+	ResourceAttributes map[string]string
+	// 批量发送大小，默认为 100
+	BatchSize int
+	// 定期发送的间隔，默认为 5 秒
+	FlushInterval time.Duration
+	// 单个请求的超时时间，默认为 10 秒
+	Timeout time.Duration
+	// 缓冲区最多保存的日志条数，超过后丢弃最早的日志，默认为 BatchSize 的 100 倍
+	MaxBufferEntries int
 }
 
 type Logger struct {
 	*zap.Logger
 	lokiClient *loki.Client
-	fileLogger *lumberjack.Logger
+	// otlpExporter 是 OTLP 输出的导出器，未启用时为 nil
+	otlpExporter *otlp.Exporter
+	fileLogger   *rotatingFile
+	deviceFile   *os.File
+	// syslogConn 是 syslog 输出的连接，未启用时为 nil
+	syslogConn io.Closer
+	// recentLogs 是最近日志的环形缓冲区，未启用时为 nil
+	recentLogs *recentLogs
+	extractors []ContextExtractor
+	config     Config
+	name       string
+	// fields 是 With 附加的字段，转发到 Loki 时放在每条日志的字段之前
+	fields []zap.Field
+	// levels 是控制台和文件输出可以在运行时修改的级别
+	levels levels
+	// lokiSampler 是 Loki 输出的采样器，未配置采样时为 nil
+	lokiSampler *sampler
+	// redactor 在发送到 Loki 之前处理敏感字段，未配置时为 nil
+	redactor *redactor
+	// deduper 是 zap 输出的去重器，lokiDeduper 是 Loki 输出的去重器，未配置去重时为 nil
+	deduper     *deduper
+	lokiDeduper *deduper
 }
 
 // NewLogger 创建并返回一个新的日志实例
+// 可以不启用任何输出，此时返回的日志器丢弃所有日志，效果与 NewNop 相同
 func NewLogger(cfg *Config) (*Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var redact *redactor
+	if cfg.EnableLoki {
+		var err error
+		if redact, err = newRedactor(cfg.LokiConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	var cores []zapcore.Core
+	levels := newLevels(cfg)
 
-	// 使用 zap 预设的 Production 编码器配置
-	encoderConfig := zap.NewProductionEncoderConfig()
-	encoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
+	encoderConfig, err := cfg.encoderConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	// 控制台输出
 	if cfg.EnableConsole {
-		consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
-		consoleCore := zapcore.NewCore(
-			consoleEncoder,
-			zapcore.AddSync(os.Stdout),
-			cfg.ConsoleLevel,
-		)
-		cores = append(cores, consoleCore)
+		console, err := consoleCores(cfg, encoderConfig, levels.console)
+		if err != nil {
+			return nil, err
+		}
+		cores = append(cores, console...)
 	}
 
 	// 文件输出
-	var fileLogger *lumberjack.Logger
+	var fileLogger *rotatingFile
+	var deviceFile *os.File
 	if cfg.EnableFile {
-		fileLogger = &lumberjack.Logger{
-			Filename:   cfg.FilePath,
-			MaxSize:    cfg.MaxSize,
-			MaxBackups: cfg.MaxBackups,
-			MaxAge:     cfg.MaxAge,
-			Compress:   cfg.Compress,
-		}
-		fileEncoder := zapcore.NewJSONEncoder(encoderConfig)
+		fileEncoder, err := newEncoder(cfg.FileEncoding, EncodingJSON, encoderConfig)
+		if err != nil {
+			return nil, err
+		}
+		var fileWriter zapcore.WriteSyncer
+		device, isDevice, err := openDevice(cfg.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("打开日志设备失败: %v", err)
+		}
+		if isDevice {
+			// 设备文件不支持切割，直接写入
+			fileWriter = zapcore.Lock(device)
+			if device != os.Stdout && device != os.Stderr {
+				deviceFile = device
+			}
+		} else {
+			fileLogger = newRotatingFile(cfg)
+			fileWriter = newFileSyncer(fileLogger, func() error { return syncPath(fileLogger.filename()) }, cfg.FileSyncMode, cfg.FileSyncEvery)
+		}
 		fileCore := zapcore.NewCore(
 			fileEncoder,
-			zapcore.AddSync(fileLogger),
-			cfg.FileLevel,
+			fileWriter,
+			levels.file,
 		)
 		cores = append(cores, fileCore)
 	}
 
+	// 最近日志的环形缓冲区
+	var recent *recentLogs
+	if cfg.RecentLogsSize > 0 {
+		recent = newRecentLogs(cfg.RecentLogsSize)
+		cores = append(cores, newRecentCore(recent, encoderConfig, cfg.RecentLogsLevel))
+	}
+
+	// syslog 输出
+	var syslogConn io.Closer
+	if cfg.EnableSyslog {
+		var syslogCore zapcore.Core
+		syslogCore, syslogConn, err = newSyslogCore(cfg, encoderConfig)
+		if err != nil {
+			return nil, fmt.Errorf("连接 syslog 失败: %v", err)
+		}
+		cores = append(cores, syslogCore)
+	}
+
+	// OTLP 输出
+	var otlpExporter *otlp.Exporter
+	if cfg.EnableOTLP {
+		otlpExporter, err = otlp.NewExporter(otlpConfig(cfg))
+		if err != nil {
+			if syslogConn != nil {
+				_ = syslogConn.Close()
+			}
+			return nil, fmt.Errorf("创建 OTLP 导出器失败: %v", err)
+		}
+		cores = append(cores, otlp.NewCore(otlpExporter, cfg.OTLPLevel))
+	}
+
 	// 创建并启动 Loki 客户端
 	var lokiClient *loki.Client
 	if cfg.EnableLoki {
-		var err error
-		lokiClient, err = loki.NewClient(loki.ClientConfig{
-			URL:        cfg.LokiConfig.URL,
-			BatchSize:  cfg.LokiConfig.BatchSize,
-			Labels:     cfg.LokiConfig.Labels,
-			MinLevel:   cfg.LokiLevel,
-			HTTPClient: cfg.LokiConfig.HTTPClient,
-			// 添加一些合理的默认值
-			MinWaitTime: 1,  // 1秒
-			MaxWaitTime: 10, // 10秒
-		})
+		lokiClient, err = loki.NewClient(lokiClientConfig(cfg))
 		if err != nil {
+			if otlpExporter != nil {
+				_ = otlpExporter.Shutdown(context.Background())
+			}
+			if syslogConn != nil {
+				_ = syslogConn.Close()
+			}
 			return nil, fmt.Errorf("创建 Loki 客户端失败: %v", err)
 		}
 		lokiClient.Start() // 确保调用 Start()
 	}
 
-	core := zapcore.NewTee(cores...)
+	// 没有启用控制台、文件、syslog 或 OTLP 输出时显式使用 no-op core，
+	// 日志不写入任何地方，启用了 Loki 时仍然会转发到 Loki
+	core := zapcore.NewNopCore()
+	if len(cores) > 0 {
+		core = zapcore.NewTee(cores...)
+	}
+	// 去重在采样之内，汇总日志不会被采样丢弃
+	dedupe := newDeduper(cfg.DedupeWindow)
+	var lokiDeduper *deduper
+	if dedupe != nil {
+		core = &dedupeCore{Core: core, deduper: dedupe}
+		if lokiClient != nil {
+			lokiDeduper = newDeduper(cfg.DedupeWindow)
+		}
+	}
+	var lokiSampler *sampler
+	if cfg.Sampling != nil {
+		core = cfg.Sampling.wrap(core)
+		if lokiClient != nil {
+			lokiSampler = cfg.Sampling.newSampler()
+		}
+	}
 	// 根据配置决定是否添加调用者信息
 	var opts []zap.Option
 	if cfg.EnableCaller {
 		opts = append(opts, zap.AddCaller(), zap.AddCallerSkip(1))
 	}
+	if len(cfg.Hooks) > 0 {
+		opts = append(opts, zap.Hooks(cfg.Hooks...))
+	}
 
 	// 创建logger
 	logger := zap.New(core, opts...)
 
-	return &Logger{
-		Logger:     logger,
-		lokiClient: lokiClient,
-		fileLogger: fileLogger,
-	}, nil
+	l := &Logger{
+		Logger:       logger,
+		lokiClient:   lokiClient,
+		otlpExporter: otlpExporter,
+		fileLogger:   fileLogger,
+		deviceFile:   deviceFile,
+		syslogConn:   syslogConn,
+		recentLogs:   recent,
+		extractors:   cfg.ContextExtractors,
+		config:       *cfg,
+		levels:       levels,
+		lokiSampler:  lokiSampler,
+		redactor:     redact,
+		deduper:      dedupe,
+		lokiDeduper:  lokiDeduper,
+	}
+
+	// 对可疑配置给出一次性警告
+	if !cfg.SuppressWarnings {
+		for _, warning := range cfg.Warnings() {
+			l.Warn(warning)
+		}
+	}
+
+	return l, nil
+}
+
+// otlpConfig 根据日志配置生成 OTLP 导出器配置
+func otlpConfig(cfg *Config) otlp.Config {
+	return otlp.Config{
+		Endpoint:           cfg.OTLPConfig.Endpoint,
+		LogsPath:           cfg.OTLPConfig.LogsPath,
+		Headers:            cfg.OTLPConfig.Headers,
+		HTTPClient:         cfg.OTLPConfig.HTTPClient,
+		ResourceAttributes: cfg.OTLPConfig.ResourceAttributes,
+		BatchSize:          cfg.OTLPConfig.BatchSize,
+		FlushInterval:      cfg.OTLPConfig.FlushInterval,
+		Timeout:            cfg.OTLPConfig.Timeout,
+		MaxBufferEntries:   cfg.OTLPConfig.MaxBufferEntries,
+	}
+}
+
+// lokiHTTPClient 返回发送到 Loki 的 HTTP 客户端，按 Timeout 设置超时
+// 自定义客户端没有超时时复制一份再设置，不修改调用方的客户端。
+// gRPC 推送需要支持 HTTP/2 的客户端，没有自定义客户端时交给 Loki 客户端创建
+func lokiHTTPClient(lc LokiConfig) *http.Client {
+	if lc.Timeout <= 0 {
+		return lc.HTTPClient
+	}
+	if lc.HTTPClient == nil {
+		if lc.Transport == loki.TransportGRPC {
+			return nil
+		}
+		return &http.Client{Timeout: time.Duration(lc.Timeout) * time.Second}
+	}
+	if lc.HTTPClient.Timeout > 0 {
+		return lc.HTTPClient
+	}
+	client := *lc.HTTPClient
+	client.Timeout = time.Duration(lc.Timeout) * time.Second
+	return &client
+}
+
+// lokiClientConfig 根据日志配置生成 Loki 客户端配置
+func lokiClientConfig(cfg *Config) loki.ClientConfig {
+	return loki.ClientConfig{
+		URL:                cfg.LokiConfig.URL,
+		PushPath:           cfg.LokiConfig.PushPath,
+		BatchSize:          cfg.LokiConfig.BatchSize,
+		Labels:             cfg.LokiConfig.Labels,
+		LevelLabel:         cfg.LokiConfig.LevelLabel,
+		LabelsFunc:         cfg.LokiConfig.LabelsFunc,
+		MinLevel:           cfg.LokiLevel,
+		HTTPClient:         lokiHTTPClient(cfg.LokiConfig),
+		TenantID:           cfg.LokiConfig.TenantID,
+		LevelTenants:       cfg.LokiConfig.LevelTenants,
+		Auth:               cfg.LokiConfig.Auth,
+		Targets:            cfg.LokiConfig.Targets,
+		Compression:        cfg.LokiConfig.Compression,
+		Protocol:           cfg.LokiConfig.Protocol,
+		Transport:          cfg.LokiConfig.Transport,
+		ValidateResponse:   cfg.LokiConfig.ValidateResponse,
+		MaxEntryAge:        cfg.LokiConfig.MaxEntryAge,
+		ShutdownTimeout:    cfg.LokiConfig.ShutdownTimeout,
+		MaxRetries:         cfg.LokiConfig.MaxRetries,
+		RetryBackoff:       cfg.LokiConfig.RetryBackoff,
+		IsRetryable:        cfg.LokiConfig.IsRetryable,
+		IdempotencyHeader:  cfg.LokiConfig.IdempotencyHeader,
+		AddEntryID:         cfg.LokiConfig.AddEntryID,
+		TimestampFormat:    cfg.LokiConfig.TimestampFormat,
+		FlushWorkers:       cfg.LokiConfig.FlushWorkers,
+		MaxConcurrentSends: cfg.LokiConfig.MaxConcurrentSends,
+		MaxQueuedSends:     cfg.LokiConfig.MaxQueuedSends,
+		OnSendError:        cfg.LokiConfig.OnSendError,
+		ErrorLogger:        cfg.LokiConfig.ErrorLogger,
+		LogShutdownSummary: cfg.LokiConfig.LogShutdownSummary,
+		LabelPolicy:        cfg.LokiConfig.LabelPolicy,
+		DropEventsSize:     cfg.LokiConfig.DropEventsSize,
+		DropAuditFile:      cfg.LokiConfig.DropAuditFile,
+		MaxBufferEntries:   cfg.LokiConfig.MaxBufferEntries,
+		BufferDropPolicy:   cfg.LokiConfig.BufferDropPolicy,
+		MaxStreamsPerFlush: cfg.LokiConfig.MaxStreamsPerFlush,
+		MaxBatchBytes:      cfg.LokiConfig.MaxBatchBytes,
+		// 添加一些合理的默认值
+		MinWaitTime: 1,  // 1秒
+		MaxWaitTime: 10, // 10秒
+	}
 }
 
 // 重写日志方法以支持同时写入Loki
 func (l *Logger) Debug(msg string, fields ...zap.Field) {
+	fields = l.prepareFields(zapcore.DebugLevel, fields)
 	l.Logger.Debug(msg, fields...)
-	if l.lokiClient != nil {
-		formattedMsg := formatMessage(msg, fields)
-		_ = l.lokiClient.Debug(formattedMsg)
-	}
+	l.forward(context.Background(), zapcore.DebugLevel, msg, fields)
 }
 
 func (l *Logger) Info(msg string, fields ...zap.Field) {
+	fields = l.prepareFields(zapcore.InfoLevel, fields)
 	l.Logger.Info(msg, fields...)
-	if l.lokiClient != nil {
-		formattedMsg := formatMessage(msg, fields)
-		_ = l.lokiClient.Info(formattedMsg)
-	}
+	l.forward(context.Background(), zapcore.InfoLevel, msg, fields)
 }
 
 func (l *Logger) Warn(msg string, fields ...zap.Field) {
+	fields = l.prepareFields(zapcore.WarnLevel, fields)
 	l.Logger.Warn(msg, fields...)
-	if l.lokiClient != nil {
-		formattedMsg := formatMessage(msg, fields)
-		_ = l.lokiClient.Warn(formattedMsg)
-	}
+	l.forward(context.Background(), zapcore.WarnLevel, msg, fields)
 }
 
 func (l *Logger) Error(msg string, fields ...zap.Field) {
+	fields = l.prepareFields(zapcore.ErrorLevel, fields)
 	l.Logger.Error(msg, fields...)
-	if l.lokiClient != nil {
-		formattedMsg := formatMessage(msg, fields)
-		_ = l.lokiClient.Error(formattedMsg)
-	}
+	l.forward(context.Background(), zapcore.ErrorLevel, msg, fields)
 }
 
 func (l *Logger) DPanic(msg string, fields ...zap.Field) {
-	l.Logger.DPanic(msg, fields...)
-	if l.lokiClient != nil {
-		formattedMsg := formatMessage(msg, fields)
-		_ = l.lokiClient.Error(formattedMsg) // Loki 没有 DPanic 级别，使用 Error
-	}
+	fields = l.prepareFields(zapcore.DPanicLevel, fields)
+	l.forward(context.Background(), zapcore.DPanicLevel, msg, fields)
+	l.Logger.DPanic(msg, fields...) // 开发模式下 DPanic 会 panic，所以先发送到 Loki
 }
 
 func (l *Logger) Panic(msg string, fields ...zap.Field) {
-	l.Logger.Panic(msg, fields...)
-	if l.lokiClient != nil {
-		formattedMsg := formatMessage(msg, fields)
-		_ = l.lokiClient.Error(formattedMsg) // Loki 没有 Panic 级别，使用 Error
-	}
+	fields = l.prepareFields(zapcore.PanicLevel, fields)
+	l.forward(context.Background(), zapcore.PanicLevel, msg, fields)
+	l.Logger.Panic(msg, fields...) // Panic 之后不会返回，所以先发送到 Loki
 }
 
 func (l *Logger) Fatal(msg string, fields ...zap.Field) {
-	if l.lokiClient != nil {
-		formattedMsg := formatMessage(msg, fields)
-		_ = l.lokiClient.Error(formattedMsg) // Loki 没有 Fatal 级别，使用 Error
-	}
+	fields = l.prepareFields(zapcore.FatalLevel, fields)
+	l.forward(context.Background(), zapcore.FatalLevel, msg, fields)
+	l.flushBeforeExit()
 	l.Logger.Fatal(msg, fields...) // Fatal 会导致程序退出，所以先发送到 Loki
 }
 
+// fatalFlushTimeout 是 Fatal 退出程序之前等待 Loki 发送完成的最长时间
+const fatalFlushTimeout = 5 * time.Second
+
+// flushBeforeExit 在 Fatal 退出程序之前同步发送 Loki 缓冲区中的日志
+// Loki 的日志先进入缓冲区再由后台协程批量发送，不等待的话 Fatal 日志几乎总会随进程退出而丢失。
+// 最多等待 fatalFlushTimeout，Loki 不可用时不会阻止程序退出
+func (l *Logger) flushBeforeExit() {
+	if l.lokiClient == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fatalFlushTimeout)
+	defer cancel()
+	_ = l.lokiClient.FlushContext(ctx)
+}
+
+// openDevice 判断日志路径是否指向设备文件
+// lumberjack 会对日志文件执行 stat 和 rename，无法用于设备文件，
+// 因此 /dev/stdout、/dev/stderr 直接使用对应的标准输出，其他字符设备以追加方式打开。
+// 返回的 bool 表示路径是否为设备，为 false 时应使用 lumberjack 写入普通文件。
+func openDevice(path string) (*os.File, bool, error) {
+	switch path {
+	case "/dev/stdout":
+		return os.Stdout, true, nil
+	case "/dev/stderr":
+		return os.Stderr, true, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		// 文件不存在或是普通文件，交给 lumberjack 处理
+		return nil, false, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, true, err
+	}
+	return f, true, nil
+}
+
+// 预设的时间格式
+const (
+	// TimeFormatRFC3339 是 RFC3339 格式，精确到秒，默认值
+	TimeFormatRFC3339 = "rfc3339"
+	// TimeFormatISO8601 是 ISO8601 格式，精确到毫秒
+	TimeFormatISO8601 = "iso8601"
+	// TimeFormatEpoch 是浮点数形式的Unix秒
+	TimeFormatEpoch = "epoch"
+	// TimeFormatEpochMillis 是浮点数形式的Unix毫秒
+	TimeFormatEpochMillis = "epochmillis"
+)
+
+// encoderConfig 返回控制台与文件输出使用的编码器配置
+// 未设置 EncoderConfig 时使用 zap 预设的 Production 编码器配置，并按 TimeFormat 设置时间编码
+func (cfg *Config) encoderConfig() (zapcore.EncoderConfig, error) {
+	if cfg.EncoderConfig != nil {
+		return *cfg.EncoderConfig, nil
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	timeEncoder, err := cfg.timeEncoder()
+	if err != nil {
+		return zapcore.EncoderConfig{}, err
+	}
+	encoderConfig.EncodeTime = timeEncoder
+	return encoderConfig, nil
+}
+
+// timeEncoder 返回控制台与文件输出使用的时间编码器
+// TimeFormat 优先于 TimeLayout，不是预设格式时作为 Go 时间布局处理
+func (cfg *Config) timeEncoder() (zapcore.TimeEncoder, error) {
+	format := cfg.TimeFormat
+	if format == "" {
+		format = cfg.TimeLayout
+	}
+
+	switch format {
+	case "", TimeFormatRFC3339:
+		return zapcore.RFC3339TimeEncoder, nil
+	case TimeFormatISO8601:
+		return zapcore.ISO8601TimeEncoder, nil
+	case TimeFormatEpoch:
+		return zapcore.EpochTimeEncoder, nil
+	case TimeFormatEpochMillis:
+		return zapcore.EpochMillisTimeEncoder, nil
+	}
+	if err := validateTimeLayout(format); err != nil {
+		return nil, err
+	}
+	return zapcore.TimeEncoderOfLayout(format), nil
+}
+
+// validateTimeLayout 检查时间布局是否有效
+// 不同时间格式化后的结果相同说明布局中没有任何时间占位符
+func validateTimeLayout(layout string) error {
+	t1 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	t2 := time.Date(2017, 11, 23, 8, 31, 47, 123000000, time.UTC)
+	if t1.Format(layout) == t2.Format(layout) {
+		return fmt.Errorf("无效的时间格式 %q: 不包含任何时间占位符", layout)
+	}
+	return nil
+}
+
+// prepareFields 在写入之前统一处理日志字段
+// 依次附加 goroutine 信息、转换字节类型的字段
+func (l *Logger) prepareFields(level zapcore.Level, fields []zap.Field) []zap.Field {
+	fields = l.goroutineInfo(level, fields)
+	return l.encodeBinaryFields(fields)
+}
+
+// maxPooledFields 是放回池中的编码器最多保留的字段数，避免个别大日志的 map 长期占用内存
+const maxPooledFields = 64
+
+// mapEncoderPool 复用 formatMessage 的字段编码器
+var mapEncoderPool = sync.Pool{
+	New: func() interface{} { return zapcore.NewMapObjectEncoder() },
+}
+
+// sortedFieldsPool 复用 formatMessage 排序字段的切片
+var sortedFieldsPool = sync.Pool{
+	New: func() interface{} { return new([]zap.Field) },
+}
+
+// messageBufferPool 复用 formatMessage 拼接消息的缓冲区
+var messageBufferPool = buffer.NewPool()
+
+// fieldEncoder 是只输出字段的 JSON 编码器，用于格式化发送到 Loki 的字段
+// 时间字段与 json.Marshal 一致编码为 RFC3339 字符串，时长字段编码为纳秒数
+var fieldEncoder = zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+	EncodeTime: zapcore.RFC3339NanoTimeEncoder,
+})
+
 // formatMessage 格式化日志消息，包含字段信息
+// 控制台和文件输出的编码结果无法复用：Loki 日志的字段在转发时会被移出 trace ID、
+// 结构化元数据和标签字段并经过转换器处理，控制台输出也可能不是 JSON 格式，
+// 因此字段需要单独编码一次。为了降低这次编码的开销，优先将字段按名称排序后使用 zap 的 JSON 编码器，
+// 不经过 map 和反射，输出与 json.Marshal 相同，只是不转义 HTML 字符。
+// 字段中有重复的字段名、命名空间、NaN 或无穷大的浮点数、复数时，zap 的输出与 json.Marshal 不同，
+// 这些日志仍通过 map 编码，同名字段只保留最后一个，无法编码的字段替换为 <key>Error 字段
 func formatMessage(msg string, fields []zap.Field) string {
 	if len(fields) == 0 {
 		return msg
 	}
 
-	// 创建一个临时的编码器来格式化字段
-	enc := zapcore.NewMapObjectEncoder()
+	buf := messageBufferPool.Get()
+	defer buf.Free()
+	buf.AppendString(msg)
+	buf.AppendByte(' ')
+
+	if directEncodable(fields) {
+		sorted := sortedFieldsPool.Get().(*[]zap.Field)
+		*sorted = append((*sorted)[:0], fields...)
+		slices.SortStableFunc(*sorted, func(a, b zap.Field) int { return strings.Compare(a.Key, b.Key) })
+		encoded, err := fieldEncoder.EncodeEntry(zapcore.Entry{}, *sorted)
+		clear(*sorted)
+		sortedFieldsPool.Put(sorted)
+		if err == nil {
+			encoded.TrimNewline()
+			_, _ = buf.Write(encoded.Bytes())
+			encoded.Free()
+			return buf.String()
+		}
+	}
+
+	enc, pooled := getMapEncoder(fields)
 	for _, field := range fields {
 		field.AddTo(enc)
 	}
 
-	// 将字段转换为 JSON 字符串
-	fieldsJSON, err := json.Marshal(enc.Fields)
-	if err != nil {
-		return msg
+	// 与 json.Marshal 的输出一致，只是直接写入缓冲区，并去掉 Encode 追加的换行
+	// 编码失败时 Encode 不会写入任何内容
+	if err := json.NewEncoder(buf).Encode(enc.Fields); err != nil {
+		_, _ = buf.Write(marshalFieldsIsolated(enc.Fields))
+	} else {
+		buf.TrimNewline()
+	}
+	if pooled {
+		putMapEncoder(enc)
 	}
 
-	return fmt.Sprintf("%s %s", msg, string(fieldsJSON))
+	return buf.String()
+}
+
+// directEncodable 判断字段能否直接用 fieldEncoder 编码，且结果与 json.Marshal 等价
+func directEncodable(fields []zap.Field) bool {
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.Float64Type:
+			if v := math.Float64frombits(uint64(f.Integer)); math.IsNaN(v) || math.IsInf(v, 0) {
+				return false
+			}
+		case zapcore.Float32Type:
+			if v := float64(math.Float32frombits(uint32(f.Integer))); math.IsNaN(v) || math.IsInf(v, 0) {
+				return false
+			}
+		case zapcore.Complex128Type, zapcore.Complex64Type, zapcore.NamespaceType:
+			return false
+		case zapcore.SkipType:
+			continue
+		}
+		for _, prev := range fields[:i] {
+			if prev.Key == f.Key && prev.Type != zapcore.SkipType {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// getMapEncoder 返回一个空的字段编码器，以及它是否来自对象池
+// zap.Namespace 会让编码器之后的字段写入嵌套的 map，且无法重置，这种情况下不使用对象池
+func getMapEncoder(fields []zap.Field) (*zapcore.MapObjectEncoder, bool) {
+	for _, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			return zapcore.NewMapObjectEncoder(), false
+		}
+	}
+	return mapEncoderPool.Get().(*zapcore.MapObjectEncoder), true
+}
+
+// putMapEncoder 清空编码器并放回对象池
+func putMapEncoder(enc *zapcore.MapObjectEncoder) {
+	if len(enc.Fields) > maxPooledFields {
+		return
+	}
+	clear(enc.Fields)
+	mapEncoderPool.Put(enc)
+}
+
+// marshalFieldsIsolated 逐个编码字段，用于整体编码失败的情况，例如字段中包含 NaN 或 chan
+// 与 zap 的 JSON 编码器一致，无法编码的字段被替换为 <key>Error 字段，值为错误信息，
+// 其余字段照常输出
+func marshalFieldsIsolated(fields map[string]interface{}) []byte {
+	isolated := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if _, err := json.Marshal(v); err != nil {
+			isolated[k+"Error"] = err.Error()
+			continue
+		}
+		isolated[k] = v
+	}
+
+	// 剩下的字段都可以编码，这里不会失败
+	data, _ := json.Marshal(isolated)
+	return data
+}
+
+// DroppedEvents 返回最近的 Loki 日志丢弃事件，未启用 Loki 时返回 nil
+func (l *Logger) DroppedEvents() []loki.DropEvent {
+	if l.lokiClient == nil {
+		return nil
+	}
+	return l.lokiClient.DroppedEvents()
+}
+
+// LokiLastError 返回最近一次发送到 Loki 失败的错误，从未失败或未启用 Loki 时返回 nil
+// 写日志的方法不返回错误：日志先进入缓冲区，发送在后台进行，
+// 配置 SyncDelivery 后可以在写日志之后立即通过该方法检查是否发送失败
+func (l *Logger) LokiLastError() error {
+	if l.lokiClient == nil {
+		return nil
+	}
+	return l.lokiClient.LastError()
+}
+
+// LokiClient 返回底层的 Loki 客户端，未启用 Loki 时返回 nil
+// 可用于调用 Flush、Stats 等方法。客户端由日志器管理，不要单独调用 Stop，应通过 Close 关闭
+func (l *Logger) LokiClient() *loki.Client {
+	return l.lokiClient
 }
 
 // Close 关闭日志器
 func (l *Logger) Close() error {
-	// 先同步 zap logger
+	return l.CloseContext(context.Background())
+}
+
+// CloseContext 关闭日志器，ctx 结束时中断仍在进行的 Loki 发送
+// 适合在服务的优雅关闭流程中使用
+func (l *Logger) CloseContext(ctx context.Context) error {
+	// 先输出去重窗口中的汇总日志，再同步 zap logger
+	l.deduper.flush()
+	l.lokiDeduper.flush()
 	err := l.Logger.Sync()
 
-	// 然后关闭 Loki 客户端
+	// 然后关闭 Loki 客户端和 OTLP 导出器
 	if l.lokiClient != nil {
-		l.lokiClient.Stop()
+		err = errors.Join(err, l.lokiClient.Stop(ctx))
+	}
+	if l.otlpExporter != nil {
+		err = errors.Join(err, l.otlpExporter.Shutdown(ctx))
 	}
 
 	// 最后关闭文件日志
 	if l.fileLogger != nil {
 		_ = l.fileLogger.Close()
 	}
+	if l.deviceFile != nil {
+		_ = l.deviceFile.Close()
+	}
+	if l.syslogConn != nil {
+		_ = l.syslogConn.Close()
+	}
 
 	return err
 }
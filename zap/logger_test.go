@@ -0,0 +1,561 @@
+package zap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bt-smart/btlog/pkg"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newFileLogger 创建只输出到临时文件的日志器，返回日志器和文件路径
+func newFileLogger(t *testing.T, cfg Config) (*Logger, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg.EnableFile = true
+	cfg.FilePath = path
+	logger, err := NewLogger(&cfg)
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	return logger, path
+}
+
+// readFileLines 关闭日志器后按行解析日志文件中的 JSON 记录
+func readFileLines(t *testing.T, logger *Logger, path string) []map[string]interface{} {
+	t.Helper()
+
+	_ = logger.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("unmarshal log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestTimeLayoutInFileOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  string
+		pattern string
+	}{
+		{"default RFC3339", "", `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(Z|[+-]\d{2}:\d{2})$`},
+		{"custom layout", "2006-01-02 15:04:05.000", `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, path := newFileLogger(t, Config{TimeLayout: tt.layout})
+			logger.Info("hello")
+
+			records := readFileLines(t, logger, path)
+			if len(records) != 1 {
+				t.Fatalf("got %d records, want 1", len(records))
+			}
+			ts, _ := records[0]["ts"].(string)
+			if !regexp.MustCompile(tt.pattern).MatchString(ts) {
+				t.Errorf("ts = %q, want match %s", ts, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		pattern string
+	}{
+		{"rfc3339", Config{TimeFormat: TimeFormatRFC3339}, `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(Z|[+-]\d{2}:\d{2})$`},
+		{"iso8601", Config{TimeFormat: TimeFormatISO8601}, `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}(Z|[+-]\d{4})$`},
+		{"epoch", Config{TimeFormat: TimeFormatEpoch}, `^\d{10}(\.\d+)?$`},
+		{"epochmillis", Config{TimeFormat: TimeFormatEpochMillis}, `^\d{13}(\.\d+)?$`},
+		{"custom layout", Config{TimeFormat: "2006/01/02 15:04"}, `^\d{4}/\d{2}/\d{2} \d{2}:\d{2}$`},
+		{"overrides TimeLayout", Config{TimeFormat: TimeFormatEpoch, TimeLayout: "15:04"}, `^\d{10}(\.\d+)?$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, path := newFileLogger(t, tt.cfg)
+			logger.Info("hello")
+
+			records := readFileLines(t, logger, path)
+			if len(records) != 1 {
+				t.Fatalf("got %d records, want 1", len(records))
+			}
+			// epoch 格式的时间是 JSON 数字
+			ts := fmt.Sprint(records[0]["ts"])
+			if f, ok := records[0]["ts"].(float64); ok {
+				ts = strconv.FormatFloat(f, 'f', -1, 64)
+			}
+			if !regexp.MustCompile(tt.pattern).MatchString(ts) {
+				t.Errorf("ts = %q, want match %s", ts, tt.pattern)
+			}
+		})
+	}
+
+	if _, err := NewLogger(&Config{TimeFormat: "unix"}); err == nil {
+		t.Error("NewLogger with unknown TimeFormat succeeded, want error")
+	}
+}
+
+func TestValidateTimeLayout(t *testing.T) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05.000", "15:04"} {
+		if err := validateTimeLayout(layout); err != nil {
+			t.Errorf("validateTimeLayout(%q) error = %v", layout, err)
+		}
+	}
+	for _, layout := range []string{"yyyy-MM-dd", "timestamp"} {
+		if err := validateTimeLayout(layout); err == nil {
+			t.Errorf("validateTimeLayout(%q) = nil, want error", layout)
+		}
+	}
+
+	if _, err := NewLogger(&Config{TimeLayout: "yyyy-MM-dd"}); err == nil {
+		t.Error("NewLogger with invalid TimeLayout succeeded, want error")
+	}
+}
+
+func TestOpenDevice(t *testing.T) {
+	regular := filepath.Join(t.TempDir(), "app.log")
+
+	tests := []struct {
+		path       string
+		wantDevice bool
+		wantFile   *os.File
+	}{
+		{"/dev/stdout", true, os.Stdout},
+		{"/dev/stderr", true, os.Stderr},
+		{"/dev/null", true, nil},
+		{regular, false, nil},
+	}
+	for _, tt := range tests {
+		f, isDevice, err := openDevice(tt.path)
+		if err != nil {
+			t.Fatalf("openDevice(%q) error = %v", tt.path, err)
+		}
+		if isDevice != tt.wantDevice {
+			t.Errorf("openDevice(%q) isDevice = %v, want %v", tt.path, isDevice, tt.wantDevice)
+		}
+		if tt.wantFile != nil && f != tt.wantFile {
+			t.Errorf("openDevice(%q) returned %v, want %v", tt.path, f, tt.wantFile)
+		}
+		if f != nil && f != os.Stdout && f != os.Stderr {
+			_ = f.Close()
+		}
+	}
+}
+
+func TestFilePathDeviceBypassesRotation(t *testing.T) {
+	stdout, err := NewLogger(&Config{EnableFile: true, FilePath: "/dev/stdout"})
+	if err != nil {
+		t.Fatalf("NewLogger(/dev/stdout) error = %v", err)
+	}
+	if stdout.fileLogger != nil || stdout.deviceFile != nil {
+		t.Error("/dev/stdout should write to os.Stdout without lumberjack or an opened file")
+	}
+
+	null, err := NewLogger(&Config{EnableFile: true, FilePath: "/dev/null"})
+	if err != nil {
+		t.Fatalf("NewLogger(/dev/null) error = %v", err)
+	}
+	if null.fileLogger != nil || null.deviceFile == nil {
+		t.Error("/dev/null should be opened directly without lumberjack")
+	}
+	null.Info("discarded")
+	_ = null.Close()
+
+	regular, path := newFileLogger(t, Config{})
+	if regular.fileLogger == nil || regular.deviceFile != nil {
+		t.Error("regular file should use lumberjack")
+	}
+	regular.Info("rotated")
+	if records := readFileLines(t, regular, path); len(records) != 1 {
+		t.Errorf("got %d records in regular file, want 1", len(records))
+	}
+}
+
+func TestFormatMessageIsolatesUnencodableFields(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{})
+	logger.Info("mixed",
+		zap.String("user", "alice"),
+		zap.Float64("ratio", math.NaN()),
+		zap.Any("ch", make(chan int)),
+		zap.Int("count", 3),
+	)
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	fields := lineFields(t, lines[0].Line)
+	if fields["user"] != "alice" || fields["count"] != float64(3) {
+		t.Errorf("fields = %v, want encodable fields kept", fields)
+	}
+	for _, key := range []string{"ratio", "ch"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("unencodable field %q present", key)
+		}
+		if msg, _ := fields[key+"Error"].(string); msg == "" {
+			t.Errorf("missing %sError in %v", key, fields)
+		}
+	}
+}
+
+func TestCloseContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	logger, err := NewLogger(&Config{
+		EnableLoki:       true,
+		LokiConfig:       LokiConfig{URL: server.URL},
+		SuppressWarnings: true,
+	})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	logger.Info("stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if err := logger.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CloseContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("CloseContext took %s, want it to return at the ctx deadline", elapsed)
+	}
+}
+
+func TestCustomEncoderConfig(t *testing.T) {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "@timestamp"
+	encoderConfig.LevelKey = "severity"
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	logger, path := newFileLogger(t, Config{
+		EncoderConfig: &encoderConfig,
+		// 设置了 EncoderConfig 时忽略
+		TimeFormat: TimeFormatEpoch,
+	})
+	logger.Warn("custom")
+
+	records := readFileLines(t, logger, path)
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	r := records[0]
+	if r["severity"] != "WARN" {
+		t.Errorf("severity = %v, want WARN", r["severity"])
+	}
+	if _, ok := r["@timestamp"].(string); !ok {
+		t.Errorf("@timestamp = %v, want ISO8601 string", r["@timestamp"])
+	}
+	if _, ok := r["ts"]; ok {
+		t.Errorf("record %v still has the default ts key", r)
+	}
+}
+
+// fatalHook 代替 os.Exit 的 Fatal 钩子，记录被调用时 Loki 已经收到的日志
+type fatalHook struct {
+	server *fakeLoki
+	lines  []lokiLine
+	called bool
+}
+
+func (h *fatalHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	h.called = true
+	h.lines = h.server.Lines()
+}
+
+func TestFatalFlushesLoki(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{LokiLevel: zapcore.InfoLevel})
+	defer logger.Close()
+	hook := &fatalHook{server: server}
+	logger.Logger = logger.Logger.WithOptions(zap.WithFatalHook(hook))
+
+	logger.Info("before")
+	logger.Fatal("boom")
+
+	if !hook.called {
+		t.Fatal("fatal hook was not called")
+	}
+	if len(hook.lines) != 2 || hook.lines[0].Line != "before" || hook.lines[1].Line != "boom" {
+		t.Errorf("Loki lines before exit = %+v, want before and boom", hook.lines)
+	}
+
+	// 日志作用域中的 Fatal 日志同样在退出之前发送
+	ctx, end := logger.BeginLogScope(context.Background())
+	defer end()
+	hook.called = false
+	logger.FatalCtx(ctx, "scoped boom")
+	if !hook.called || len(hook.lines) != 3 || hook.lines[2].Line != "scoped boom" {
+		t.Errorf("Loki lines before exit = %+v, want scoped boom", hook.lines)
+	}
+}
+
+func TestLokiTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	logger, err := NewLogger(&Config{EnableLoki: true, SuppressWarnings: true, LokiConfig: LokiConfig{
+		URL:         slow.URL,
+		Timeout:     1,
+		OnSendError: func([]pkg.LogEntry, error) {},
+	}})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	logger.Info("hello")
+	start := time.Now()
+	err = logger.LokiClient().Flush()
+	if err == nil || !strings.Contains(err.Error(), "Client.Timeout") {
+		t.Errorf("Flush() error = %v, want the HTTP client timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Flush() took %s, want about 1s", elapsed)
+	}
+}
+
+func TestOTLPOutput(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(data))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	logger, err := NewLogger(&Config{
+		EnableOTLP:       true,
+		OTLPLevel:        zapcore.WarnLevel,
+		OTLPConfig:       OTLPConfig{Endpoint: collector.URL},
+		SuppressWarnings: true,
+	})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	logger.Info("filtered")
+	logger.Warn("exported")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	all := strings.Join(bodies, "\n")
+	if !strings.Contains(all, `"exported"`) || strings.Contains(all, `"filtered"`) {
+		t.Errorf("OTLP requests = %s, want only the Warn entry", all)
+	}
+
+	if _, err := NewLogger(&Config{EnableOTLP: true}); err == nil {
+		t.Error("NewLogger() error = nil, want missing endpoint error")
+	}
+}
+
+func BenchmarkFormatMessage(b *testing.B) {
+	fields := []zap.Field{
+		zap.String("user", "alice"),
+		zap.Int("attempt", 3),
+		zap.Duration("elapsed", 150*time.Millisecond),
+		zap.Bool("cached", false),
+		zap.String("path", "/api/v1/orders"),
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = formatMessage("request handled", fields)
+	}
+}
+
+func TestFormatMessageReusesEncoders(t *testing.T) {
+	tests := []struct {
+		fields []zap.Field
+		want   string
+	}{
+		{[]zap.Field{zap.String("a", "1"), zap.Int("n", 2)}, `msg {"a":"1","n":2}`},
+		// 字段按名称排序，不转义 HTML 字符
+		{[]zap.Field{zap.String("z", "<x>"), zap.Time("t", time.Unix(0, 0).UTC()), zap.Duration("d", time.Second)},
+			`msg {"d":1000000000,"t":"1970-01-01T00:00:00Z","z":"<x>"}`},
+		// 以下情况通过 map 编码
+		{[]zap.Field{zap.Namespace("ns"), zap.String("k", "v")}, `msg {"ns":{"k":"v"}}`},
+		{[]zap.Field{zap.Int("k", 1), zap.Int("k", 2)}, `msg {"k":2}`},
+		{[]zap.Field{zap.Float64("f", math.Inf(1)), zap.Skip()}, `msg {"fError":"json: unsupported value: +Inf"}`},
+		{[]zap.Field{zap.String("c", "3")}, `msg {"c":"3"}`},
+		{nil, "msg"},
+	}
+	// 多次执行，确保放回对象池的编码器不会残留上一条日志的字段
+	for i := 0; i < 3; i++ {
+		for _, tt := range tests {
+			if got := formatMessage("msg", tt.fields); got != tt.want {
+				t.Errorf("formatMessage() = %q, want %q", got, tt.want)
+			}
+		}
+	}
+}
+
+func BenchmarkLoggerInfo(b *testing.B) {
+	fields := []zap.Field{
+		zap.String("user", "alice"),
+		zap.Int("attempt", 3),
+		zap.Duration("elapsed", 150*time.Millisecond),
+		zap.Bool("cached", false),
+		zap.String("path", "/api/v1/orders"),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	for _, bc := range []struct {
+		name string
+		loki bool
+	}{{"file", false}, {"file+loki", true}} {
+		b.Run(bc.name, func(b *testing.B) {
+			logger, err := NewLogger(&Config{
+				EnableFile:       true,
+				FilePath:         os.DevNull,
+				EnableLoki:       bc.loki,
+				LokiConfig:       LokiConfig{URL: server.URL, BatchSize: 1000},
+				SuppressWarnings: true,
+			})
+			if err != nil {
+				b.Fatalf("NewLogger() error = %v", err)
+			}
+			defer logger.Close()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.Info("request handled", fields...)
+			}
+		})
+	}
+}
+
+func TestLokiClientAccessor(t *testing.T) {
+	if NewNop().LokiClient() != nil {
+		t.Error("LokiClient() = non-nil, want nil when Loki is disabled")
+	}
+
+	logger, server := newLokiLogger(t, Config{})
+	defer logger.Close()
+	client := logger.LokiClient()
+	if client == nil {
+		t.Fatal("LokiClient() = nil, want the Loki client")
+	}
+
+	logger.Info("hello")
+	if err := client.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if lines := server.Lines(); len(lines) != 1 || lines[0].Line != "hello" {
+		t.Errorf("Loki lines = %+v, want hello", lines)
+	}
+	if stats := client.Stats(); stats.EntriesSent != 1 {
+		t.Errorf("Stats().EntriesSent = %d, want 1", stats.EntriesSent)
+	}
+}
+
+func TestNoOutputs(t *testing.T) {
+	logger, err := NewLogger(&Config{})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	if logger.Core().Enabled(zapcore.FatalLevel) {
+		t.Error("core is enabled, want a no-op core when no output is enabled")
+	}
+	logger.Info("dropped")
+	logger.Sugar().Errorw("dropped", "k", "v")
+	if err := logger.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	// 只启用 Loki 时 zap 使用 no-op core，日志仍然转发到 Loki
+	lokiOnly, server := newLokiLogger(t, Config{})
+	lokiOnly.Info("to loki")
+	if lines := closeAndCollect(t, lokiOnly, server); len(lines) != 1 || lines[0].Line != "to loki" {
+		t.Errorf("Loki lines = %+v, want to loki", lines)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	var errorCount, total int
+	logger, path := newFileLogger(t, Config{
+		FileLevel: zapcore.InfoLevel,
+		Hooks: []func(zapcore.Entry) error{
+			func(e zapcore.Entry) error {
+				total++
+				return nil
+			},
+			func(e zapcore.Entry) error {
+				if e.Level >= zapcore.ErrorLevel {
+					errorCount++
+				}
+				return nil
+			},
+		},
+	})
+
+	logger.Debug("filtered")
+	logger.Info("info")
+	logger.Error("first error")
+	logger.Named("db").Sugar().Errorf("second %s", "error")
+	_ = readFileLines(t, logger, path)
+
+	if total != 3 {
+		t.Errorf("hook saw %d entries, want 3 (debug is below FileLevel)", total)
+	}
+	if errorCount != 2 {
+		t.Errorf("error hook counted %d, want 2", errorCount)
+	}
+}
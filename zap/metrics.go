@@ -0,0 +1,36 @@
+package zap
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bt-smart/btlog/loki"
+)
+
+// MetricsHandler 返回以 Prometheus 文本格式输出 Loki 客户端统计的 HTTP 处理器
+// 不依赖 Prometheus 客户端库，可以直接挂载到 /metrics 供抓取
+// 未启用 Loki 时所有指标均为 0
+func (l *Logger) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var stats loki.Stats
+		if l.lokiClient != nil {
+			stats = l.lokiClient.Stats()
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetric(w, "btlog_logs_pushed_total", "counter", "Total number of log entries pushed to the Loki client.", stats.LogsPushed)
+		writeMetric(w, "btlog_batches_sent_total", "counter", "Total number of batches successfully sent to Loki.", stats.BatchesSent)
+		writeMetric(w, "btlog_entries_sent_total", "counter", "Total number of log entries successfully sent to Loki.", stats.EntriesSent)
+		writeMetric(w, "btlog_send_failures_total", "counter", "Total number of failed Loki push requests.", stats.SendFailures)
+		writeMetric(w, "btlog_dropped_total", "counter", "Total number of log entries dropped.", stats.Dropped)
+		writeMetric(w, "btlog_buffer_length", "gauge", "Number of log entries waiting in the buffer.", stats.BufferLength)
+	})
+}
+
+// writeMetric 输出一个不带标签的指标
+func writeMetric(w io.Writer, name, typ, help string, value interface{}) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	fmt.Fprintf(w, "%s %v\n", name, value)
+}
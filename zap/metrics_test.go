@@ -0,0 +1,107 @@
+package zap
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// parseExposition 解析 Prometheus 文本格式，返回指标值和类型
+func parseExposition(t *testing.T, body string) (map[string]float64, map[string]string) {
+	t.Helper()
+
+	values := make(map[string]float64)
+	types := make(map[string]string)
+	helps := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			parts := strings.SplitN(strings.TrimPrefix(line, "# HELP "), " ", 2)
+			if len(parts) != 2 || parts[1] == "" {
+				t.Fatalf("malformed HELP line %q", line)
+			}
+			helps[parts[0]] = true
+		case strings.HasPrefix(line, "# TYPE "):
+			parts := strings.Fields(strings.TrimPrefix(line, "# TYPE "))
+			if len(parts) != 2 || (parts[1] != "counter" && parts[1] != "gauge") {
+				t.Fatalf("malformed TYPE line %q", line)
+			}
+			types[parts[0]] = parts[1]
+		default:
+			parts := strings.Fields(line)
+			if len(parts) != 2 {
+				t.Fatalf("malformed sample line %q", line)
+			}
+			v, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				t.Fatalf("sample %q: %v", line, err)
+			}
+			if !helps[parts[0]] || types[parts[0]] == "" {
+				t.Fatalf("sample %q has no HELP or TYPE", line)
+			}
+			values[parts[0]] = v
+		}
+	}
+	return values, types
+}
+
+func TestMetricsHandler(t *testing.T) {
+	logger, _ := newLokiLogger(t, Config{})
+	defer logger.Close()
+
+	logger.Info("one")
+	logger.Info("two")
+	if err := logger.lokiClient.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	logger.Info("buffered")
+
+	rec := httptest.NewRecorder()
+	logger.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	values, types := parseExposition(t, rec.Body.String())
+	want := map[string]float64{
+		"btlog_logs_pushed_total":   3,
+		"btlog_batches_sent_total":  1,
+		"btlog_entries_sent_total":  2,
+		"btlog_send_failures_total": 0,
+		"btlog_dropped_total":       0,
+		"btlog_buffer_length":       1,
+	}
+	for name, v := range want {
+		got, ok := values[name]
+		if !ok {
+			t.Errorf("metric %s missing", name)
+			continue
+		}
+		if got != v {
+			t.Errorf("%s = %v, want %v", name, got, v)
+		}
+	}
+	if types["btlog_buffer_length"] != "gauge" || types["btlog_logs_pushed_total"] != "counter" {
+		t.Errorf("types = %v", types)
+	}
+}
+
+func TestMetricsHandlerWithoutLoki(t *testing.T) {
+	logger, err := NewLogger(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	logger.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	values, _ := parseExposition(t, rec.Body.String())
+	for name, v := range values {
+		if v != 0 {
+			t.Errorf("%s = %v, want 0 without Loki", name, v)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package zap
+
+import "go.uber.org/zap"
+
+// LoggerLabel 是日志器名称在 Loki 中的标签名
+const LoggerLabel = "logger"
+
+// Named 返回一个添加了名称的子日志器
+// 与 zap 一致，多次调用时名称以 "." 连接，例如 "payments.refund"。
+// 子日志器与原日志器共享同一个 Loki 客户端和输出，只需要对原日志器调用 Close。
+// 开启 AddLoggerLabel 时，名称会作为 logger 标签发送到 Loki；
+// 否则作为 logger 字段追加到 Loki 消息中，与文件输出一致，可以通过 | json 过滤。
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+
+	child := *l
+	child.Logger = l.Logger.Named(name)
+	if l.name == "" {
+		child.name = name
+	} else {
+		child.name = l.name + "." + name
+	}
+	return &child
+}
+
+// With 返回一个附加了固定字段的子日志器
+// 字段会出现在控制台、文件输出和发送到 Loki 的消息中，位于每次调用传入的字段之前。
+// 子日志器与原日志器共享同一个 Loki 客户端和输出，只需要对原日志器调用 Close。
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+
+	fields = l.encodeBinaryFields(fields)
+	child := *l
+	child.Logger = l.Logger.With(fields...)
+	child.fields = append(append([]zap.Field(nil), l.fields...), fields...)
+	return &child
+}
+
+// loggerField 在未开启 AddLoggerLabel 时将日志器名称作为 logger 字段加入 Loki 消息
+func (l *Logger) loggerField(e *Entry) {
+	if l.config.AddLoggerLabel || l.name == "" {
+		return
+	}
+	e.Fields = append(e.Fields, zap.String(LoggerLabel, l.name))
+}
+
+// loggerLabels 返回需要附加到 Loki 日志上的日志器名称标签
+func (l *Logger) loggerLabels(labels map[string]string) map[string]string {
+	if !l.config.AddLoggerLabel || l.name == "" {
+		return labels
+	}
+
+	merged := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		merged[k] = v
+	}
+	merged[LoggerLabel] = l.name
+	return merged
+}
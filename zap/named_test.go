@@ -0,0 +1,113 @@
+package zap
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestLoggerLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		addLabel bool
+		named    func(*Logger) *Logger
+		want     string
+	}{
+		{"unnamed", true, func(l *Logger) *Logger { return l }, ""},
+		{"named", true, func(l *Logger) *Logger { return l.Named("payments") }, "payments"},
+		{"nested", true, func(l *Logger) *Logger { return l.Named("payments").Named("refund") }, "payments.refund"},
+		{"empty name", true, func(l *Logger) *Logger { return l.Named("") }, ""},
+		{"disabled", false, func(l *Logger) *Logger { return l.Named("payments") }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, server := newLokiLogger(t, Config{AddLoggerLabel: tt.addLabel})
+			tt.named(logger).Info("hello")
+
+			lines := closeAndCollect(t, logger, server)
+			if len(lines) != 1 {
+				t.Fatalf("got %d lines, want 1", len(lines))
+			}
+			got, ok := lines[0].Labels[LoggerLabel]
+			if tt.want == "" && ok {
+				t.Errorf("logger label = %q, want none", got)
+			}
+			if tt.want != "" && got != tt.want {
+				t.Errorf("logger label = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerLabelSeparatesStreams(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{AddLoggerLabel: true})
+	logger.Named("payments").Info("a")
+	logger.Named("orders").Info("b")
+	logger.Info("c")
+
+	streams := make(map[string]string)
+	for _, line := range closeAndCollect(t, logger, server) {
+		streams[line.Line] = line.Labels[LoggerLabel]
+	}
+	want := map[string]string{"a": "payments", "b": "orders", "c": ""}
+	for line, label := range want {
+		if streams[line] != label {
+			t.Errorf("line %q has logger label %q, want %q", line, streams[line], label)
+		}
+	}
+}
+
+func TestWith(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, server := newLokiLogger(t, Config{EnableFile: true, FilePath: path})
+
+	child := logger.With(zap.String("request", "r1"))
+	child.With(zap.Int("attempt", 2)).Info("retry", zap.String("op", "pay"))
+	logger.Info("parent")
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if want := `retry {"attempt":2,"op":"pay","request":"r1"}`; lines[0].Line != want {
+		t.Errorf("child line = %q, want %q", lines[0].Line, want)
+	}
+	if lines[1].Line != "parent" {
+		t.Errorf("parent line = %q, want base fields not shared", lines[1].Line)
+	}
+
+	records := readFileLines(t, logger, path)
+	if len(records) != 2 {
+		t.Fatalf("got %d file records, want 2", len(records))
+	}
+	if records[0]["request"] != "r1" || records[0]["attempt"] != float64(2) {
+		t.Errorf("file record = %v, want base fields", records[0])
+	}
+	if _, ok := records[1]["request"]; ok {
+		t.Errorf("parent file record = %v, want no base fields", records[1])
+	}
+}
+
+func TestLoggerNameInMessage(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{})
+	logger.Named("payments").Named("refund").Info("a", zap.String("op", "pay"))
+	logger.Info("b")
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if want := `a {"logger":"payments.refund","op":"pay"}`; lines[0].Line != want {
+		t.Errorf("named line = %q, want %q", lines[0].Line, want)
+	}
+	if lines[1].Line != "b" {
+		t.Errorf("unnamed line = %q, want no logger field", lines[1].Line)
+	}
+	for _, line := range lines {
+		if _, ok := line.Labels[LoggerLabel]; ok {
+			t.Errorf("line %q has a logger label without AddLoggerLabel", line.Line)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package zap
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// NewNop 返回不输出任何日志的日志器
+// 用于测试或不关心日志的场景，所有方法都可以正常调用，不会写文件或访问网络
+func NewNop() *Logger {
+	return &Logger{
+		Logger: zap.NewNop(),
+		levels: newLevels(&Config{}),
+	}
+}
+
+// NewObserved 返回将日志保存在内存中的日志器，以及用于读取这些日志的 ObservedLogs
+// 用于在单元测试中断言输出的日志，不会写文件或访问网络。
+// level 决定保存的最低级别，例如 zapcore.DebugLevel 保存所有日志
+func NewObserved(level zapcore.LevelEnabler) (*Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(level)
+	return &Logger{
+		Logger: zap.New(core),
+		levels: newLevels(&Config{}),
+	}, logs
+}
@@ -0,0 +1,58 @@
+package zap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewNop(t *testing.T) {
+	logger := NewNop()
+	ctx, end := logger.BeginLogScope(context.Background())
+	logger.Info("info", zap.String("k", "v"))
+	logger.InfoCtx(ctx, "ctx")
+	logger.Named("sub").With(zap.Int("n", 1)).Sugar().Warnf("warn %d", 1)
+	logger.LogStartup()
+	end()
+
+	_ = logger.Health()
+	_ = logger.DroppedEvents()
+	rec := httptest.NewRecorder()
+	logger.LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("LevelHandler() status = %d, want 200", rec.Code)
+	}
+	if err := logger.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestNewObserved(t *testing.T) {
+	logger, logs := NewObserved(zapcore.InfoLevel)
+
+	logger.Debug("hidden")
+	logger.Info("hello", zap.String("k", "v"))
+	logger.Named("db").With(zap.Int("n", 1)).Error("failed")
+	logger.Sugar().Warnw("sugared", "user", "alice")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(entries), entries)
+	}
+	if e := entries[0]; e.Message != "hello" || e.ContextMap()["k"] != "v" {
+		t.Errorf("entry 0 = %+v", e)
+	}
+	if e := entries[1]; e.Message != "failed" || e.LoggerName != "db" || e.Level != zapcore.ErrorLevel || e.ContextMap()["n"] != int64(1) {
+		t.Errorf("entry 1 = %+v", e)
+	}
+	if got := logs.FilterField(zap.String("user", "alice")).Len(); got != 1 {
+		t.Errorf("sugared entries = %d, want 1", got)
+	}
+	if err := logger.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
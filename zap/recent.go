@@ -0,0 +1,104 @@
+package zap
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// recentLogs 是保存最近若干条日志的环形缓冲区
+type recentLogs struct {
+	mu    sync.Mutex
+	lines []string
+	// next 是下一条日志写入的位置
+	next int
+	// full 表示缓冲区是否已经写满过一轮
+	full bool
+}
+
+// newRecentLogs 创建最多保存 size 条日志的环形缓冲区
+func newRecentLogs(size int) *recentLogs {
+	return &recentLogs{lines: make([]string, size)}
+}
+
+// add 保存一条日志，缓冲区已满时覆盖最早的日志
+func (r *recentLogs) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines[r.next] = line
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// snapshot 按从早到晚的顺序返回缓冲区中的日志
+func (r *recentLogs) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	out := make([]string, 0, len(r.lines))
+	out = append(out, r.lines[r.next:]...)
+	return append(out, r.lines[:r.next]...)
+}
+
+// recentCore 是将日志编码为 JSON 后保存到环形缓冲区的 zap core
+type recentCore struct {
+	zapcore.LevelEnabler
+	enc  zapcore.Encoder
+	logs *recentLogs
+}
+
+// newRecentCore 创建写入 logs 的 core
+func newRecentCore(logs *recentLogs, encoderConfig zapcore.EncoderConfig, enab zapcore.LevelEnabler) *recentCore {
+	return &recentCore{LevelEnabler: enab, enc: zapcore.NewJSONEncoder(encoderConfig), logs: logs}
+}
+
+// With 返回附加了字段的 core，与原 core 共享同一个缓冲区
+func (c *recentCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &recentCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), logs: c.logs}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return clone
+}
+
+// Check 实现 zapcore.Core
+func (c *recentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 编码日志并保存到缓冲区
+func (c *recentCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	c.logs.add(strings.TrimSuffix(buf.String(), "\n"))
+	buf.Free()
+	return nil
+}
+
+// Sync 实现 zapcore.Core，缓冲区在内存中，无需同步
+func (c *recentCore) Sync() error {
+	return nil
+}
+
+// RecentLogs 按从早到晚的顺序返回最近的日志，每条是一行 JSON
+// 最多返回 Config.RecentLogsSize 条，未启用时返回 nil。
+// 可用于实现 /debug/logs 之类的调试接口
+func (l *Logger) RecentLogs() []string {
+	if l.recentLogs == nil {
+		return nil
+	}
+	return l.recentLogs.snapshot()
+}
@@ -0,0 +1,89 @@
+package zap
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRecentLogsRing(t *testing.T) {
+	r := newRecentLogs(3)
+	if got := r.snapshot(); len(got) != 0 {
+		t.Errorf("snapshot() = %q, want empty", got)
+	}
+	for i := 1; i <= 2; i++ {
+		r.add(fmt.Sprint(i))
+	}
+	if got := fmt.Sprint(r.snapshot()); got != "[1 2]" {
+		t.Errorf("snapshot() = %s, want [1 2]", got)
+	}
+	for i := 3; i <= 7; i++ {
+		r.add(fmt.Sprint(i))
+	}
+	if got := fmt.Sprint(r.snapshot()); got != "[5 6 7]" {
+		t.Errorf("snapshot() = %s, want [5 6 7]", got)
+	}
+}
+
+func TestRecentLogs(t *testing.T) {
+	logger, err := NewLogger(&Config{RecentLogsSize: 2, RecentLogsLevel: zapcore.InfoLevel})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	logger.Debug("filtered")
+	logger.Info("first")
+	logger.With(zap.String("k", "v")).Warn("second")
+	logger.Named("db").Error("third")
+
+	lines := logger.RecentLogs()
+	if len(lines) != 2 {
+		t.Fatalf("RecentLogs() = %q, want 2 lines", lines)
+	}
+	var second, third map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &second); err != nil {
+		t.Fatalf("line %q is not JSON: %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &third); err != nil {
+		t.Fatalf("line %q is not JSON: %v", lines[1], err)
+	}
+	if second["msg"] != "second" || second["k"] != "v" || second["level"] != "warn" {
+		t.Errorf("line 0 = %v", second)
+	}
+	if third["msg"] != "third" || third["logger"] != "db" {
+		t.Errorf("line 1 = %v", third)
+	}
+
+	if got := NewNop().RecentLogs(); got != nil {
+		t.Errorf("RecentLogs() = %q, want nil when disabled", got)
+	}
+}
+
+func TestRecentLogsConcurrent(t *testing.T) {
+	logger, err := NewLogger(&Config{RecentLogsSize: 10})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer logger.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("msg")
+				_ = logger.RecentLogs()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := len(logger.RecentLogs()); got != 10 {
+		t.Errorf("RecentLogs() has %d lines, want 10", got)
+	}
+}
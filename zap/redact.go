@@ -0,0 +1,76 @@
+package zap
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// redactor 在日志发送到 Loki 之前脱敏或去掉敏感字段
+// 只检查顶层字段的字段名，对象和数组内部的字段不做处理
+type redactor struct {
+	// keys 是小写的敏感字段名
+	keys map[string]struct{}
+	// pattern 匹配敏感字段名，为 nil 时只按 keys 匹配
+	pattern *regexp.Regexp
+	// drop 为 true 时去掉敏感字段，否则将取值替换为 [REDACTED]
+	drop bool
+}
+
+// newRedactor 根据 Loki 配置创建脱敏器，没有配置敏感字段时返回 nil
+func newRedactor(lc LokiConfig) (*redactor, error) {
+	if len(lc.RedactKeys) == 0 && lc.RedactKeyPattern == "" {
+		return nil, nil
+	}
+
+	r := &redactor{keys: make(map[string]struct{}, len(lc.RedactKeys)), drop: lc.RedactDrop}
+	for _, k := range lc.RedactKeys {
+		r.keys[strings.ToLower(k)] = struct{}{}
+	}
+	if lc.RedactKeyPattern != "" {
+		pattern, err := regexp.Compile(lc.RedactKeyPattern)
+		if err != nil {
+			return nil, fmt.Errorf("LokiConfig.RedactKeyPattern 无效: %v", err)
+		}
+		r.pattern = pattern
+	}
+	return r, nil
+}
+
+// match 判断字段名是否是敏感字段，RedactKeys 不区分大小写
+func (r *redactor) match(key string) bool {
+	if _, ok := r.keys[strings.ToLower(key)]; ok {
+		return true
+	}
+	return r.pattern != nil && r.pattern.MatchString(key)
+}
+
+// redact 返回脱敏后的字段，没有敏感字段时原样返回，不会修改传入的切片
+func (r *redactor) redact(fields []zap.Field) []zap.Field {
+	if r == nil {
+		return fields
+	}
+
+	var result []zap.Field
+	for i, field := range fields {
+		if !r.match(field.Key) {
+			if result != nil {
+				result = append(result, field)
+			}
+			continue
+		}
+		if result == nil {
+			result = make([]zap.Field, i, len(fields))
+			copy(result, fields[:i])
+		}
+		if !r.drop {
+			result = append(result, zap.String(field.Key, RedactedValue))
+		}
+	}
+	if result == nil {
+		return fields
+	}
+	return result
+}
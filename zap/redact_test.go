@@ -0,0 +1,115 @@
+package zap
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		loki     LokiConfig
+		wantLine string
+	}{
+		{
+			name:     "mask",
+			loki:     LokiConfig{RedactKeys: []string{"password", "SSN"}},
+			wantLine: `login {"api_token":"t-1","password":"[REDACTED]","ssn":"[REDACTED]","user":"alice"}`,
+		},
+		{
+			name:     "drop",
+			loki:     LokiConfig{RedactKeys: []string{"password", "ssn"}, RedactDrop: true},
+			wantLine: `login {"api_token":"t-1","user":"alice"}`,
+		},
+		{
+			name:     "pattern",
+			loki:     LokiConfig{RedactKeys: []string{"password"}, RedactKeyPattern: `(?i)(token|ssn)$`},
+			wantLine: `login {"api_token":"[REDACTED]","password":"[REDACTED]","ssn":"[REDACTED]","user":"alice"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			logger, server := newLokiLogger(t, Config{EnableFile: true, FilePath: path, LokiConfig: tt.loki})
+
+			// With 附加的字段同样被脱敏
+			logger.With(zap.String("password", "hunter2")).Info("login",
+				zap.String("user", "alice"),
+				zap.String("ssn", "123-45-6789"),
+				zap.String("api_token", "t-1"),
+			)
+
+			lines := closeAndCollect(t, logger, server)
+			if len(lines) != 1 {
+				t.Fatalf("got %d lines, want 1", len(lines))
+			}
+			if lines[0].Line != tt.wantLine {
+				t.Errorf("Loki line = %q, want %q", lines[0].Line, tt.wantLine)
+			}
+
+			// 本地文件保留原始字段
+			records := readFileLines(t, logger, path)
+			if len(records) != 1 || records[0]["password"] != "hunter2" || records[0]["ssn"] != "123-45-6789" {
+				t.Errorf("file records = %v, want sensitive fields kept", records)
+			}
+		})
+	}
+}
+
+func TestRedactBeforeExtraction(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{LokiConfig: LokiConfig{
+		RedactKeys:             []string{"authorization", "tenant"},
+		StructuredMetadataKeys: []string{"authorization"},
+		LabelKeys:              []string{"tenant"},
+		MaxStreamsPerFlush:     10,
+	}})
+
+	logger.Info("request", zap.String("authorization", "Bearer abc"), zap.String("tenant", "acme"))
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	if got := lines[0].Metadata["authorization"]; got != RedactedValue {
+		t.Errorf("metadata authorization = %q, want %q", got, RedactedValue)
+	}
+	if got := lines[0].Labels["tenant"]; got != RedactedValue {
+		t.Errorf("label tenant = %q, want %q", got, RedactedValue)
+	}
+	if strings.Contains(lines[0].Line, "abc") || strings.Contains(lines[0].Line, "acme") {
+		t.Errorf("line %q contains a sensitive value", lines[0].Line)
+	}
+}
+
+func TestRedactKeyPatternInvalid(t *testing.T) {
+	cfg := Config{EnableLoki: true, LokiConfig: LokiConfig{URL: "http://loki:3100", RedactKeyPattern: "("}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "RedactKeyPattern") {
+		t.Errorf("Validate() error = %v, want RedactKeyPattern error", err)
+	}
+}
+
+func TestWrapZapRedact(t *testing.T) {
+	core, observed := observer.New(zapcore.InfoLevel)
+	server := newFakeLoki(t)
+	logger, err := WrapZap(zap.New(core), LokiConfig{URL: server.URL, RedactKeys: []string{"password"}})
+	if err != nil {
+		t.Fatalf("WrapZap() error = %v", err)
+	}
+
+	logger.Info("login", zap.String("password", "hunter2"))
+	lines := closeAndCollect(t, logger, server)
+
+	if len(lines) != 1 || lineFields(t, lines[0].Line)["password"] != RedactedValue {
+		t.Errorf("Loki lines = %+v, want password redacted", lines)
+	}
+	// 原日志器的输出不受影响
+	if entries := observed.All(); len(entries) != 1 || entries[0].ContextMap()["password"] != "hunter2" {
+		t.Errorf("base entries = %+v, want password kept", entries)
+	}
+}
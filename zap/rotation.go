@@ -0,0 +1,130 @@
+package zap
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// RotationDaily 每天切换到一个新的日志文件，文件名带有日期，例如 app-2006-01-02.log
+	RotationDaily = "daily"
+	// RotationHourly 每小时切换到一个新的日志文件，文件名带有日期和小时，例如 app-2006-01-02-15.log
+	RotationHourly = "hourly"
+)
+
+// rotationLayout 返回按时间切割时文件名中日期的格式，不按时间切割时返回空字符串
+func rotationLayout(interval string) string {
+	switch interval {
+	case RotationDaily:
+		return "2006-01-02"
+	case RotationHourly:
+		return "2006-01-02-15"
+	default:
+		return ""
+	}
+}
+
+// validRotationInterval 判断 RotationInterval 是否是支持的取值，空字符串表示只按大小切割
+func validRotationInterval(interval string) bool {
+	return interval == "" || rotationLayout(interval) != ""
+}
+
+// datedPath 在文件名和扩展名之间插入日期，例如 logs/app.log 变为 logs/app-2006-01-02.log
+func datedPath(path, period string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + period + ext
+}
+
+// rotatingFile 是由 lumberjack 按大小切割的日志文件
+// 设置了 RotationInterval 时，每个周期写入一个带日期的文件，周期内仍按 MaxSize 切割。
+// 切换在周期开始后的第一次写入时进行，没有日志的周期不会产生空文件。
+// lumberjack 的 MaxBackups 和 MaxAge 只清理同一个周期内切割出的旧文件，
+// 不同日期的文件可以由外部按文件名中的日期清理
+type rotatingFile struct {
+	mu sync.Mutex
+	// path 是配置的文件路径，按时间切割时在其中插入日期
+	path       string
+	maxSize    int
+	maxBackups int
+	maxAge     int
+	compress   bool
+	// layout 是文件名中日期的格式，为空时不按时间切割
+	layout string
+	// now 返回当前时间，便于测试
+	now func() time.Time
+	// period 是当前文件所属的周期
+	period string
+	// current 是当前写入的文件
+	current *lumberjack.Logger
+}
+
+// newRotatingFile 按配置创建日志文件，文件在第一次写入时才会被创建
+func newRotatingFile(cfg *Config) *rotatingFile {
+	f := &rotatingFile{
+		path:       cfg.FilePath,
+		maxSize:    cfg.MaxSize,
+		maxBackups: cfg.MaxBackups,
+		maxAge:     cfg.MaxAge,
+		compress:   cfg.Compress,
+		layout:     rotationLayout(cfg.RotationInterval),
+		now:        time.Now,
+	}
+	f.rotate()
+	return f
+}
+
+// rotate 在进入新的周期时关闭当前文件，切换到新周期的文件，调用方需持有锁
+// lumberjack 的清理协程会读取 Filename，因此每个周期创建新的 lumberjack.Logger 而不是修改原来的
+func (f *rotatingFile) rotate() {
+	filename := f.path
+	if f.layout != "" {
+		period := f.now().Format(f.layout)
+		if f.current != nil && period == f.period {
+			return
+		}
+		f.period = period
+		filename = datedPath(f.path, period)
+	} else if f.current != nil {
+		return
+	}
+
+	if f.current != nil {
+		_ = f.current.Close()
+	}
+	f.current = &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    f.maxSize,
+		MaxBackups: f.maxBackups,
+		MaxAge:     f.maxAge,
+		Compress:   f.compress,
+	}
+}
+
+// Write 实现 io.Writer，写入当前周期的文件
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rotate()
+	return f.current.Write(p)
+}
+
+// Close 关闭当前文件
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.current.Close()
+}
+
+// filename 返回当前写入的文件路径
+func (f *rotatingFile) filename() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.current.Filename
+}
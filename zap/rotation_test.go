@@ -0,0 +1,103 @@
+package zap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDatedPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"logs/app.log", "logs/app-2024-03-01.log"},
+		{"logs/app", "logs/app-2024-03-01"},
+		{"app.json.log", "app.json-2024-03-01.log"},
+	}
+	for _, tt := range tests {
+		if got := datedPath(tt.path, "2024-03-01"); got != tt.want {
+			t.Errorf("datedPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRotatingFileByTime(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     []string
+	}{
+		{RotationDaily, []string{"app-2024-03-01.log", "app-2024-03-02.log"}},
+		{RotationHourly, []string{"app-2024-03-01-23.log", "app-2024-03-02-00.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			dir := t.TempDir()
+			now := time.Date(2024, 3, 1, 23, 10, 0, 0, time.Local)
+			f := newRotatingFile(&Config{FilePath: filepath.Join(dir, "app.log"), RotationInterval: tt.interval})
+			f.now = func() time.Time { return now }
+			defer f.Close()
+
+			// 前两条在同一个周期内，第三条跨过零点，同时进入新的一天和新的一小时
+			for i, line := range []string{"a\n", "b\n", "c\n"} {
+				if i == 1 {
+					now = now.Add(40 * time.Minute)
+				}
+				if i == 2 {
+					now = now.Add(20 * time.Minute)
+				}
+				if _, err := f.Write([]byte(line)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+
+			wantContent := []string{"a\nb\n", "c\n"}
+			for i, name := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatalf("read %s: %v", name, err)
+				}
+				if string(data) != wantContent[i] {
+					t.Errorf("%s = %q, want %q", name, data, wantContent[i])
+				}
+			}
+			if got := f.filename(); got != filepath.Join(dir, tt.want[1]) {
+				t.Errorf("filename() = %q, want the current period's file", got)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "app.log")); !os.IsNotExist(err) {
+				t.Errorf("undated app.log exists, want only dated files (err = %v)", err)
+			}
+		})
+	}
+}
+
+func TestRotationIntervalConfig(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := NewLogger(&Config{
+		EnableFile:       true,
+		FilePath:         filepath.Join(dir, "app.log"),
+		RotationInterval: RotationDaily,
+		SuppressWarnings: true,
+	})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	logger.Info("hello")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(datedPath(filepath.Join(dir, "app.log"), time.Now().Format("2006-01-02")))
+	if err != nil {
+		t.Fatalf("read dated log file: %v", err)
+	}
+	if !strings.Contains(string(data), "hello") {
+		t.Errorf("dated log file = %q, want the entry", data)
+	}
+
+	if _, err := NewLogger(&Config{EnableFile: true, FilePath: filepath.Join(dir, "x.log"), RotationInterval: "weekly"}); err == nil {
+		t.Error("NewLogger() with RotationInterval weekly error = nil, want error")
+	}
+}
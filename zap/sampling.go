@@ -0,0 +1,154 @@
+package zap
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SampledDroppedKey 是采样丢弃条数的字段名
+// 某条消息被采样丢弃后，下一条保留下来的同类日志会带上该字段，
+// 值为两者之间被丢弃的条数，便于在 Grafana 中还原真实的日志频率
+const SampledDroppedKey = "sampled_dropped"
+
+// samplerBuckets 是每个级别的计数桶个数，消息按哈希值分桶计数
+const samplerBuckets = 4096
+
+// SamplingConfig 定义日志采样配置
+// 在每个 Tick 周期内，级别和消息都相同的日志只保留前 Initial 条，
+// 之后每 Thereafter 条保留一条，用于在故障期间限制重复日志的数量。
+// 采样同时作用于控制台、文件和 Loki 输出，各输出保留的日志相同。
+// 达到 BypassLevel 的日志不参与采样，总是输出到所有目标。
+type SamplingConfig struct {
+	// Initial 是每个周期内每条消息保留的前几条日志
+	Initial int
+	// Thereafter 是超过 Initial 之后每隔多少条保留一条，为 0 时丢弃之后的所有日志
+	Thereafter int
+	// Tick 是采样的统计周期，默认为 1 秒
+	Tick time.Duration
+	// BypassLevel 是不参与采样的最低级别，为 nil 时为 Error，即 Error 及以上的日志从不被丢弃
+	BypassLevel *zapcore.Level
+}
+
+// bypassLevel 返回不参与采样的最低级别
+func (s *SamplingConfig) bypassLevel() zapcore.Level {
+	if s.BypassLevel == nil {
+		return zapcore.ErrorLevel
+	}
+	return *s.BypassLevel
+}
+
+// newSampler 根据配置创建采样器
+func (s *SamplingConfig) newSampler() *sampler {
+	tick := s.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return &sampler{
+		tick:       tick,
+		first:      uint64(max(s.Initial, 0)),
+		thereafter: uint64(max(s.Thereafter, 0)),
+		bypass:     s.bypassLevel(),
+	}
+}
+
+// wrap 用采样器包装 core
+func (s *SamplingConfig) wrap(core zapcore.Core) zapcore.Core {
+	return &samplerCore{Core: core, sampler: s.newSampler()}
+}
+
+// sampleCount 是一个计数桶
+type sampleCount struct {
+	// resetAt 是当前周期结束的Unix纳秒时间戳
+	resetAt int64
+	// n 是当前周期内的日志条数
+	n uint64
+	// dropped 是上一条保留的日志之后被丢弃的条数，跨周期累计
+	dropped uint64
+}
+
+// sampler 是记录丢弃条数的采样器
+// 与 zap 的采样器一样按级别和消息的哈希值分桶计数，哈希冲突的消息共享同一个计数
+type sampler struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+	bypass     zapcore.Level
+
+	mu     sync.Mutex
+	counts [zapcore.FatalLevel - zapcore.DebugLevel + 1][samplerBuckets]sampleCount
+}
+
+// sample 判断一条日志是否保留
+// 保留时返回自上一条保留的同类日志以来被丢弃的条数
+func (s *sampler) sample(level zapcore.Level, msg string) (keep bool, dropped uint64) {
+	if level >= s.bypass || level < zapcore.DebugLevel || level > zapcore.FatalLevel {
+		return true, 0
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(msg))
+	now := time.Now().UnixNano()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &s.counts[level-zapcore.DebugLevel][h.Sum32()%samplerBuckets]
+	if now >= c.resetAt {
+		c.n = 0
+		c.resetAt = now + int64(s.tick)
+	}
+	c.n++
+	if c.n > s.first && (s.thereafter == 0 || (c.n-s.first)%s.thereafter != 0) {
+		c.dropped++
+		return false, 0
+	}
+	dropped, c.dropped = c.dropped, 0
+	return true, dropped
+}
+
+// samplerCore 用 sampler 对日志进行采样的 core
+// 保留的日志之前有被丢弃的同类日志时，附加 sampled_dropped 字段
+type samplerCore struct {
+	zapcore.Core
+	sampler *sampler
+}
+
+// With 附加字段，返回的 core 与原 core 共享同一个采样器
+func (c *samplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplerCore{Core: c.Core.With(fields), sampler: c.sampler}
+}
+
+// Check 对日志采样，未启用的级别不计数
+func (c *samplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	keep, dropped := c.sampler.sample(ent.Level, ent.Message)
+	if !keep {
+		return ce
+	}
+	if dropped > 0 {
+		return c.Core.With([]zapcore.Field{zap.Uint64(SampledDroppedKey, dropped)}).Check(ent, ce)
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// sampleLoki 对一条 Loki 日志采样，未配置采样时总是保留
+// Loki 的日志不经过 zap 的 core，而是由 forward 直接推送，因此使用单独的采样器，
+// 保留时返回需要附加到消息中的 sampled_dropped 字段
+func (l *Logger) sampleLoki(level zapcore.Level, msg string) (keep bool, fields []zap.Field) {
+	if l.lokiSampler == nil {
+		return true, nil
+	}
+
+	keep, dropped := l.lokiSampler.sample(level, msg)
+	if keep && dropped > 0 {
+		fields = []zap.Field{zap.Uint64(SampledDroppedKey, dropped)}
+	}
+	return keep, fields
+}
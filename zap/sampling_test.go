@@ -0,0 +1,171 @@
+package zap
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampling *SamplingConfig
+		want     int
+	}{
+		{"disabled", nil, 12},
+		// 保留第 1、2 条，之后每 5 条保留一条：第 7、12 条
+		{"initial and thereafter", &SamplingConfig{Initial: 2, Thereafter: 5, Tick: time.Minute}, 4},
+		{"initial only", &SamplingConfig{Initial: 3, Tick: time.Minute}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			logger, server := newLokiLogger(t, Config{EnableFile: true, FilePath: path, Sampling: tt.sampling})
+
+			for i := 0; i < 12; i++ {
+				logger.Info("flood")
+			}
+			logger.Info("other")
+
+			lines := closeAndCollect(t, logger, server)
+			records := readFileLines(t, logger, path)
+
+			count := func(msgs []string) int {
+				n := 0
+				for _, msg := range msgs {
+					if strings.HasPrefix(msg, "flood") {
+						n++
+					}
+				}
+				return n
+			}
+			var lokiMsgs, fileMsgs []string
+			for _, line := range lines {
+				lokiMsgs = append(lokiMsgs, line.Line)
+			}
+			for _, r := range records {
+				fileMsgs = append(fileMsgs, r["msg"].(string))
+			}
+
+			if got := count(lokiMsgs); got != tt.want {
+				t.Errorf("Loki got %d flood entries, want %d", got, tt.want)
+			}
+			if got := count(fileMsgs); got != tt.want {
+				t.Errorf("file got %d flood entries, want %d", got, tt.want)
+			}
+			if len(lokiMsgs)-count(lokiMsgs) != 1 || len(fileMsgs)-count(fileMsgs) != 1 {
+				t.Errorf("other message sampled out: loki %v, file %v", lokiMsgs, fileMsgs)
+			}
+		})
+	}
+}
+
+func TestSamplingBypassLevel(t *testing.T) {
+	warn := zapcore.WarnLevel
+	tests := []struct {
+		name      string
+		bypass    *zapcore.Level
+		wantWarn  int
+		wantError int
+	}{
+		{"default bypasses error", nil, 1, 50},
+		{"bypass from warn", &warn, 50, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			logger, server := newLokiLogger(t, Config{
+				EnableFile: true,
+				FilePath:   path,
+				Sampling:   &SamplingConfig{Initial: 1, Tick: time.Minute, BypassLevel: tt.bypass},
+			})
+
+			for i := 0; i < 50; i++ {
+				logger.Info("flood")
+				logger.Warn("flood")
+				logger.Error("flood")
+			}
+
+			lines := closeAndCollect(t, logger, server)
+			records := readFileLines(t, logger, path)
+
+			lokiCounts := make(map[string]int)
+			for _, line := range lines {
+				lokiCounts[line.Labels["level"]]++
+			}
+			fileCounts := make(map[string]int)
+			for _, r := range records {
+				fileCounts[r["level"].(string)]++
+			}
+
+			want := map[string]int{"info": 1, "warn": tt.wantWarn, "error": tt.wantError}
+			for level, n := range want {
+				if lokiCounts[level] != n {
+					t.Errorf("Loki got %d %s entries, want %d", lokiCounts[level], level, n)
+				}
+				if fileCounts[level] != n {
+					t.Errorf("file got %d %s entries, want %d", fileCounts[level], level, n)
+				}
+			}
+		})
+	}
+}
+
+func TestSampledDroppedCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, server := newLokiLogger(t, Config{
+		EnableFile: true,
+		FilePath:   path,
+		Sampling:   &SamplingConfig{Initial: 1, Thereafter: 5, Tick: time.Minute},
+	})
+
+	// 保留第 1、6、11 条，后两条之前各丢弃了 4 条
+	for i := 0; i < 11; i++ {
+		logger.Info("flood")
+	}
+
+	lines := closeAndCollect(t, logger, server)
+	records := readFileLines(t, logger, path)
+	if len(lines) != 3 || len(records) != 3 {
+		t.Fatalf("got %d Loki lines and %d file records, want 3 each", len(lines), len(records))
+	}
+
+	want := []interface{}{nil, float64(4), float64(4)}
+	for i, w := range want {
+		if got := lineFields(t, lines[i].Line)[SampledDroppedKey]; got != w {
+			t.Errorf("Loki line %d %s = %v, want %v", i, SampledDroppedKey, got, w)
+		}
+		if got := records[i][SampledDroppedKey]; got != w {
+			t.Errorf("file record %d %s = %v, want %v", i, SampledDroppedKey, got, w)
+		}
+	}
+}
+
+func TestSamplerCarriesDropsAcrossTicks(t *testing.T) {
+	s := (&SamplingConfig{Initial: 1, Tick: 20 * time.Millisecond}).newSampler()
+
+	if keep, dropped := s.sample(zapcore.InfoLevel, "flood"); !keep || dropped != 0 {
+		t.Fatalf("first entry: keep %v dropped %d, want kept with no drops", keep, dropped)
+	}
+	for i := 0; i < 4; i++ {
+		if keep, _ := s.sample(zapcore.InfoLevel, "flood"); keep {
+			t.Fatalf("entry %d kept, want dropped", i+2)
+		}
+	}
+	if keep, _ := s.sample(zapcore.WarnLevel, "flood"); !keep {
+		t.Error("same message at another level was dropped, want separate counts")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if keep, dropped := s.sample(zapcore.InfoLevel, "flood"); !keep || dropped != 4 {
+		t.Errorf("entry after tick: keep %v dropped %d, want kept with 4 drops", keep, dropped)
+	}
+	if keep, dropped := s.sample(zapcore.ErrorLevel, "flood"); !keep || dropped != 0 {
+		t.Errorf("error entry: keep %v dropped %d, want bypassed", keep, dropped)
+	}
+}
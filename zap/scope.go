@@ -0,0 +1,97 @@
+package zap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/bt-smart/btlog/pkg"
+)
+
+// ScopeLabel 是日志作用域 ID 在 Loki 中的标签名
+const ScopeLabel = "scope_id"
+
+// scopeKey 是日志作用域在 context 中的键
+type scopeKey struct{}
+
+// logScope 收集同一个作用域内产生的 Loki 日志
+type logScope struct {
+	// id 是作用域的唯一标识，作为 scope_id 标签发送
+	id string
+	// mu 保护 entries 和 closed
+	mu sync.Mutex
+	// entries 是作用域内累积的日志
+	entries []pkg.LogEntry
+	// closed 表示作用域是否已结束
+	closed bool
+}
+
+// BeginLogScope 开启一个日志作用域
+// 在返回的 context 上通过 ...Ctx 方法记录的日志会暂存在作用域内，
+// 调用返回的结束函数时作为一个整体立即发送到 Loki，并带上相同的 scope_id 标签，
+// 便于在 Grafana 中查看一次事务的完整日志。
+// 控制台和文件输出不受影响，仍然实时写入。
+// 注意每个作用域都会产生新的 scope_id 标签值，即新的 Loki 流，
+// 只适合用于低频的事务级别场景。
+// 结束函数可以被多次调用，只有第一次调用会发送日志。
+func (l *Logger) BeginLogScope(ctx context.Context) (context.Context, func()) {
+	if l.lokiClient == nil {
+		return ctx, func() {}
+	}
+
+	scope := &logScope{id: newScopeID()}
+	ctx = context.WithValue(ctx, scopeKey{}, scope)
+
+	return ctx, func() {
+		scope.mu.Lock()
+		if scope.closed {
+			scope.mu.Unlock()
+			return
+		}
+		scope.closed = true
+		entries := scope.entries
+		scope.entries = nil
+		scope.mu.Unlock()
+
+		if len(entries) > 0 {
+			_ = l.lokiClient.PushBatch(entries)
+		}
+	}
+}
+
+// add 将日志加入作用域并附加 scope_id 标签，作用域已结束时返回 false
+func (s *logScope) add(entry pkg.LogEntry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	labels := make(map[string]string, len(entry.Labels)+1)
+	for k, v := range entry.Labels {
+		labels[k] = v
+	}
+	labels[ScopeLabel] = s.id
+	entry.Labels = labels
+
+	s.entries = append(s.entries, entry)
+	return true
+}
+
+// scopeFromContext 返回 context 中的日志作用域，不存在时返回 nil
+func scopeFromContext(ctx context.Context) *logScope {
+	if ctx == nil {
+		return nil
+	}
+	scope, _ := ctx.Value(scopeKey{}).(*logScope)
+	return scope
+}
+
+// newScopeID 生成随机的作用域 ID
+func newScopeID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,76 @@
+package zap
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBeginLogScope(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{})
+
+	ctx, end := logger.BeginLogScope(context.Background())
+	logger.InfoCtx(ctx, "step one")
+	logger.WarnCtx(ctx, "step two")
+	logger.Info("outside scope")
+
+	if n := len(server.Lines()); n != 0 {
+		t.Fatalf("Loki received %d lines before scope end, want 0", n)
+	}
+
+	end()
+	lines := server.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines after scope end, want 2", len(lines))
+	}
+	id := lines[0].Labels[ScopeLabel]
+	if id == "" {
+		t.Fatal("scope line has no scope_id label")
+	}
+	if lines[1].Labels[ScopeLabel] != id {
+		t.Errorf("scope ids differ: %q and %q", id, lines[1].Labels[ScopeLabel])
+	}
+
+	// 再次调用结束函数不会重复发送
+	end()
+	// 作用域结束后记录的日志走普通缓冲区
+	logger.InfoCtx(ctx, "after end")
+
+	lines = closeAndCollect(t, logger, server)
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines in total, want 4", len(lines))
+	}
+	for _, line := range lines[2:] {
+		if _, ok := line.Labels[ScopeLabel]; ok {
+			t.Errorf("line %q outside scope has scope_id", line.Line)
+		}
+	}
+}
+
+func TestBeginLogScopeIDsAreDistinct(t *testing.T) {
+	logger, server := newLokiLogger(t, Config{})
+	defer logger.Close()
+
+	for i := 0; i < 2; i++ {
+		ctx, end := logger.BeginLogScope(context.Background())
+		logger.InfoCtx(ctx, "in scope")
+		end()
+	}
+
+	lines := server.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if lines[0].Labels[ScopeLabel] == lines[1].Labels[ScopeLabel] {
+		t.Error("two scopes share the same scope_id")
+	}
+}
+
+func TestBeginLogScopeWithoutLoki(t *testing.T) {
+	logger, err := NewLogger(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, end := logger.BeginLogScope(context.Background())
+	logger.InfoCtx(ctx, "no loki")
+	end()
+}
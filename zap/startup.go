@@ -0,0 +1,51 @@
+package zap
+
+import (
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+// LogStartup 以 Info 级别输出一条记录日志器配置的启动日志
+// 包含各输出目标的启用状态、最小级别以及 Loki 服务器地址等信息，
+// 便于排查“为什么 Loki 里没有日志”之类的问题。
+// Loki 地址只记录主机部分，不会输出其中的用户名、密码、路径和查询参数。
+// 服务名、版本等业务信息可以通过 extra 传入。
+func (l *Logger) LogStartup(extra ...zap.Field) {
+	cfg := l.config
+
+	fields := []zap.Field{
+		zap.Bool("console_enabled", cfg.EnableConsole),
+		zap.Bool("file_enabled", cfg.EnableFile),
+		zap.Bool("loki_enabled", cfg.EnableLoki),
+	}
+	if cfg.EnableConsole {
+		fields = append(fields, zap.Stringer("console_level", cfg.ConsoleLevel))
+	}
+	if cfg.EnableFile {
+		fields = append(fields,
+			zap.Stringer("file_level", cfg.FileLevel),
+			zap.String("file_path", cfg.FilePath),
+		)
+	}
+	if cfg.EnableLoki {
+		fields = append(fields,
+			zap.Stringer("loki_level", cfg.LokiLevel),
+			zap.String("loki_host", redactURL(cfg.LokiConfig.URL)),
+			zap.Int("loki_batch_size", cfg.LokiConfig.BatchSize),
+			zap.Any("loki_labels", cfg.LokiConfig.Labels),
+		)
+	}
+	fields = append(fields, extra...)
+
+	l.Info("logger started", fields...)
+}
+
+// redactURL 只保留 URL 的协议和主机部分，去掉其中可能包含的凭据
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "<invalid>"
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,186 @@
+package zap
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// badKey 是 ...w 方法中无法作为字段名的参数所使用的字段名
+const badKey = "!BADKEY"
+
+// SugaredLogger 是支持 printf 风格和松散键值对的日志器
+// 与 zap.SugaredLogger 的用法一致，每条日志同样会转发到 Loki。
+// 性能低于 Logger，适合对性能不敏感的调用位置。
+type SugaredLogger struct {
+	base *Logger
+}
+
+// Sugar 返回共享同一个 Loki 客户端和输出的 SugaredLogger
+func (l *Logger) Sugar() *SugaredLogger {
+	base := *l
+	// SugaredLogger 的方法多包了一层调用，跳过它保证调用方信息正确
+	base.Logger = l.Logger.WithOptions(zap.AddCallerSkip(1))
+	return &SugaredLogger{base: &base}
+}
+
+// Desugar 返回对应的 Logger
+func (s *SugaredLogger) Desugar() *Logger {
+	base := *s.base
+	base.Logger = s.base.Logger.WithOptions(zap.AddCallerSkip(-1))
+	return &base
+}
+
+// With 返回一个附加了键值对字段的子日志器，参数规则与 Infow 相同
+func (s *SugaredLogger) With(keysAndValues ...interface{}) *SugaredLogger {
+	return &SugaredLogger{base: s.base.With(sweetenFields(keysAndValues)...)}
+}
+
+// Debug 使用 fmt.Sprint 格式化参数并记录 Debug 日志
+func (s *SugaredLogger) Debug(args ...interface{}) {
+	s.base.Debug(fmt.Sprint(args...))
+}
+
+// Info 使用 fmt.Sprint 格式化参数并记录 Info 日志
+func (s *SugaredLogger) Info(args ...interface{}) {
+	s.base.Info(fmt.Sprint(args...))
+}
+
+// Warn 使用 fmt.Sprint 格式化参数并记录 Warn 日志
+func (s *SugaredLogger) Warn(args ...interface{}) {
+	s.base.Warn(fmt.Sprint(args...))
+}
+
+// Error 使用 fmt.Sprint 格式化参数并记录 Error 日志
+func (s *SugaredLogger) Error(args ...interface{}) {
+	s.base.Error(fmt.Sprint(args...))
+}
+
+// DPanic 使用 fmt.Sprint 格式化参数并记录 DPanic 日志
+func (s *SugaredLogger) DPanic(args ...interface{}) {
+	s.base.DPanic(fmt.Sprint(args...))
+}
+
+// Panic 使用 fmt.Sprint 格式化参数并记录 Panic 日志，然后 panic
+func (s *SugaredLogger) Panic(args ...interface{}) {
+	s.base.Panic(fmt.Sprint(args...))
+}
+
+// Fatal 使用 fmt.Sprint 格式化参数并记录 Fatal 日志，然后退出程序
+func (s *SugaredLogger) Fatal(args ...interface{}) {
+	s.base.Fatal(fmt.Sprint(args...))
+}
+
+// Debugf 使用 fmt.Sprintf 格式化参数并记录 Debug 日志
+func (s *SugaredLogger) Debugf(template string, args ...interface{}) {
+	s.base.Debug(sprintf(template, args))
+}
+
+// Infof 使用 fmt.Sprintf 格式化参数并记录 Info 日志
+func (s *SugaredLogger) Infof(template string, args ...interface{}) {
+	s.base.Info(sprintf(template, args))
+}
+
+// Warnf 使用 fmt.Sprintf 格式化参数并记录 Warn 日志
+func (s *SugaredLogger) Warnf(template string, args ...interface{}) {
+	s.base.Warn(sprintf(template, args))
+}
+
+// Errorf 使用 fmt.Sprintf 格式化参数并记录 Error 日志
+func (s *SugaredLogger) Errorf(template string, args ...interface{}) {
+	s.base.Error(sprintf(template, args))
+}
+
+// DPanicf 使用 fmt.Sprintf 格式化参数并记录 DPanic 日志
+func (s *SugaredLogger) DPanicf(template string, args ...interface{}) {
+	s.base.DPanic(sprintf(template, args))
+}
+
+// Panicf 使用 fmt.Sprintf 格式化参数并记录 Panic 日志，然后 panic
+func (s *SugaredLogger) Panicf(template string, args ...interface{}) {
+	s.base.Panic(sprintf(template, args))
+}
+
+// Fatalf 使用 fmt.Sprintf 格式化参数并记录 Fatal 日志，然后退出程序
+func (s *SugaredLogger) Fatalf(template string, args ...interface{}) {
+	s.base.Fatal(sprintf(template, args))
+}
+
+// Debugw 记录一条带键值对字段的 Debug 日志
+func (s *SugaredLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	s.base.Debug(msg, sweetenFields(keysAndValues)...)
+}
+
+// Infow 记录一条带键值对字段的 Info 日志
+// 键值对依次为字段名和取值，也可以直接传入 zap.Field，例如
+//
+//	s.Infow("登录成功", "user", "alice", zap.Int("attempt", 2))
+func (s *SugaredLogger) Infow(msg string, keysAndValues ...interface{}) {
+	s.base.Info(msg, sweetenFields(keysAndValues)...)
+}
+
+// Warnw 记录一条带键值对字段的 Warn 日志
+func (s *SugaredLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	s.base.Warn(msg, sweetenFields(keysAndValues)...)
+}
+
+// Errorw 记录一条带键值对字段的 Error 日志
+func (s *SugaredLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	s.base.Error(msg, sweetenFields(keysAndValues)...)
+}
+
+// DPanicw 记录一条带键值对字段的 DPanic 日志
+func (s *SugaredLogger) DPanicw(msg string, keysAndValues ...interface{}) {
+	s.base.DPanic(msg, sweetenFields(keysAndValues)...)
+}
+
+// Panicw 记录一条带键值对字段的 Panic 日志，然后 panic
+func (s *SugaredLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	s.base.Panic(msg, sweetenFields(keysAndValues)...)
+}
+
+// Fatalw 记录一条带键值对字段的 Fatal 日志，然后退出程序
+func (s *SugaredLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	s.base.Fatal(msg, sweetenFields(keysAndValues)...)
+}
+
+// Sync 同步底层的 zap 输出
+func (s *SugaredLogger) Sync() error {
+	return s.base.Sync()
+}
+
+// sprintf 与 zap 一致，没有参数时直接使用模板，避免模板中的 % 被误解析
+func sprintf(template string, args []interface{}) string {
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// sweetenFields 将松散的键值对转换为 zap 字段
+// zap.Field 直接使用；字段名不是字符串、或最后一个字段名缺少取值时，
+// 该参数以 !BADKEY 为字段名保留下来，不会丢失
+func sweetenFields(args []interface{}) []zap.Field {
+	if len(args) == 0 {
+		return nil
+	}
+
+	fields := make([]zap.Field, 0, len(args)/2+1)
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(zap.Field); ok {
+			fields = append(fields, f)
+			i++
+			continue
+		}
+
+		key, ok := args[i].(string)
+		if !ok || i == len(args)-1 {
+			fields = append(fields, zap.Any(badKey, args[i]))
+			i++
+			continue
+		}
+		fields = append(fields, zap.Any(key, args[i+1]))
+		i += 2
+	}
+	return fields
+}
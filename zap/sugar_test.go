@@ -0,0 +1,96 @@
+package zap
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSugar(t *testing.T) {
+	core, observed := observer.New(zapcore.DebugLevel)
+	server := newFakeLoki(t)
+	logger, err := WrapZap(zap.New(core, zap.AddCaller()), LokiConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("WrapZap() error = %v", err)
+	}
+
+	sugar := logger.Sugar()
+	_, file, line, _ := runtime.Caller(0)
+	sugar.Infof("user %s logged in %d times", "alice", 3)
+	sugar.Errorw("payment failed", "order", 42, zap.String("op", "pay"))
+	sugar.With("request", "r1").Warn("slow ", "query")
+	sugar.Debugf("100%")
+
+	lines := closeAndCollect(t, logger, server)
+
+	entries := observed.All()
+	if len(entries) != 4 {
+		t.Fatalf("zap got %d entries, want 4", len(entries))
+	}
+	caller := entries[0].Caller
+	if filepath.Base(caller.File) != filepath.Base(file) || caller.Line != line+1 {
+		t.Errorf("caller = %s:%d, want %s:%d", caller.File, caller.Line, filepath.Base(file), line+1)
+	}
+
+	want := []struct {
+		level string
+		line  string
+	}{
+		{"info", "user alice logged in 3 times"},
+		{"error", `payment failed {"op":"pay","order":42}`},
+		{"warn", `slow query {"request":"r1"}`},
+		{"debug", "100%"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Loki got %d lines, want %d", len(lines), len(want))
+	}
+	got := make(map[string]string)
+	for _, l := range lines {
+		got[l.Labels["level"]] = l.Line
+	}
+	for _, w := range want {
+		if got[w.level] != w.line {
+			t.Errorf("%s line = %q, want %q", w.level, got[w.level], w.line)
+		}
+	}
+}
+
+func TestDesugarRestoresCaller(t *testing.T) {
+	core, observed := observer.New(zapcore.InfoLevel)
+	logger := &Logger{Logger: zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))}
+
+	_, file, line, _ := runtime.Caller(0)
+	logger.Sugar().Desugar().Info("plain")
+
+	caller := observed.All()[0].Caller
+	if filepath.Base(caller.File) != filepath.Base(file) || caller.Line != line+1 {
+		t.Errorf("caller = %s:%d, want %s:%d", caller.File, caller.Line, filepath.Base(file), line+1)
+	}
+}
+
+func TestSweetenFields(t *testing.T) {
+	tests := []struct {
+		name string
+		args []interface{}
+		want []zap.Field
+	}{
+		{"empty", nil, nil},
+		{"pairs", []interface{}{"a", 1, "b", "x"}, []zap.Field{zap.Any("a", 1), zap.Any("b", "x")}},
+		{"strongly typed", []interface{}{zap.Int("n", 2), "a", 1}, []zap.Field{zap.Int("n", 2), zap.Any("a", 1)}},
+		{"dangling key", []interface{}{"a", 1, "b"}, []zap.Field{zap.Any("a", 1), zap.Any(badKey, "b")}},
+		{"non-string key", []interface{}{7, "a", 1}, []zap.Field{zap.Any(badKey, 7), zap.Any("a", 1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sweetenFields(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sweetenFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+//go:build !windows && !plan9
+
+package zap
+
+import (
+	"io"
+	"log/syslog"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogWriter 是 syslog 输出需要的写入方法，由 *syslog.Writer 实现，测试时可以替换
+type syslogWriter interface {
+	Debug(m string) error
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+	Crit(m string) error
+	Alert(m string) error
+}
+
+// newSyslogCore 连接 syslog 并创建 syslog 输出的 core，返回的 io.Closer 用于关闭连接
+// SyslogNetwork 为空时连接本机的 syslog 守护进程
+func newSyslogCore(cfg *Config, encoderConfig zapcore.EncoderConfig) (zapcore.Core, io.Closer, error) {
+	w, err := syslog.Dial(cfg.SyslogNetwork, cfg.SyslogAddr, syslog.LOG_USER, cfg.SyslogTag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newSyslogWriterCore(w, encoderConfig, cfg.SyslogLevel), w, nil
+}
+
+// syslogCore 是将日志写入 syslog 的 zap core
+// 每条日志编码为一行 JSON，时间和级别由 syslog 协议本身携带，不再写入日志内容
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   syslogWriter
+}
+
+// newSyslogWriterCore 创建写入 w 的 syslog core
+func newSyslogWriterCore(w syslogWriter, encoderConfig zapcore.EncoderConfig, enab zapcore.LevelEnabler) *syslogCore {
+	encoderConfig.TimeKey = ""
+	encoderConfig.LevelKey = ""
+	return &syslogCore{LevelEnabler: enab, enc: zapcore.NewJSONEncoder(encoderConfig), w: w}
+}
+
+// With 返回附加了字段的 core，与原 core 共享同一个连接
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &syslogCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), w: c.w}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return clone
+}
+
+// Check 实现 zapcore.Core
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 编码日志并按级别对应的 syslog 严重性写入
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := strings.TrimSuffix(buf.String(), "\n")
+	buf.Free()
+	return writeSyslog(c.w, ent.Level, msg)
+}
+
+// Sync 实现 zapcore.Core，syslog 的写入没有缓冲，无需同步
+func (c *syslogCore) Sync() error {
+	return nil
+}
+
+// writeSyslog 按 zap 级别对应的 syslog 严重性写入一条消息
+// DPanic 和 Panic 对应 CRIT，Fatal 对应 ALERT。
+// 不使用 EMERG，避免 syslog 守护进程将消息广播到所有终端
+func writeSyslog(w syslogWriter, level zapcore.Level, msg string) error {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return w.Debug(msg)
+	case level == zapcore.InfoLevel:
+		return w.Info(msg)
+	case level == zapcore.WarnLevel:
+		return w.Warning(msg)
+	case level == zapcore.ErrorLevel:
+		return w.Err(msg)
+	case level < zapcore.FatalLevel:
+		return w.Crit(msg)
+	default:
+		return w.Alert(msg)
+	}
+}
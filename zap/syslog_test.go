@@ -0,0 +1,123 @@
+//go:build !windows && !plan9
+
+package zap
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeSyslog 记录每次写入的严重性和消息
+type fakeSyslog struct {
+	writes []string
+}
+
+func (f *fakeSyslog) record(severity, m string) error {
+	f.writes = append(f.writes, severity+" "+m)
+	return nil
+}
+
+func (f *fakeSyslog) Debug(m string) error   { return f.record("debug", m) }
+func (f *fakeSyslog) Info(m string) error    { return f.record("info", m) }
+func (f *fakeSyslog) Warning(m string) error { return f.record("warning", m) }
+func (f *fakeSyslog) Err(m string) error     { return f.record("err", m) }
+func (f *fakeSyslog) Crit(m string) error    { return f.record("crit", m) }
+func (f *fakeSyslog) Alert(m string) error   { return f.record("alert", m) }
+
+func TestSyslogSeverity(t *testing.T) {
+	w := &fakeSyslog{}
+	core := newSyslogWriterCore(w, zap.NewProductionEncoderConfig(), zapcore.DebugLevel)
+
+	levels := []zapcore.Level{
+		zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel,
+		zapcore.DPanicLevel, zapcore.PanicLevel, zapcore.FatalLevel,
+	}
+	for _, level := range levels {
+		if err := core.Write(zapcore.Entry{Level: level, Message: "m"}, nil); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	want := []string{"debug", "info", "warning", "err", "crit", "crit", "alert"}
+	if len(w.writes) != len(want) {
+		t.Fatalf("writes = %q, want %d", w.writes, len(want))
+	}
+	for i, severity := range want {
+		if !strings.HasPrefix(w.writes[i], severity+" ") {
+			t.Errorf("%s written as %q, want severity %s", levels[i], w.writes[i], severity)
+		}
+	}
+}
+
+func TestSyslogMessage(t *testing.T) {
+	w := &fakeSyslog{}
+	core := newSyslogWriterCore(w, zap.NewProductionEncoderConfig(), zapcore.InfoLevel)
+	logger := zap.New(core).With(zap.String("app", "demo"))
+
+	logger.Debug("filtered")
+	logger.Info("hello", zap.Int("n", 1))
+
+	if len(w.writes) != 1 {
+		t.Fatalf("writes = %q, want 1", w.writes)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(w.writes[0], "info ")), &record); err != nil {
+		t.Fatalf("message %q is not JSON: %v", w.writes[0], err)
+	}
+	if record["msg"] != "hello" || record["app"] != "demo" || record["n"] != float64(1) {
+		t.Errorf("record = %v", record)
+	}
+	if _, ok := record["ts"]; ok {
+		t.Errorf("record = %v, want no timestamp", record)
+	}
+	if _, ok := record["level"]; ok {
+		t.Errorf("record = %v, want no level", record)
+	}
+}
+
+func TestSyslogOutput(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen udp: %v", err)
+	}
+	defer conn.Close()
+
+	logger, err := NewLogger(&Config{
+		EnableSyslog:  true,
+		SyslogNetwork: "udp",
+		SyslogAddr:    conn.LocalAddr().String(),
+		SyslogTag:     "btlog-test",
+		SyslogLevel:   zapcore.WarnLevel,
+	})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	logger.Info("filtered")
+	logger.Error("boom")
+	defer logger.Close()
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read syslog packet: %v", err)
+	}
+	packet := string(buf[:n])
+	// 设施 user(1)，严重性 err(3)：PRI = 1*8 + 3
+	if !strings.HasPrefix(packet, "<11>") || !strings.Contains(packet, "btlog-test") || !strings.Contains(packet, `"msg":"boom"`) {
+		t.Errorf("syslog packet = %q", packet)
+	}
+}
+
+func TestSyslogValidate(t *testing.T) {
+	err := (&Config{EnableSyslog: true, SyslogNetwork: "udp"}).Validate()
+	if err == nil || !strings.Contains(err.Error(), "SyslogAddr") {
+		t.Errorf("Validate() error = %v, want SyslogAddr required", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+
+package zap
+
+import (
+	"errors"
+	"io"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// newSyslogCore 在不支持 log/syslog 的平台上总是返回错误
+func newSyslogCore(*Config, zapcore.EncoderConfig) (zapcore.Core, io.Closer, error) {
+	return nil, nil, errors.New("当前平台不支持 syslog")
+}
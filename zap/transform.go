@@ -0,0 +1,116 @@
+package zap
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry 是转发到 Loki 之前的一条日志
+// 转换器可以修改其中的任意内容
+type Entry struct {
+	// Level 是发送到 Loki 的日志级别
+	Level zapcore.Level
+	// Time 是日志时间
+	Time time.Time
+	// Message 是日志消息，不包含字段
+	Message string
+	// Fields 是日志字段，最终会以 JSON 形式追加到消息后面
+	Fields []zap.Field
+	// Labels 是附加的流标签
+	Labels map[string]string
+	// Metadata 是结构化元数据
+	Metadata map[string]string
+}
+
+// EntryTransformer 在日志转发到 Loki 之前对其进行转换
+// 返回 nil 表示丢弃该条日志，后续的转换器不会再执行
+//
+// 转换器按 Config.Transformers 中的顺序依次执行，
+// 每个转换器看到的是前一个转换器的输出。
+// 转换器只作用于 Loki 输出，控制台和文件输出不受影响。
+type EntryTransformer func(e *Entry) *Entry
+
+// RedactedValue 是被脱敏字段的替换值
+const RedactedValue = "[REDACTED]"
+
+// RedactFields 返回一个将指定字段的值替换为 [REDACTED] 的转换器
+func RedactFields(keys ...string) EntryTransformer {
+	set := keySet(keys)
+	return func(e *Entry) *Entry {
+		for i, field := range e.Fields {
+			if _, ok := set[field.Key]; ok {
+				e.Fields[i] = zap.String(field.Key, RedactedValue)
+			}
+		}
+		return e
+	}
+}
+
+// TruncateMessage 返回一个将消息截断到 maxBytes 字节以内的转换器
+// 截断时不会拆开多字节字符，并在末尾追加 "..."
+func TruncateMessage(maxBytes int) EntryTransformer {
+	return func(e *Entry) *Entry {
+		if maxBytes <= 0 || len(e.Message) <= maxBytes {
+			return e
+		}
+		cut := maxBytes
+		for cut > 0 && !utf8.RuneStart(e.Message[cut]) {
+			cut--
+		}
+		e.Message = e.Message[:cut] + "..."
+		return e
+	}
+}
+
+// PromoteFieldsToLabels 返回一个将指定字段移到流标签中的转换器
+// 注意每个不同的取值都会产生一个新的 Loki 流，只应用于取值有限的字段
+func PromoteFieldsToLabels(keys ...string) EntryTransformer {
+	set := keySet(keys)
+	return func(e *Entry) *Entry {
+		rest := e.Fields[:0:0]
+		for _, field := range e.Fields {
+			if _, ok := set[field.Key]; !ok {
+				rest = append(rest, field)
+				continue
+			}
+			if e.Labels == nil {
+				e.Labels = make(map[string]string)
+			}
+			e.Labels[field.Key] = fieldString(field)
+		}
+		e.Fields = rest
+		return e
+	}
+}
+
+// TimestampFromField 返回一个使用指定时间字段作为日志时间的转换器
+// 字段必须是 zap.Time 类型，使用后该字段会从日志中移除
+func TimestampFromField(key string) EntryTransformer {
+	return func(e *Entry) *Entry {
+		for i, field := range e.Fields {
+			if field.Key != key || field.Type != zapcore.TimeType {
+				continue
+			}
+			if loc, ok := field.Interface.(*time.Location); ok {
+				e.Time = time.Unix(0, field.Integer).In(loc)
+			} else {
+				e.Time = time.Unix(0, field.Integer)
+			}
+			e.Fields = append(e.Fields[:i:i], e.Fields[i+1:]...)
+			return e
+		}
+		return e
+	}
+}
+
+// keySet 将键列表转换为集合
+func keySet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	return set
+}
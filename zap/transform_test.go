@@ -0,0 +1,100 @@
+package zap
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTransformerPipeline(t *testing.T) {
+	var seen []string
+	tagTenant := func(e *Entry) *Entry {
+		// 自定义转换器看到的是前面转换器的输出
+		seen = append(seen, e.Message)
+		if e.Labels == nil {
+			e.Labels = map[string]string{}
+		}
+		e.Labels["tenant"] = "acme"
+		return e
+	}
+	dropHealthChecks := func(e *Entry) *Entry {
+		if e.Message == "health check" {
+			return nil
+		}
+		return e
+	}
+
+	logger, server := newLokiLogger(t, Config{
+		Transformers: []EntryTransformer{
+			dropHealthChecks,
+			RedactFields("password", "token"),
+			TruncateMessage(10),
+			tagTenant,
+		},
+	})
+
+	logger.Info("health check")
+	logger.Info("user logged in successfully", zap.String("user", "alice"), zap.String("password", "hunter2"))
+
+	lines := closeAndCollect(t, logger, server)
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1 (health check dropped)", len(lines))
+	}
+	line := lines[0]
+	if want := `user logge... {"password":"[REDACTED]","user":"alice"}`; line.Line != want {
+		t.Errorf("line = %q, want %q", line.Line, want)
+	}
+	if line.Labels["tenant"] != "acme" {
+		t.Errorf("labels = %v, want tenant=acme", line.Labels)
+	}
+	if len(seen) != 1 || seen[0] != "user logge..." {
+		t.Errorf("custom transformer saw %q, want the truncated message only", seen)
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		msg      string
+		maxBytes int
+		want     string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"longer than ten", 10, "longer tha..."},
+		{"日志消息很长", 7, "日志..."},
+		{"anything", 0, "anything"},
+	}
+	for _, tt := range tests {
+		e := TruncateMessage(tt.maxBytes)(&Entry{Message: tt.msg})
+		if e.Message != tt.want {
+			t.Errorf("TruncateMessage(%d)(%q) = %q, want %q", tt.maxBytes, tt.msg, e.Message, tt.want)
+		}
+	}
+}
+
+func TestPromoteFieldsToLabels(t *testing.T) {
+	e := PromoteFieldsToLabels("region")(&Entry{
+		Fields: []zap.Field{zap.String("region", "eu"), zap.Int("n", 1)},
+	})
+	if e.Labels["region"] != "eu" {
+		t.Errorf("labels = %v, want region=eu", e.Labels)
+	}
+	if len(e.Fields) != 1 || e.Fields[0].Key != "n" {
+		t.Errorf("fields = %v, want only n", e.Fields)
+	}
+}
+
+func TestTimestampFromField(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	e := TimestampFromField("event_time")(&Entry{
+		Time:   time.Now(),
+		Fields: []zap.Field{zap.Time("event_time", at), zap.String("k", "v")},
+	})
+	if !e.Time.Equal(at) {
+		t.Errorf("Time = %v, want %v", e.Time, at)
+	}
+	if len(e.Fields) != 1 || e.Fields[0].Key != "k" {
+		t.Errorf("fields = %v, want event_time removed", e.Fields)
+	}
+}
@@ -0,0 +1,78 @@
+package zap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate 检查配置中相互依赖的字段和取值范围，返回汇总了所有问题的错误
+// 例如启用了文件输出但没有设置 FilePath、启用了 Loki 但没有设置 URL、大小或个数为负数等。
+// NewLogger 在创建任何输出之前会先调用该方法，配置有效时返回 nil
+func (cfg *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if cfg.EnableFile {
+		check(cfg.FilePath != "", "启用文件输出时必须设置 FilePath")
+	}
+	check(cfg.MaxSize >= 0, "MaxSize 不能为负数: %d", cfg.MaxSize)
+	check(cfg.MaxBackups >= 0, "MaxBackups 不能为负数: %d", cfg.MaxBackups)
+	check(cfg.MaxAge >= 0, "MaxAge 不能为负数: %d", cfg.MaxAge)
+	check(cfg.RecentLogsSize >= 0, "RecentLogsSize 不能为负数: %d", cfg.RecentLogsSize)
+	check(cfg.DedupeWindow >= 0, "DedupeWindow 不能为负数: %s", cfg.DedupeWindow)
+	check(cfg.GoroutineStackSize >= 0, "GoroutineStackSize 不能为负数: %d", cfg.GoroutineStackSize)
+	check(validFileSyncMode(cfg.FileSyncMode), "不支持的 FileSyncMode %q，可选值为 %q、%q 或 %q", cfg.FileSyncMode, FileSyncBuffered, FileSyncEveryN, FileSyncAlways)
+	check(validRotationInterval(cfg.RotationInterval), "不支持的 RotationInterval %q，可选值为 %q 或 %q", cfg.RotationInterval, RotationDaily, RotationHourly)
+	check(cfg.FileSyncEvery >= 0, "FileSyncEvery 不能为负数: %d", cfg.FileSyncEvery)
+	check(validEncoding(cfg.ConsoleEncoding), "不支持的 ConsoleEncoding %q，可选值为 %q 或 %q", cfg.ConsoleEncoding, EncodingConsole, EncodingJSON)
+	check(validEncoding(cfg.FileEncoding), "不支持的 FileEncoding %q，可选值为 %q 或 %q", cfg.FileEncoding, EncodingConsole, EncodingJSON)
+	if _, err := cfg.encoderConfig(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if cfg.EnableLoki {
+		lc := cfg.LokiConfig
+		check(lc.URL != "", "启用 Loki 输出时必须设置 LokiConfig.URL")
+		check(lc.BatchSize >= 0, "LokiConfig.BatchSize 不能为负数: %d", lc.BatchSize)
+		check(lc.Timeout >= 0, "LokiConfig.Timeout 不能为负数: %d", lc.Timeout)
+		check(lc.MaxRetries >= 0, "LokiConfig.MaxRetries 不能为负数: %d", lc.MaxRetries)
+		check(lc.FlushWorkers >= 0, "LokiConfig.FlushWorkers 不能为负数: %d", lc.FlushWorkers)
+		check(lc.MaxConcurrentSends >= 0, "LokiConfig.MaxConcurrentSends 不能为负数: %d", lc.MaxConcurrentSends)
+		check(lc.MaxQueuedSends >= 0, "LokiConfig.MaxQueuedSends 不能为负数: %d", lc.MaxQueuedSends)
+		check(lc.MaxBufferEntries >= 0, "LokiConfig.MaxBufferEntries 不能为负数: %d", lc.MaxBufferEntries)
+		check(lc.MaxBatchBytes >= 0, "LokiConfig.MaxBatchBytes 不能为负数: %d", lc.MaxBatchBytes)
+		check(lc.MaxStreamsPerFlush >= 0, "LokiConfig.MaxStreamsPerFlush 不能为负数: %d", lc.MaxStreamsPerFlush)
+		if _, err := newRedactor(lc); err != nil {
+			errs = append(errs, err)
+		}
+		check(lc.TraceBuckets >= 0, "LokiConfig.TraceBuckets 不能为负数: %d", lc.TraceBuckets)
+		check(lc.DropEventsSize >= 0, "LokiConfig.DropEventsSize 不能为负数: %d", lc.DropEventsSize)
+		check(lc.MaxEntryAge >= 0, "LokiConfig.MaxEntryAge 不能为负数: %s", lc.MaxEntryAge)
+		check(lc.ShutdownTimeout >= 0, "LokiConfig.ShutdownTimeout 不能为负数: %s", lc.ShutdownTimeout)
+		check(lc.RetryBackoff >= 0, "LokiConfig.RetryBackoff 不能为负数: %s", lc.RetryBackoff)
+	}
+
+	if cfg.EnableSyslog && cfg.SyslogNetwork != "" {
+		check(cfg.SyslogAddr != "", "设置了 SyslogNetwork 时必须设置 SyslogAddr")
+	}
+
+	if cfg.EnableOTLP {
+		oc := cfg.OTLPConfig
+		check(oc.Endpoint != "", "启用 OTLP 输出时必须设置 OTLPConfig.Endpoint")
+		check(oc.BatchSize >= 0, "OTLPConfig.BatchSize 不能为负数: %d", oc.BatchSize)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("日志配置无效: %w", errors.Join(errs...))
+}
+
+// validEncoding 判断编码格式是否受支持，空字符串表示使用默认值
+func validEncoding(encoding string) bool {
+	return encoding == "" || encoding == EncodingConsole || encoding == EncodingJSON
+}
@@ -0,0 +1,56 @@
+package zap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"empty", Config{}, nil},
+		{"console only", Config{EnableConsole: true, ConsoleEncoding: EncodingJSON}, nil},
+		{"file without path", Config{EnableFile: true}, []string{"FilePath"}},
+		{"loki without url", Config{EnableLoki: true}, []string{"LokiConfig.URL"}},
+		{"otlp without endpoint", Config{EnableOTLP: true}, []string{"OTLPConfig.Endpoint"}},
+		{"negative sizes", Config{MaxSize: -1, MaxAge: -1}, []string{"MaxSize", "MaxAge"}},
+		{"bad encoding", Config{FileEncoding: "xml"}, []string{"FileEncoding"}},
+		{"bad time layout", Config{TimeLayout: "no placeholders"}, []string{"no placeholders"}},
+		{
+			"aggregated",
+			Config{EnableFile: true, EnableLoki: true, LokiConfig: LokiConfig{BatchSize: -5}},
+			[]string{"FilePath", "LokiConfig.URL", "LokiConfig.BatchSize"},
+		},
+		{"disabled loki is not checked", Config{LokiConfig: LokiConfig{BatchSize: -5}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %v", tt.want)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(err.Error(), s) {
+					t.Errorf("Validate() error = %q, want it to mention %q", err, s)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLoggerValidates(t *testing.T) {
+	_, err := NewLogger(&Config{EnableFile: true, EnableLoki: true})
+	if err == nil || !strings.Contains(err.Error(), "FilePath") || !strings.Contains(err.Error(), "LokiConfig.URL") {
+		t.Errorf("NewLogger() error = %v, want both problems reported", err)
+	}
+}
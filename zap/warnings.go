@@ -0,0 +1,80 @@
+package zap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bt-smart/btlog/loki"
+)
+
+// 单次发送的日志条数超过该值时给出警告
+const largeBatchSize = 10000
+
+// 标签值超过该长度时视为可能的高基数标签
+const longLabelValue = 64
+
+// 取值通常是唯一 ID 的标签名，作为标签会导致 Loki 流数量爆炸
+var highCardinalityLabels = []string{"trace_id", "span_id", "request_id", "user_id", "session_id"}
+
+// Warnings 检查配置中容易踩坑的地方，返回对应的警告信息
+// 这些配置可以正常工作，但行为往往不符合预期，例如：
+//   - 启用 Loki 但既没有设置 Timeout，HTTP 客户端也没有超时，Loki 无响应时发送会一直阻塞
+//   - 批量大小过大，单次请求体积过大且日志延迟很高
+//   - 标签中包含 ID 类的高基数取值
+//
+// NewLogger 会在创建完成后将这些警告以 Warn 级别输出一次，
+// 可以通过 SuppressWarnings 关闭。
+func (cfg *Config) Warnings() []string {
+	var warnings []string
+
+	if !cfg.EnableConsole && !cfg.EnableFile && !cfg.EnableLoki && !cfg.EnableOTLP && !cfg.EnableSyslog && cfg.RecentLogsSize <= 0 {
+		warnings = append(warnings, "未启用任何输出，所有日志都会被丢弃")
+	}
+
+	if cfg.EnableLoki {
+		lc := cfg.LokiConfig
+		if client := lokiHTTPClient(lc); client == nil || client.Timeout == 0 {
+			warnings = append(warnings, "Loki HTTP 客户端未设置超时，Loki 无响应时发送会一直阻塞，可以设置 LokiConfig.Timeout")
+		}
+		if lc.BatchSize > largeBatchSize {
+			warnings = append(warnings, fmt.Sprintf("Loki 批量大小 %d 过大，单次请求体积大且日志延迟高", lc.BatchSize))
+		}
+		names := make([]string, 0, len(lc.Labels))
+		for k := range lc.Labels {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			v := lc.Labels[k]
+			if isHighCardinalityLabel(k) {
+				warnings = append(warnings, fmt.Sprintf("Loki 标签 %q 通常是唯一 ID，会导致流数量爆炸", k))
+			} else if len(v) > longLabelValue {
+				warnings = append(warnings, fmt.Sprintf("Loki 标签 %q 的取值过长，可能是高基数标签", k))
+			}
+		}
+		for _, k := range lc.LabelKeys {
+			if !loki.ValidLabelName(k) {
+				warnings = append(warnings, fmt.Sprintf("Loki 标签字段 %q 不是合法的标签名，发送时会被跳过", k))
+			} else if isHighCardinalityLabel(k) {
+				warnings = append(warnings, fmt.Sprintf("Loki 标签字段 %q 通常是唯一 ID，会导致流数量爆炸", k))
+			}
+		}
+		if len(lc.LabelKeys) > 0 && lc.MaxStreamsPerFlush <= 0 {
+			warnings = append(warnings, "设置了 LabelKeys 但未设置 MaxStreamsPerFlush，流的个数不受限制")
+		}
+	}
+
+	return warnings
+}
+
+// isHighCardinalityLabel 判断标签名是否属于通常取值唯一的 ID 类标签
+func isHighCardinalityLabel(name string) bool {
+	name = strings.ToLower(name)
+	for _, label := range highCardinalityLabels {
+		if name == label {
+			return true
+		}
+	}
+	return false
+}
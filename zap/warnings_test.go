@@ -0,0 +1,121 @@
+package zap
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWarnings(t *testing.T) {
+	withTimeout := &http.Client{Timeout: 5 * time.Second}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{
+			name: "loki disabled",
+			cfg:  Config{EnableConsole: true, LokiConfig: LokiConfig{BatchSize: largeBatchSize + 1}},
+		},
+		{
+			name: "no outputs",
+			cfg:  Config{},
+			want: []string{"未启用任何输出"},
+		},
+		{
+			name: "sane config",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: withTimeout, BatchSize: 100, Labels: map[string]string{"app": "svc"}}},
+		},
+		{
+			name: "default http client",
+			cfg:  Config{EnableLoki: true},
+			want: []string{"未设置超时"},
+		},
+		{
+			name: "timeout seconds",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{Timeout: 5}},
+		},
+		{
+			name: "client without timeout and timeout seconds",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: &http.Client{}, Timeout: 5}},
+		},
+		{
+			name: "client without timeout",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: http.DefaultClient}},
+			want: []string{"未设置超时"},
+		},
+		{
+			name: "large batch",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: withTimeout, BatchSize: largeBatchSize + 1}},
+			want: []string{"批量大小 10001 过大"},
+		},
+		{
+			name: "id labels",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: withTimeout, Labels: map[string]string{"Trace_ID": "x", "user_id": "u"}}},
+			want: []string{`"Trace_ID" 通常是唯一 ID`, `"user_id" 通常是唯一 ID`},
+		},
+		{
+			name: "long label value",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: withTimeout, Labels: map[string]string{"version": strings.Repeat("v", longLabelValue+1)}}},
+			want: []string{`"version" 的取值过长`},
+		},
+		{
+			name: "label keys without stream limit",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: withTimeout, LabelKeys: []string{"tenant", "request_id"}}},
+			want: []string{`"request_id" 通常是唯一 ID`, "未设置 MaxStreamsPerFlush"},
+		},
+		{
+			name: "invalid label key",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: withTimeout, LabelKeys: []string{"http.route"}, MaxStreamsPerFlush: 50}},
+			want: []string{`"http.route" 不是合法的标签名`},
+		},
+		{
+			name: "label keys with stream limit",
+			cfg:  Config{EnableLoki: true, LokiConfig: LokiConfig{HTTPClient: withTimeout, LabelKeys: []string{"tenant"}, MaxStreamsPerFlush: 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.Warnings()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Warnings() = %q, want %d warnings", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("warning %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLoggerEmitsWarnings(t *testing.T) {
+	server := newFakeLoki(t)
+
+	for _, suppress := range []bool{false, true} {
+		logger, path := newFileLogger(t, Config{
+			EnableLoki:       true,
+			LokiConfig:       LokiConfig{URL: server.URL},
+			SuppressWarnings: suppress,
+		})
+		logger.Info("started")
+
+		var warnings int
+		for _, record := range readFileLines(t, logger, path) {
+			msg, _ := record["msg"].(string)
+			if record["level"] == "warn" && strings.Contains(msg, "未设置超时") {
+				warnings++
+			}
+		}
+		want := 1
+		if suppress {
+			want = 0
+		}
+		if warnings != want {
+			t.Errorf("SuppressWarnings=%v: got %d warnings, want %d", suppress, warnings, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package zap
+
+import (
+	"fmt"
+
+	"github.com/bt-smart/btlog/loki"
+	"go.uber.org/zap"
+)
+
+// WrapZap 为已有的 zap 日志器增加 Loki 输出
+// 适用于已经由其他库配置好 *zap.Logger、只想额外转发到 Loki 的场景，无需重新构建输出。
+// 原日志器的输出保持不变，Loki 的最小级别与原日志器的级别一致。
+// 与 NewLogger 一样先检查配置，RedactKeys 等脱敏配置只作用于发送到 Loki 的日志。
+// 返回的日志器在原日志器的基础上跳过一层调用栈，保证调用方信息指向实际的调用位置。
+// Close 只会同步原日志器并关闭 Loki 客户端，不会关闭原日志器的输出。
+func WrapZap(base *zap.Logger, lokiCfg LokiConfig) (*Logger, error) {
+	cfg := Config{
+		EnableLoki: true,
+		LokiLevel:  base.Level(),
+		LokiConfig: lokiCfg,
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	redact, err := newRedactor(lokiCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	lokiClient, err := loki.NewClient(lokiClientConfig(&cfg))
+	if err != nil {
+		return nil, fmt.Errorf("创建 Loki 客户端失败: %v", err)
+	}
+	lokiClient.Start()
+
+	return &Logger{
+		Logger:     base.WithOptions(zap.AddCallerSkip(1)),
+		lokiClient: lokiClient,
+		redactor:   redact,
+		config:     cfg,
+		// 原日志器的输出级别由其自身决定，SetConsoleLevel 和 SetFileLevel 对其没有作用
+		levels: levels{
+			console: zap.NewAtomicLevelAt(base.Level()),
+			file:    zap.NewAtomicLevelAt(base.Level()),
+		},
+	}, nil
+}
@@ -0,0 +1,60 @@
+package zap
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWrapZap(t *testing.T) {
+	core, observed := observer.New(zapcore.InfoLevel)
+	base := zap.New(core, zap.AddCaller())
+
+	server := newFakeLoki(t)
+	logger, err := WrapZap(base, LokiConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("WrapZap() error = %v", err)
+	}
+
+	_, file, line, _ := runtime.Caller(0)
+	logger.Info("wrapped", zap.String("k", "v"))
+	logger.Debug("below base level")
+
+	lines := closeAndCollect(t, logger, server)
+
+	entries := observed.All()
+	if len(entries) != 1 {
+		t.Fatalf("base logger got %d entries, want 1", len(entries))
+	}
+	if entries[0].Message != "wrapped" || entries[0].ContextMap()["k"] != "v" {
+		t.Errorf("base entry = %+v", entries[0])
+	}
+	caller := entries[0].Caller
+	if filepath.Base(caller.File) != filepath.Base(file) || caller.Line != line+1 {
+		t.Errorf("caller = %s:%d, want %s:%d", caller.File, caller.Line, filepath.Base(file), line+1)
+	}
+
+	if len(lines) != 1 {
+		t.Fatalf("Loki got %d lines, want 1 (Debug is below the base level)", len(lines))
+	}
+	if lines[0].Labels["level"] != "info" || lineFields(t, lines[0].Line)["k"] != "v" {
+		t.Errorf("Loki line = %+v", lines[0])
+	}
+}
+
+func TestWrapZapInvalidConfig(t *testing.T) {
+	if _, err := WrapZap(zap.NewNop(), LokiConfig{}); err == nil {
+		t.Error("WrapZap() without URL error = nil, want error")
+	}
+	// 与 NewLogger 一样拒绝无效的配置
+	if _, err := WrapZap(zap.NewNop(), LokiConfig{URL: "http://loki:3100", BatchSize: -1}); err == nil {
+		t.Error("WrapZap() with negative BatchSize error = nil, want error")
+	}
+	if _, err := WrapZap(zap.NewNop(), LokiConfig{URL: "http://loki:3100", RedactKeyPattern: "("}); err == nil {
+		t.Error("WrapZap() with invalid RedactKeyPattern error = nil, want error")
+	}
+}